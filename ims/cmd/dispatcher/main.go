@@ -0,0 +1,43 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/omniful/go_commons/config"
+	commonsHttp "github.com/omniful/go_commons/http"
+	"github.com/omniful/go_commons/log"
+
+	"github.com/abhirup.dandapat/ims/internal/dispatcher"
+	"github.com/abhirup.dandapat/ims/internal/store"
+)
+
+func main() {
+	if err := config.Init(30 * time.Second); err != nil {
+		panic(err)
+	}
+	ctx, err := config.TODOContext()
+	if err != nil {
+		panic(err)
+	}
+
+	log.SetLevel(config.GetString(ctx, "log.level"))
+	log.Infof("Starting IMS webhook dispatcher")
+
+	store.InitPostgres(ctx)
+
+	transport := &http.Transport{}
+	httpClient, err := commonsHttp.NewHTTPClient("ims-webhook-dispatcher", "", transport)
+	if err != nil {
+		log.DefaultLogger().Panicf("http client init failed: %v", err)
+	}
+
+	d := dispatcher.NewDispatcher(
+		store.DB,
+		httpClient,
+		config.GetInt(ctx, "webhooks.batchSize"),
+		config.GetInt(ctx, "webhooks.maxAttempts"),
+		config.GetDuration(ctx, "webhooks.backoff"),
+	)
+	d.Run(ctx, config.GetDuration(ctx, "webhooks.pollInterval"))
+}
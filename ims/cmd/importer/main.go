@@ -0,0 +1,30 @@
+package main
+
+import (
+	"time"
+
+	"github.com/omniful/go_commons/config"
+	"github.com/omniful/go_commons/log"
+
+	"github.com/abhirup.dandapat/ims/internal/api"
+	"github.com/abhirup.dandapat/ims/internal/store"
+)
+
+func main() {
+	if err := config.Init(30 * time.Second); err != nil {
+		panic(err)
+	}
+	ctx, err := config.TODOContext()
+	if err != nil {
+		panic(err)
+	}
+
+	log.SetLevel(config.GetString(ctx, "log.level"))
+	log.Infof("Starting IMS catalog importer")
+
+	store.InitPostgres(ctx)
+	store.InitRedis(ctx)
+
+	imp := api.NewCatalogImporter(store.DB, config.GetInt(ctx, "imports.maxAttempts"))
+	imp.Run(ctx, config.GetDuration(ctx, "imports.pollInterval"))
+}
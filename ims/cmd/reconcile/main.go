@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"time"
+
+	"github.com/omniful/go_commons/config"
+	"github.com/omniful/go_commons/log"
+
+	"github.com/abhirup.dandapat/ims/constants"
+	"github.com/abhirup.dandapat/ims/internal/ledger"
+	"github.com/abhirup.dandapat/ims/internal/store"
+)
+
+// reconcile checks inventory balances against the ledger for every hub (or a
+// tenant's / a single hub's) and prints the report as JSON. With -fix it posts
+// corrective adjustment rows for on-hand gaps.
+func main() {
+	tenantID := flag.String("tenant", "", "only scan hubs of this tenant")
+	hubID := flag.String("hub", "", "only scan this hub")
+	fix := flag.Bool("fix", false, "post corrective adjustment rows")
+	reason := flag.String("reason", constants.ReasonCodeLedgerReconcile, "reason_code for corrective rows")
+	flag.Parse()
+
+	if err := config.Init(30 * time.Second); err != nil {
+		panic(err)
+	}
+	ctx, err := config.TODOContext()
+	if err != nil {
+		panic(err)
+	}
+
+	log.SetLevel(config.GetString(ctx, "log.level"))
+	store.InitPostgres(ctx)
+
+	report, err := ledger.Reconcile(ctx, store.DB, ledger.ReconcileOptions{
+		TenantID:   *tenantID,
+		HubID:      *hubID,
+		Fix:        *fix,
+		ReasonCode: *reason,
+	})
+	if err != nil {
+		log.DefaultLogger().Errorf("reconcile failed: %v", err)
+		os.Exit(1)
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		log.DefaultLogger().Errorf("write report: %v", err)
+		os.Exit(1)
+	}
+	for _, m := range report.Mismatches {
+		if !m.Corrected || m.QuantityReserved != m.LedgerReserved {
+			os.Exit(2)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"time"
+
+	"github.com/omniful/go_commons/config"
+	"github.com/omniful/go_commons/log"
+
+	"github.com/abhirup.dandapat/ims/internal/ledger"
+	"github.com/abhirup.dandapat/ims/internal/store"
+)
+
+func main() {
+	if err := config.Init(30 * time.Second); err != nil {
+		panic(err)
+	}
+	ctx, err := config.TODOContext()
+	if err != nil {
+		panic(err)
+	}
+
+	log.SetLevel(config.GetString(ctx, "log.level"))
+	log.Infof("Starting IMS inventory snapshotter")
+
+	store.InitPostgres(ctx)
+
+	s := ledger.NewSnapshotter(
+		store.DB,
+		config.GetDuration(ctx, "snapshots.interval"),
+		config.GetDuration(ctx, "snapshots.lag"),
+	)
+	s.Run(ctx)
+}
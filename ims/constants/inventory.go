@@ -0,0 +1,87 @@
+package constants
+
+// Transaction types posted to inventory_transactions. The delta on reserve and
+// release rows moves quantity_reserved; commit moves both quantity_on_hand and
+// quantity_reserved; every other type moves quantity_on_hand only.
+const (
+	TransactionTypeUpsert     = "upsert"
+	TransactionTypeAdjustment = "adjustment"
+	TransactionTypeReserve    = "reserve"
+	TransactionTypeCommit     = "commit"
+	TransactionTypeRelease    = "release"
+	TransactionTypeReceipt    = "receipt"
+
+	// Transfer legs share the transfer ID as reference_id: transfer_out at the
+	// source on dispatch, transfer_in at the destination on receipt and
+	// transfer_cancel at the source for quantity cancelled while in transit.
+	TransactionTypeTransferOut    = "transfer_out"
+	TransactionTypeTransferIn     = "transfer_in"
+	TransactionTypeTransferCancel = "transfer_cancel"
+
+	// TransactionTypeLegacyReservation rows were logged by older OMS builds
+	// without touching any balance; the ledger ignores them.
+	TransactionTypeLegacyReservation = "reservation"
+)
+
+// Reason codes on adjustment rows posted by IMS itself.
+const (
+	ReasonCodeLedgerReconcile = "ledger_reconcile"
+	ReasonCodeCycleCount      = "cycle_count"
+)
+
+const (
+	ReservationStatusReserved  = "reserved"
+	ReservationStatusCommitted = "committed"
+	ReservationStatusReleased  = "released"
+)
+
+const (
+	TransferStatusInTransit         = "in_transit"
+	TransferStatusPartiallyReceived = "partially_received"
+	TransferStatusReceived          = "received"
+	TransferStatusCancelled         = "cancelled"
+)
+
+// Serial statuses: a unit is in_stock at its hub from receipt until a commit
+// ships it. Shipped units may be received again, e.g. as a return.
+const (
+	SerialStatusInStock = "in_stock"
+	SerialStatusShipped = "shipped"
+)
+
+const (
+	LocationTypeZone  = "zone"
+	LocationTypeAisle = "aisle"
+	LocationTypeBin   = "bin"
+)
+
+// Tenant costing methods for valuing stock.
+const (
+	CostingMethodFIFO    = "fifo"
+	CostingMethodAverage = "average"
+)
+
+const (
+	CountStatusOpen      = "open"
+	CountStatusApproved  = "approved"
+	CountStatusCancelled = "cancelled"
+)
+
+// Modes and per-row statuses for PUT /inventory/batch.
+const (
+	BatchModeAllOrNothing = "all_or_nothing"
+	BatchModeBestEffort   = "best_effort"
+
+	BatchRowApplied    = "applied"
+	BatchRowFailed     = "failed"
+	BatchRowRolledBack = "rolled_back"
+	BatchRowSkipped    = "skipped"
+)
+
+const (
+	AlertTypeLowStock  = "low_stock"
+	AlertTypeOverStock = "over_stock"
+
+	AlertStatusOpen     = "open"
+	AlertStatusResolved = "resolved"
+)
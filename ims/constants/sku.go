@@ -0,0 +1,43 @@
+package constants
+
+// DefaultBaseUOM is the unit a SKU's stock is counted in when it is created
+// without one. Inventory, ledger and reservation quantities are always in the
+// SKU's base unit.
+const DefaultBaseUOM = "each"
+
+// Types of product variant attributes. enum attributes take one of a fixed
+// list of values.
+const (
+	AttributeTypeString = "string"
+	AttributeTypeNumber = "number"
+	AttributeTypeEnum   = "enum"
+)
+
+// MaxVariantMatrix caps how many variants one matrix request may create.
+const MaxVariantMatrix = 1000
+
+// Barcode symbologies, told apart by length: EAN-8, UPC-A (12 digits),
+// EAN-13 and GTIN-14.
+const (
+	BarcodeTypeEAN8   = "ean8"
+	BarcodeTypeUPCA   = "upc_a"
+	BarcodeTypeEAN13  = "ean13"
+	BarcodeTypeGTIN14 = "gtin14"
+)
+
+// Catalog import file formats and job statuses. An import is pending until
+// the importer claims it, then completed once its rows are written, or failed
+// when it could not be processed at all; rows that do not validate fail on
+// their own and are listed in the import's error report.
+const (
+	ImportFormatCSV  = "csv"
+	ImportFormatJSON = "json"
+
+	ImportStatusPending    = "pending"
+	ImportStatusProcessing = "processing"
+	ImportStatusCompleted  = "completed"
+	ImportStatusFailed     = "failed"
+)
+
+// MaxCatalogImportRows caps how many SKUs one catalog import may carry.
+const MaxCatalogImportRows = 50000
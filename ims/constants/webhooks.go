@@ -0,0 +1,17 @@
+package constants
+
+// Events IMS delivers to registered webhooks. A webhook receives an event only
+// when it is listed in the registration's events array.
+const (
+	EventInventoryUpdated            = "inventory.updated"
+	EventInventoryLowStock           = "inventory.low_stock"
+	EventInventoryOverStock          = "inventory.over_stock"
+	EventInventoryTransactionCreated = "inventory.transaction.created"
+)
+
+const (
+	DeliveryStatusPending   = "pending"
+	DeliveryStatusDelivered = "delivered"
+	DeliveryStatusFailed    = "failed"
+	DeliveryStatusCancelled = "cancelled"
+)
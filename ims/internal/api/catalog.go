@@ -0,0 +1,396 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/omniful/go_commons/i18n"
+	"github.com/omniful/go_commons/log"
+	"gorm.io/gorm"
+
+	"github.com/abhirup.dandapat/ims/constants"
+	"github.com/abhirup.dandapat/ims/internal/models"
+	"github.com/abhirup.dandapat/ims/internal/store"
+)
+
+var catalogLogger = log.DefaultLogger()
+
+// errInUse is returned when soft deleting a row that live rows still refer to.
+var errInUse = errors.New("row still in use")
+
+// liveFilter hides soft-deleted rows from a listing unless the request asks
+// for them with include_deleted=true.
+func liveFilter(c *gin.Context, where []string) ([]string, error) {
+	include, err := parseBoolQuery(c, "include_deleted")
+	if err != nil {
+		return where, err
+	}
+	if !include {
+		where = append(where, "deleted_at IS NULL")
+	}
+	return where, nil
+}
+
+// isUniqueViolation reports whether err is Postgres refusing a row that a
+// unique index already holds (SQLSTATE 23505).
+func isUniqueViolation(err error) bool {
+	var pgErr interface{ SQLState() string }
+	return errors.As(err, &pgErr) && pgErr.SQLState() == "23505"
+}
+
+// softDelete marks a live row of table deleted. refs are "table.column"
+// pairs; while a live row of one of them points at id the row is kept and
+// errInUse returned. A missing or already deleted row is gorm.ErrRecordNotFound.
+// The row is locked for the check, and creates lock the live rows they refer
+// to, so a row cannot be deleted while one that points at it goes in.
+func softDelete(db *gorm.DB, table, id string, refs []string, now time.Time) error {
+	tx := db.Begin()
+	if tx.Error != nil {
+		return tx.Error
+	}
+	defer tx.Rollback()
+
+	var ids []string
+	if err := tx.Raw(
+		fmt.Sprintf(`SELECT id FROM %s WHERE id = ? AND deleted_at IS NULL FOR UPDATE`, table), id,
+	).Scan(&ids).Error; err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	if len(refs) > 0 {
+		checks := make([]string, len(refs))
+		args := make([]interface{}, len(refs))
+		for i, ref := range refs {
+			refTable, column, _ := strings.Cut(ref, ".")
+			checks[i] = fmt.Sprintf(`EXISTS(SELECT 1 FROM %s WHERE %s = ? AND deleted_at IS NULL)`, refTable, column)
+			args[i] = id
+		}
+		var inUse bool
+		if err := tx.Raw(`SELECT `+strings.Join(checks, " OR "), args...).Scan(&inUse).Error; err != nil {
+			return err
+		}
+		if inUse {
+			return errInUse
+		}
+	}
+
+	if err := tx.Exec(
+		fmt.Sprintf(`UPDATE %s SET deleted_at = ?, updated_at = ? WHERE id = ?`, table),
+		now, now, id,
+	).Error; err != nil {
+		return err
+	}
+	return tx.Commit().Error
+}
+
+// marshalMetadata encodes optional metadata for a JSONB column; nil stays
+// NULL so COALESCE can keep the stored value.
+func marshalMetadata(m map[string]interface{}) ([]byte, error) {
+	if m == nil {
+		return nil, nil
+	}
+	return json.Marshal(m)
+}
+
+func listTenants(c *gin.Context) {
+	where, err := liveFilter(c, []string{"1=1"})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_request")})
+		return
+	}
+
+	sqlStr := fmt.Sprintf(
+		`SELECT id,name,metadata,allow_negative_inventory,costing_method,created_at,updated_at,deleted_at
+           FROM tenants WHERE %s ORDER BY name`, strings.Join(where, " AND "),
+	)
+
+	db := store.DB.GetSlaveDB(c.Request.Context())
+	rows, err := db.Raw(sqlStr).Rows()
+	if err != nil {
+		catalogLogger.Errorf("listTenants DB error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.list_tenants_failed")})
+		return
+	}
+	defer rows.Close()
+
+	var tenants []models.Tenant
+	for rows.Next() {
+		var t models.Tenant
+		if err := db.ScanRows(rows, &t); err != nil {
+			catalogLogger.Errorf("listTenants scan error: %v", err)
+			continue
+		}
+		tenants = append(tenants, t)
+	}
+	c.JSON(http.StatusOK, tenants)
+}
+
+// TenantUpdateRequest renames a tenant; the other fields keep their stored
+// value when left out.
+type TenantUpdateRequest struct {
+	Name                   string                 `json:"name"                     binding:"required"`
+	Metadata               map[string]interface{} `json:"metadata"`
+	AllowNegativeInventory *bool                  `json:"allow_negative_inventory"`
+	CostingMethod          string                 `json:"costing_method"`
+}
+
+func updateTenant(c *gin.Context) {
+	var req TenantUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_request")})
+		return
+	}
+	switch req.CostingMethod {
+	case "", constants.CostingMethodFIFO, constants.CostingMethodAverage:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_request")})
+		return
+	}
+	metaBytes, err := marshalMetadata(req.Metadata)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_request")})
+		return
+	}
+
+	var t models.Tenant
+	db := store.DB.GetMasterDB(c.Request.Context())
+	res := db.Raw(
+		`UPDATE tenants SET name=?,metadata=COALESCE(?,metadata),
+                allow_negative_inventory=COALESCE(?,allow_negative_inventory),
+                costing_method=COALESCE(NULLIF(?,''),costing_method),updated_at=?
+         WHERE id=? AND deleted_at IS NULL
+         RETURNING id,name,metadata,allow_negative_inventory,costing_method,created_at,updated_at,deleted_at`,
+		req.Name, metaBytes, req.AllowNegativeInventory, req.CostingMethod, time.Now().UTC(), c.Param("id"),
+	).Scan(&t)
+	if res.Error != nil {
+		catalogLogger.Errorf("updateTenant DB error: %v", res.Error)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.update_tenant_failed")})
+		return
+	}
+	if res.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.Translate(c, "error.tenant_not_found")})
+		return
+	}
+
+	c.JSON(http.StatusOK, t)
+}
+
+// deleteTenant soft deletes a tenant once its sellers, hubs, products, SKUs
+// and categories have been deleted.
+func deleteTenant(c *gin.Context) {
+	db := store.DB.GetMasterDB(c.Request.Context())
+	err := softDelete(db, "tenants", c.Param("id"),
+		[]string{"sellers.tenant_id", "hubs.tenant_id", "products.tenant_id", "skus.tenant_id", "categories.tenant_id"}, time.Now().UTC())
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.Translate(c, "error.tenant_not_found")})
+		return
+	case errors.Is(err, errInUse):
+		c.JSON(http.StatusConflict, gin.H{"error": i18n.Translate(c, "error.tenant_in_use")})
+		return
+	case err != nil:
+		catalogLogger.Errorf("deleteTenant DB error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.delete_tenant_failed")})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func listSellers(c *gin.Context) {
+	where := []string{"1=1"}
+	args := []interface{}{}
+	if tenantID := c.Query("tenant_id"); tenantID != "" {
+		where = append(where, "tenant_id = ?")
+		args = append(args, tenantID)
+	}
+	where, err := liveFilter(c, where)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_request")})
+		return
+	}
+
+	sqlStr := fmt.Sprintf(
+		`SELECT id,tenant_id,name,metadata,created_at,updated_at,deleted_at
+           FROM sellers WHERE %s ORDER BY name`, strings.Join(where, " AND "),
+	)
+
+	db := store.DB.GetSlaveDB(c.Request.Context())
+	rows, err := db.Raw(sqlStr, args...).Rows()
+	if err != nil {
+		catalogLogger.Errorf("listSellers DB error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.list_sellers_failed")})
+		return
+	}
+	defer rows.Close()
+
+	var sellers []models.Seller
+	for rows.Next() {
+		var s models.Seller
+		if err := db.ScanRows(rows, &s); err != nil {
+			catalogLogger.Errorf("listSellers scan error: %v", err)
+			continue
+		}
+		sellers = append(sellers, s)
+	}
+	c.JSON(http.StatusOK, sellers)
+}
+
+// SellerUpdateRequest renames a seller; metadata keeps its stored value when
+// left out.
+type SellerUpdateRequest struct {
+	Name     string                 `json:"name"     binding:"required"`
+	Metadata map[string]interface{} `json:"metadata"`
+}
+
+func updateSeller(c *gin.Context) {
+	var req SellerUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_request")})
+		return
+	}
+	metaBytes, err := marshalMetadata(req.Metadata)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_request")})
+		return
+	}
+
+	var s models.Seller
+	db := store.DB.GetMasterDB(c.Request.Context())
+	res := db.Raw(
+		`UPDATE sellers SET name=?,metadata=COALESCE(?,metadata),updated_at=?
+         WHERE id=? AND deleted_at IS NULL
+         RETURNING id,tenant_id,name,metadata,created_at,updated_at,deleted_at`,
+		req.Name, metaBytes, time.Now().UTC(), c.Param("id"),
+	).Scan(&s)
+	if res.Error != nil {
+		catalogLogger.Errorf("updateSeller DB error: %v", res.Error)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.update_seller_failed")})
+		return
+	}
+	if res.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.Translate(c, "error.seller_not_found")})
+		return
+	}
+
+	c.JSON(http.StatusOK, s)
+}
+
+// deleteSeller soft deletes a seller once its hubs, products and SKUs have
+// been deleted.
+func deleteSeller(c *gin.Context) {
+	db := store.DB.GetMasterDB(c.Request.Context())
+	err := softDelete(db, "sellers", c.Param("id"), []string{"hubs.seller_id", "products.seller_id", "skus.seller_id"}, time.Now().UTC())
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.Translate(c, "error.seller_not_found")})
+		return
+	case errors.Is(err, errInUse):
+		c.JSON(http.StatusConflict, gin.H{"error": i18n.Translate(c, "error.seller_in_use")})
+		return
+	case err != nil:
+		catalogLogger.Errorf("deleteSeller DB error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.delete_seller_failed")})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func listCategories(c *gin.Context) {
+	where := []string{"1=1"}
+	args := []interface{}{}
+	if tenantID := c.Query("tenant_id"); tenantID != "" {
+		where = append(where, "tenant_id = ?")
+		args = append(args, tenantID)
+	}
+	if parentID := c.Query("parent_id"); parentID != "" {
+		where = append(where, "parent_id = ?")
+		args = append(args, parentID)
+	}
+	where, err := liveFilter(c, where)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_request")})
+		return
+	}
+
+	sqlStr := fmt.Sprintf(
+		`SELECT id,tenant_id,parent_id,name,description,created_at,updated_at,deleted_at
+           FROM categories WHERE %s ORDER BY name`, strings.Join(where, " AND "),
+	)
+
+	db := store.DB.GetSlaveDB(c.Request.Context())
+	rows, err := db.Raw(sqlStr, args...).Rows()
+	if err != nil {
+		catalogLogger.Errorf("listCategories DB error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.list_categories_failed")})
+		return
+	}
+	defer rows.Close()
+
+	var cats []models.Category
+	for rows.Next() {
+		var cat models.Category
+		if err := db.ScanRows(rows, &cat); err != nil {
+			catalogLogger.Errorf("listCategories scan error: %v", err)
+			continue
+		}
+		cats = append(cats, cat)
+	}
+	c.JSON(http.StatusOK, cats)
+}
+
+// updateCategory renames or redescribes a category; POST /categories/:id/move
+// changes its parent.
+func updateCategory(c *gin.Context) {
+	var cat models.Category
+	if err := c.ShouldBindJSON(&cat); err != nil || cat.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_request")})
+		return
+	}
+
+	db := store.DB.GetMasterDB(c.Request.Context())
+	res := db.Raw(
+		`UPDATE categories SET name=?,description=?,updated_at=?
+         WHERE id=? AND deleted_at IS NULL
+         RETURNING id,tenant_id,parent_id,name,description,created_at,updated_at,deleted_at`,
+		cat.Name, cat.Description, time.Now().UTC(), c.Param("id"),
+	).Scan(&cat)
+	if res.Error != nil {
+		catalogLogger.Errorf("updateCategory DB error: %v", res.Error)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.update_category_failed")})
+		return
+	}
+	if res.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.Translate(c, "error.category_not_found")})
+		return
+	}
+
+	c.JSON(http.StatusOK, cat)
+}
+
+// deleteCategory soft deletes a category with no live subcategories and no
+// live product or SKU filed under it.
+func deleteCategory(c *gin.Context) {
+	db := store.DB.GetMasterDB(c.Request.Context())
+	err := softDelete(db, "categories", c.Param("id"), []string{"products.category_id", "skus.category_id", "categories.parent_id"}, time.Now().UTC())
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.Translate(c, "error.category_not_found")})
+		return
+	case errors.Is(err, errInUse):
+		c.JSON(http.StatusConflict, gin.H{"error": i18n.Translate(c, "error.category_in_use")})
+		return
+	case err != nil:
+		catalogLogger.Errorf("deleteCategory DB error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.delete_category_failed")})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,300 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/omniful/go_commons/db/sql/postgres"
+	"github.com/omniful/go_commons/log"
+
+	"github.com/abhirup.dandapat/ims/constants"
+	"github.com/abhirup.dandapat/ims/internal/models"
+	"github.com/abhirup.dandapat/ims/internal/store"
+)
+
+// errImportLeaseLost is returned when an import outlived its lease and was
+// claimed again before it could complete.
+var errImportLeaseLost = errors.New("catalog import lease lost")
+
+// claimedImport is a catalog_imports row claimed by an importer.
+type claimedImport struct {
+	ID       string `gorm:"column:id"`
+	TenantID string `gorm:"column:tenant_id"`
+	Payload  []byte `gorm:"column:payload"`
+	Attempts int    `gorm:"column:attempts"`
+}
+
+// importResult is what an import wrote, for its counters and for the caches
+// to drop once it is committed.
+type importResult struct {
+	created, updated int
+	updatedIDs       []string
+	barcodes         []models.SKUBarcode
+	failed           [][]string
+}
+
+// CatalogImporter runs queued catalog imports. It lives next to the handlers
+// so that it drops the same SKU and inventory cache entries they do; it needs
+// store.RedisClient as well as the database.
+type CatalogImporter struct {
+	db          *postgres.DbCluster
+	maxAttempts int
+	lease       time.Duration
+	logger      *log.Logger
+}
+
+func NewCatalogImporter(db *postgres.DbCluster, maxAttempts int) *CatalogImporter {
+	return &CatalogImporter{
+		db:          db,
+		maxAttempts: maxAttempts,
+		lease:       10 * time.Minute,
+		logger:      log.DefaultLogger(),
+	}
+}
+
+// Run polls for pending imports every interval until ctx is done.
+func (imp *CatalogImporter) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := imp.ImportPending(ctx); err != nil {
+			imp.logger.Errorf("run pending catalog imports: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ImportPending runs pending imports, oldest first, until none is left. A
+// claim sets lease_until, so concurrent importers skip imports in flight and
+// an import whose importer crashed is picked up again once its lease is over.
+func (imp *CatalogImporter) ImportPending(ctx context.Context) error {
+	for ctx.Err() == nil {
+		now := time.Now().UTC()
+		var jobs []claimedImport
+		if err := imp.db.GetMasterDB(ctx).Raw(
+			`UPDATE catalog_imports
+             SET status = ?, attempts = attempts + 1, lease_until = ?, started_at = COALESCE(started_at, ?), updated_at = ?
+             WHERE id = (
+                 SELECT id FROM catalog_imports
+                 WHERE status = ? OR (status = ? AND lease_until <= ?)
+                 ORDER BY created_at
+                 LIMIT 1
+                 FOR UPDATE SKIP LOCKED
+             )
+             RETURNING id,tenant_id,payload,attempts`,
+			constants.ImportStatusProcessing, now.Add(imp.lease), now, now,
+			constants.ImportStatusPending, constants.ImportStatusProcessing, now,
+		).Scan(&jobs).Error; err != nil {
+			return err
+		}
+		if len(jobs) == 0 {
+			return nil
+		}
+		imp.process(ctx, jobs[0])
+	}
+	return ctx.Err()
+}
+
+func (imp *CatalogImporter) process(ctx context.Context, job claimedImport) {
+	if job.Attempts > imp.maxAttempts {
+		imp.release(ctx, job, constants.ImportStatusFailed, fmt.Sprintf("import did not finish in %d attempts", imp.maxAttempts))
+		return
+	}
+	var rows []CatalogImportRow
+	if err := json.Unmarshal(job.Payload, &rows); err != nil {
+		imp.release(ctx, job, constants.ImportStatusFailed, "unreadable payload: "+err.Error())
+		return
+	}
+
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	res, err := imp.importRows(ctx, job, rows, now)
+	switch {
+	case errors.Is(err, errImportLeaseLost):
+		imp.logger.Warnf("catalog import %s was claimed again before it completed", job.ID)
+		return
+	case err != nil:
+		imp.logger.Errorf("catalog import %s attempt %d: %v", job.ID, job.Attempts, err)
+		status := constants.ImportStatusPending
+		if job.Attempts >= imp.maxAttempts {
+			status = constants.ImportStatusFailed
+		}
+		imp.release(ctx, job, status, err.Error())
+		return
+	}
+
+	for _, id := range res.updatedIDs {
+		_, _ = store.RedisClient.Del(ctx, "sku:"+id)
+	}
+	forgetBarcodes(ctx, res.barcodes)
+	if res.created+res.updated > 0 {
+		invalidateInventoryCache(ctx, job.TenantID, now)
+	}
+	imp.logger.Infof("catalog import %s completed: %d created, %d updated, %d failed",
+		job.ID, res.created, res.updated, len(res.failed))
+}
+
+// importRows writes an import's valid rows and completes it, in one
+// transaction so that a retried import starts from scratch. A row creates
+// the tenant's live SKU with its code or replaces that SKU's fields like PUT
+// /skus/:id; base_uom is only set on create, a variant keeps its product's
+// category, is_serialized is kept unless the row gives it and cannot change
+// once the SKU has stock or serials or is part of a kit, and a SKU of another
+// seller is left alone. The seller, and the
+// category if any, must be live rows of the tenant. Each row is written under
+// a savepoint, so a row the database refuses is reported and the rest go in.
+func (imp *CatalogImporter) importRows(ctx context.Context, job claimedImport, rows []CatalogImportRow, now time.Time) (importResult, error) {
+	var res importResult
+	tx := imp.db.GetMasterDB(ctx).Begin()
+	if tx.Error != nil {
+		return res, tx.Error
+	}
+	defer tx.Rollback()
+
+	sellers, err := liveIDs(tx, "sellers", job.TenantID)
+	if err != nil {
+		return res, err
+	}
+	categories, err := liveIDs(tx, "categories", job.TenantID)
+	if err != nil {
+		return res, err
+	}
+
+	firstRow := map[string]int{}
+	for _, r := range rows {
+		s, err := checkImportRow(r)
+		switch {
+		case err != nil:
+		case firstRow[s.Code] != 0:
+			err = fmt.Errorf("code repeats row %d", firstRow[s.Code])
+		case !sellers[s.SellerID]:
+			err = errors.New("seller_id is not a seller of the tenant")
+		case s.CategoryID != "" && !categories[s.CategoryID]:
+			err = errors.New("category_id is not a category of the tenant")
+		}
+		if err != nil {
+			res.failed = append(res.failed, r.errorRecord(err.Error()))
+			continue
+		}
+		firstRow[s.Code] = r.Row
+
+		var categoryID *string
+		if s.CategoryID != "" {
+			categoryID = &s.CategoryID
+		}
+		var serialized *bool
+		if strings.TrimSpace(string(r.IsSerialized)) != "" {
+			serialized = &s.IsSerialized
+		}
+		var written []struct {
+			ID       string `gorm:"column:id"`
+			Inserted bool   `gorm:"column:inserted"`
+		}
+		if err := tx.SavePoint("import_row").Error; err != nil {
+			return res, err
+		}
+		if err := tx.Raw(
+			`INSERT INTO skus(id,tenant_id,seller_id,code,name,description,category_id,weight,weight_unit,length,width,height,is_serialized,base_uom,created_at,updated_at)
+             VALUES(?,?,?,?,?,?,?,?,?,?,?,?,COALESCE(?,FALSE),?,?,?)
+             ON CONFLICT (tenant_id,code) WHERE deleted_at IS NULL DO UPDATE
+             SET name=EXCLUDED.name,description=EXCLUDED.description,
+                 category_id=CASE WHEN skus.product_id IS NULL THEN EXCLUDED.category_id ELSE skus.category_id END,
+                 weight=EXCLUDED.weight,weight_unit=EXCLUDED.weight_unit,length=EXCLUDED.length,
+                 width=EXCLUDED.width,height=EXCLUDED.height,is_serialized=COALESCE(?,skus.is_serialized),
+                 updated_at=EXCLUDED.updated_at
+             WHERE skus.seller_id = EXCLUDED.seller_id
+               AND (COALESCE(?,skus.is_serialized) = skus.is_serialized OR NOT `+serialFlagFixed+`)
+             RETURNING id,(xmax = 0) AS inserted`,
+			uuid.New().String(), job.TenantID, s.SellerID, s.Code, s.Name, s.Description,
+			categoryID, s.Weight, s.WeightUnit, s.Length, s.Width, s.Height,
+			serialized, s.BaseUOM, now, now, serialized, serialized,
+		).Scan(&written).Error; err != nil {
+			if err := tx.RollbackTo("import_row").Error; err != nil {
+				return res, err
+			}
+			res.failed = append(res.failed, r.errorRecord(err.Error()))
+			continue
+		}
+		if len(written) == 0 {
+			var sameSeller bool
+			if err := tx.Raw(
+				`SELECT EXISTS(SELECT 1 FROM skus WHERE tenant_id = ? AND code = ? AND seller_id = ? AND deleted_at IS NULL)`,
+				job.TenantID, s.Code, s.SellerID,
+			).Scan(&sameSeller).Error; err != nil {
+				return res, err
+			}
+			reason := "code belongs to a SKU of another seller"
+			if sameSeller {
+				reason = "is_serialized cannot change while the SKU has stock or serials or is part of a kit"
+			}
+			res.failed = append(res.failed, r.errorRecord(reason))
+			continue
+		}
+		switch {
+		case written[0].Inserted:
+			res.created++
+		default:
+			res.updated++
+			res.updatedIDs = append(res.updatedIDs, written[0].ID)
+		}
+	}
+
+	// Every SKU the import updated has updated_at = now; their barcode
+	// lookups are dropped from the cache with them.
+	if res.updated > 0 {
+		if err := tx.Raw(
+			`SELECT b.tenant_id,b.sku_id,b.barcode,b.barcode_type,b.gtin,b.created_at
+             FROM sku_barcodes b JOIN skus s ON s.id = b.sku_id
+             WHERE s.tenant_id = ? AND s.updated_at = ? AND s.deleted_at IS NULL`,
+			job.TenantID, now,
+		).Scan(&res.barcodes).Error; err != nil {
+			return res, err
+		}
+	}
+
+	report, err := errorReport(res.failed)
+	if err != nil {
+		return res, err
+	}
+	done := tx.Exec(
+		`UPDATE catalog_imports
+         SET status = ?, created_rows = ?, updated_rows = ?, failed_rows = ?, error_report = ?,
+             last_error = NULL, lease_until = NULL, finished_at = ?, updated_at = ?
+         WHERE id = ? AND status = ? AND attempts = ?`,
+		constants.ImportStatusCompleted, res.created, res.updated, len(res.failed), report,
+		now, now, job.ID, constants.ImportStatusProcessing, job.Attempts,
+	)
+	if done.Error != nil {
+		return res, done.Error
+	}
+	if done.RowsAffected == 0 {
+		return res, errImportLeaseLost
+	}
+	return res, tx.Commit().Error
+}
+
+// release hands a claimed import back: pending to be retried on a later
+// poll, or failed for good.
+func (imp *CatalogImporter) release(ctx context.Context, job claimedImport, status, errMsg string) {
+	now := time.Now().UTC()
+	var finishedAt *time.Time
+	if status == constants.ImportStatusFailed {
+		finishedAt = &now
+	}
+	if err := imp.db.GetMasterDB(ctx).Exec(
+		`UPDATE catalog_imports
+         SET status = ?, last_error = ?, lease_until = NULL, finished_at = ?, updated_at = ?
+         WHERE id = ? AND status = ? AND attempts = ?`,
+		status, errMsg, finishedAt, now, job.ID, constants.ImportStatusProcessing, job.Attempts,
+	).Error; err != nil {
+		imp.logger.Errorf("release catalog import %s as %s: %v", job.ID, status, err)
+	}
+}
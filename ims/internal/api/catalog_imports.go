@@ -0,0 +1,479 @@
+package api
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"mime/multipart"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/omniful/go_commons/i18n"
+	"github.com/omniful/go_commons/log"
+
+	"github.com/abhirup.dandapat/ims/constants"
+	"github.com/abhirup.dandapat/ims/internal/models"
+	"github.com/abhirup.dandapat/ims/internal/store"
+)
+
+var importLogger = log.DefaultLogger()
+
+var (
+	// errInvalidImportFile is returned for an upload that is not a CSV file
+	// with code, name and seller_id columns, or a JSON array of SKU objects.
+	errInvalidImportFile = errors.New("invalid import file")
+	// errEmptyImport is returned for an upload without any SKU rows.
+	errEmptyImport = errors.New("import has no rows")
+	// errImportTooLarge is returned for an upload of more than
+	// constants.MaxCatalogImportRows rows.
+	errImportTooLarge = errors.New("import has too many rows")
+)
+
+const (
+	defaultImportPageSize = 100
+	maxImportPageSize     = 1000
+)
+
+const catalogImportColumns = `id,tenant_id,format,file_name,status,total_rows,created_rows,updated_rows,failed_rows,attempts,last_error,created_at,updated_at,started_at,finished_at`
+
+// importFields are the SKU fields an import row may set, in the order of the
+// error report. code, name and seller_id are required.
+var importFields = []string{
+	"code", "name", "seller_id", "description", "category_id",
+	"weight", "weight_unit", "length", "width", "height", "is_serialized", "base_uom",
+}
+
+// importValue is a field of an import row as it was uploaded. In JSON it may
+// be a string, a number, a bool or null, so a bad value fails its row rather
+// than the whole upload.
+type importValue string
+
+func (v *importValue) UnmarshalJSON(b []byte) error {
+	switch {
+	case string(b) == "null":
+		*v = ""
+	case len(b) > 0 && b[0] == '"':
+		var s string
+		if err := json.Unmarshal(b, &s); err != nil {
+			return err
+		}
+		*v = importValue(s)
+	case len(b) > 0 && (b[0] == '{' || b[0] == '['):
+		return errInvalidImportFile
+	default:
+		*v = importValue(b)
+	}
+	return nil
+}
+
+// CatalogImportRow is one SKU of an import. Row is its 1-based position among
+// the uploaded rows, not counting a CSV header.
+type CatalogImportRow struct {
+	Row          int         `json:"row"`
+	Code         importValue `json:"code"`
+	Name         importValue `json:"name"`
+	SellerID     importValue `json:"seller_id"`
+	Description  importValue `json:"description"`
+	CategoryID   importValue `json:"category_id"`
+	Weight       importValue `json:"weight"`
+	WeightUnit   importValue `json:"weight_unit"`
+	Length       importValue `json:"length"`
+	Width        importValue `json:"width"`
+	Height       importValue `json:"height"`
+	IsSerialized importValue `json:"is_serialized"`
+	BaseUOM      importValue `json:"base_uom"`
+}
+
+// field returns the row's value of one of importFields, or nil for any other
+// name.
+func (r *CatalogImportRow) field(name string) *importValue {
+	switch name {
+	case "code":
+		return &r.Code
+	case "name":
+		return &r.Name
+	case "seller_id":
+		return &r.SellerID
+	case "description":
+		return &r.Description
+	case "category_id":
+		return &r.CategoryID
+	case "weight":
+		return &r.Weight
+	case "weight_unit":
+		return &r.WeightUnit
+	case "length":
+		return &r.Length
+	case "width":
+		return &r.Width
+	case "height":
+		return &r.Height
+	case "is_serialized":
+		return &r.IsSerialized
+	case "base_uom":
+		return &r.BaseUOM
+	}
+	return nil
+}
+
+// errorRecord is the row's line in the error report.
+func (r CatalogImportRow) errorRecord(reason string) []string {
+	rec := []string{strconv.Itoa(r.Row)}
+	for _, f := range importFields {
+		rec = append(rec, string(*r.field(f)))
+	}
+	return append(rec, reason)
+}
+
+// errorReport renders failed rows as CSV: the row number, the row's fields
+// as uploaded and why it was not imported.
+func errorReport(records [][]string) (string, error) {
+	buf := &bytes.Buffer{}
+	w := csv.NewWriter(buf)
+	header := append([]string{"row"}, importFields...)
+	if err := w.Write(append(header, "error")); err != nil {
+		return "", err
+	}
+	if err := w.WriteAll(records); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// numberImportRows checks the size of an upload and numbers its rows.
+func numberImportRows(rows []CatalogImportRow) ([]CatalogImportRow, error) {
+	switch {
+	case len(rows) == 0:
+		return nil, errEmptyImport
+	case len(rows) > constants.MaxCatalogImportRows:
+		return nil, errImportTooLarge
+	}
+	for i := range rows {
+		rows[i].Row = i + 1
+	}
+	return rows, nil
+}
+
+// readImportCSV reads a CSV upload. The header names the columns, in any
+// order; columns other than importFields are ignored.
+func readImportCSV(data []byte) ([]CatalogImportRow, error) {
+	r := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))))
+	r.TrimLeadingSpace = true
+	records, err := r.ReadAll()
+	if err != nil || len(records) == 0 {
+		return nil, errInvalidImportFile
+	}
+
+	header := records[0]
+	seen := map[string]bool{}
+	for i, col := range header {
+		header[i] = strings.ToLower(strings.TrimSpace(col))
+		if seen[header[i]] {
+			return nil, errInvalidImportFile
+		}
+		seen[header[i]] = true
+	}
+	for _, col := range []string{"code", "name", "seller_id"} {
+		if !seen[col] {
+			return nil, errInvalidImportFile
+		}
+	}
+
+	rows := make([]CatalogImportRow, len(records)-1)
+	for i, rec := range records[1:] {
+		for j, col := range header {
+			if v := rows[i].field(col); v != nil {
+				*v = importValue(rec[j])
+			}
+		}
+	}
+	return numberImportRows(rows)
+}
+
+// readImportJSON reads a JSON upload: an array of objects keyed by
+// importFields.
+func readImportJSON(data []byte) ([]CatalogImportRow, error) {
+	var rows []CatalogImportRow
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, errInvalidImportFile
+	}
+	return numberImportRows(rows)
+}
+
+func readImportFile(fh *multipart.FileHeader, format string) ([]CatalogImportRow, error) {
+	f, err := fh.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+
+	switch format {
+	case constants.ImportFormatCSV:
+		return readImportCSV(data)
+	case constants.ImportFormatJSON:
+		return readImportJSON(data)
+	}
+	return nil, errInvalidImportFile
+}
+
+// checkImportRow validates a row and returns the SKU it describes. The error
+// says what is wrong with the row; it goes into the error report.
+func checkImportRow(r CatalogImportRow) (models.SKU, error) {
+	s := models.SKU{
+		Code:        strings.TrimSpace(string(r.Code)),
+		Name:        strings.TrimSpace(string(r.Name)),
+		SellerID:    strings.TrimSpace(string(r.SellerID)),
+		Description: strings.TrimSpace(string(r.Description)),
+		CategoryID:  strings.TrimSpace(string(r.CategoryID)),
+		WeightUnit:  strings.TrimSpace(string(r.WeightUnit)),
+		BaseUOM:     strings.TrimSpace(string(r.BaseUOM)),
+	}
+	for _, f := range []struct {
+		name  string
+		value string
+	}{{"code", s.Code}, {"name", s.Name}, {"seller_id", s.SellerID}} {
+		if f.value == "" {
+			return s, fmt.Errorf("%s is required", f.name)
+		}
+	}
+
+	for _, f := range []struct {
+		name  string
+		value importValue
+		dst   *float64
+	}{{"weight", r.Weight, &s.Weight}, {"length", r.Length, &s.Length}, {"width", r.Width, &s.Width}, {"height", r.Height, &s.Height}} {
+		v := strings.TrimSpace(string(f.value))
+		if v == "" {
+			continue
+		}
+		n, err := strconv.ParseFloat(v, 64)
+		if err != nil || n < 0 || math.IsInf(n, 0) || math.IsNaN(n) {
+			return s, fmt.Errorf("%s must be a non-negative number", f.name)
+		}
+		*f.dst = n
+	}
+
+	if v := strings.TrimSpace(string(r.IsSerialized)); v != "" {
+		serialized, err := strconv.ParseBool(v)
+		if err != nil {
+			return s, errors.New("is_serialized must be true or false")
+		}
+		s.IsSerialized = serialized
+	}
+	if s.BaseUOM == "" {
+		s.BaseUOM = constants.DefaultBaseUOM
+	}
+	return s, nil
+}
+
+// CatalogImportRequest submits an import as JSON instead of as a file.
+type CatalogImportRequest struct {
+	TenantID string             `json:"tenant_id" binding:"required"`
+	SKUs     []CatalogImportRow `json:"skus"`
+}
+
+// createCatalogImport queues a bulk SKU import for the importer
+// (cmd/importer). It takes either a multipart form with tenant_id and a CSV
+// or JSON file, the format taken from the format field or else the file's
+// extension, or a CatalogImportRequest body. Only the shape of the upload is
+// checked here; rows are validated when the import runs.
+func createCatalogImport(c *gin.Context) {
+	var (
+		tenantID, format string
+		fileName         *string
+		rows             []CatalogImportRow
+		err              error
+	)
+	if c.ContentType() == "multipart/form-data" {
+		fh, ferr := c.FormFile("file")
+		tenantID = c.PostForm("tenant_id")
+		if ferr != nil || tenantID == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_request")})
+			return
+		}
+		format = c.PostForm("format")
+		if format == "" {
+			format = constants.ImportFormatCSV
+			if strings.EqualFold(path.Ext(fh.Filename), ".json") {
+				format = constants.ImportFormatJSON
+			}
+		}
+		fileName = &fh.Filename
+		rows, err = readImportFile(fh, format)
+	} else {
+		var req CatalogImportRequest
+		if berr := c.ShouldBindJSON(&req); berr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_request")})
+			return
+		}
+		tenantID, format = req.TenantID, constants.ImportFormatJSON
+		rows, err = numberImportRows(req.SKUs)
+	}
+	switch {
+	case errors.Is(err, errInvalidImportFile):
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_import_file")})
+		return
+	case errors.Is(err, errEmptyImport):
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.empty_import")})
+		return
+	case errors.Is(err, errImportTooLarge):
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.import_too_large")})
+		return
+	case err != nil:
+		importLogger.Errorf("createCatalogImport read error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.create_catalog_import_failed")})
+		return
+	}
+
+	payload, err := json.Marshal(rows)
+	if err != nil {
+		importLogger.Errorf("createCatalogImport marshal error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.create_catalog_import_failed")})
+		return
+	}
+
+	now := time.Now().UTC()
+	imp := models.CatalogImport{
+		ID: uuid.New().String(), TenantID: tenantID, Format: format, FileName: fileName,
+		Status: constants.ImportStatusPending, TotalRows: len(rows), CreatedAt: now, UpdatedAt: now,
+	}
+	db := store.DB.GetMasterDB(c.Request.Context())
+	var live bool
+	if err := db.Raw(
+		`SELECT EXISTS(SELECT 1 FROM tenants WHERE id = ? AND deleted_at IS NULL)`, tenantID,
+	).Scan(&live).Error; err != nil {
+		importLogger.Errorf("createCatalogImport DB error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.create_catalog_import_failed")})
+		return
+	}
+	if !live {
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.Translate(c, "error.tenant_not_found")})
+		return
+	}
+	if err := db.Exec(
+		`INSERT INTO catalog_imports(id,tenant_id,format,file_name,status,payload,total_rows,created_at,updated_at)
+         VALUES(?,?,?,?,?,?,?,?,?)`,
+		imp.ID, imp.TenantID, imp.Format, imp.FileName, imp.Status, string(payload), imp.TotalRows, imp.CreatedAt, imp.UpdatedAt,
+	).Error; err != nil {
+		importLogger.Errorf("createCatalogImport DB error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.create_catalog_import_failed")})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, imp)
+}
+
+func getCatalogImport(c *gin.Context) {
+	var imp models.CatalogImport
+	db := store.DB.GetSlaveDB(c.Request.Context())
+	res := db.Raw(`SELECT `+catalogImportColumns+` FROM catalog_imports WHERE id = ?`, c.Param("id")).Scan(&imp)
+	if res.Error != nil {
+		importLogger.Errorf("getCatalogImport DB error: %v", res.Error)
+	}
+	if res.Error != nil || res.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.Translate(c, "error.catalog_import_not_found")})
+		return
+	}
+	c.JSON(http.StatusOK, imp)
+}
+
+// listCatalogImports pages through a tenant's imports, newest first.
+func listCatalogImports(c *gin.Context) {
+	tenantID := c.Query("tenant_id")
+	if tenantID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_request")})
+		return
+	}
+	where := []string{"tenant_id = ?"}
+	args := []interface{}{tenantID}
+	if status := c.Query("status"); status != "" {
+		where = append(where, "status = ?")
+		args = append(args, status)
+	}
+	limit, err := parsePageLimit(c.Query("limit"), defaultImportPageSize, maxImportPageSize)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_request")})
+		return
+	}
+	if v := c.Query("cursor"); v != "" {
+		createdAt, id, err := decodeCreatedAtCursor(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_request")})
+			return
+		}
+		where = append(where, "(created_at, id) < (?, ?)")
+		args = append(args, createdAt, id)
+	}
+	args = append(args, limit+1)
+
+	db := store.DB.GetSlaveDB(c.Request.Context())
+	rows, err := db.Raw(
+		`SELECT `+catalogImportColumns+` FROM catalog_imports WHERE `+strings.Join(where, " AND ")+`
+         ORDER BY created_at DESC, id DESC LIMIT ?`, args...,
+	).Rows()
+	if err != nil {
+		importLogger.Errorf("listCatalogImports DB error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.list_catalog_imports_failed")})
+		return
+	}
+	defer rows.Close()
+
+	imports := []models.CatalogImport{}
+	for rows.Next() {
+		var imp models.CatalogImport
+		if err := db.ScanRows(rows, &imp); err != nil {
+			importLogger.Warnf("scan catalog_import row: %v", err)
+			continue
+		}
+		imports = append(imports, imp)
+	}
+
+	resp := gin.H{"imports": imports}
+	if len(imports) > limit {
+		imports = imports[:limit]
+		last := imports[limit-1]
+		resp["imports"] = imports
+		resp["next_cursor"] = encodeCreatedAtCursor(last.CreatedAt, last.ID)
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// getCatalogImportErrors downloads the error report of a completed import:
+// a CSV of the rows that were not imported, with the reason for each. It has
+// only the header when every row was imported.
+func getCatalogImportErrors(c *gin.Context) {
+	var job struct {
+		Status      string  `gorm:"column:status"`
+		ErrorReport *string `gorm:"column:error_report"`
+	}
+	id := c.Param("id")
+	db := store.DB.GetSlaveDB(c.Request.Context())
+	res := db.Raw(`SELECT status,error_report FROM catalog_imports WHERE id = ?`, id).Scan(&job)
+	if res.Error != nil {
+		importLogger.Errorf("getCatalogImportErrors DB error: %v", res.Error)
+	}
+	if res.Error != nil || res.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.Translate(c, "error.catalog_import_not_found")})
+		return
+	}
+	if job.Status != constants.ImportStatusCompleted || job.ErrorReport == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": i18n.Translate(c, "error.catalog_import_not_completed")})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s-errors.csv", id))
+	c.Data(http.StatusOK, "text/csv", []byte(*job.ErrorReport))
+}
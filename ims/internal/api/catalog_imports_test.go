@@ -0,0 +1,105 @@
+package api
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReadImportCSV(t *testing.T) {
+	data := "\xef\xbb\xbfCode, name,seller_id,weight,notes\n" +
+		"TEE-S,\"Tee, small\",s1,0.2,ignored\n" +
+		"TEE-M,Tee medium,s1,,\n"
+	rows, err := readImportCSV([]byte(data))
+	if err != nil {
+		t.Fatalf("readImportCSV error = %v", err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("readImportCSV read %d rows, want 2", len(rows))
+	}
+	if r := rows[0]; r.Row != 1 || r.Code != "TEE-S" || r.Name != "Tee, small" || r.SellerID != "s1" || r.Weight != "0.2" {
+		t.Errorf("first row = %+v", r)
+	}
+	if rows[1].Row != 2 || rows[1].Weight != "" {
+		t.Errorf("second row = %+v", rows[1])
+	}
+
+	bad := map[string]string{
+		"no seller_id column": "code,name\nA,B\n",
+		"repeated column":     "code,name,seller_id,code\nA,B,s1,A\n",
+		"ragged row":          "code,name,seller_id\nA,B\n",
+		"empty file":          "",
+	}
+	for name, data := range bad {
+		if _, err := readImportCSV([]byte(data)); err != errInvalidImportFile {
+			t.Errorf("%s: readImportCSV error = %v, want errInvalidImportFile", name, err)
+		}
+	}
+	if _, err := readImportCSV([]byte("code,name,seller_id\n")); err != errEmptyImport {
+		t.Errorf("header only: readImportCSV error = %v, want errEmptyImport", err)
+	}
+}
+
+func TestReadImportJSON(t *testing.T) {
+	rows, err := readImportJSON([]byte(`[{"code":"A","name":"B","seller_id":"s1","weight":1.5,"is_serialized":true,"description":null}]`))
+	if err != nil {
+		t.Fatalf("readImportJSON error = %v", err)
+	}
+	if r := rows[0]; r.Row != 1 || r.Weight != "1.5" || r.IsSerialized != "true" || r.Description != "" {
+		t.Errorf("row = %+v", r)
+	}
+
+	for _, data := range []string{`{"code":"A"}`, `[{"code":{"x":1}}]`, `not json`} {
+		if _, err := readImportJSON([]byte(data)); err != errInvalidImportFile {
+			t.Errorf("readImportJSON(%s) error = %v, want errInvalidImportFile", data, err)
+		}
+	}
+	if _, err := readImportJSON([]byte(`[]`)); err != errEmptyImport {
+		t.Errorf("readImportJSON([]) error = %v, want errEmptyImport", err)
+	}
+}
+
+func TestCheckImportRow(t *testing.T) {
+	valid := CatalogImportRow{Code: " A ", Name: "B", SellerID: "s1", Weight: "2", IsSerialized: "1"}
+	s, err := checkImportRow(valid)
+	if err != nil {
+		t.Fatalf("checkImportRow error = %v", err)
+	}
+	if s.Code != "A" || s.Weight != 2 || !s.IsSerialized || s.BaseUOM != "each" {
+		t.Errorf("checkImportRow = %+v", s)
+	}
+
+	cases := []struct {
+		name string
+		edit func(r *CatalogImportRow)
+		want string
+	}{
+		{"missing code", func(r *CatalogImportRow) { r.Code = " " }, "code is required"},
+		{"missing seller", func(r *CatalogImportRow) { r.SellerID = "" }, "seller_id is required"},
+		{"negative height", func(r *CatalogImportRow) { r.Height = "-1" }, "height must be a non-negative number"},
+		{"bad weight", func(r *CatalogImportRow) { r.Weight = "heavy" }, "weight must be a non-negative number"},
+		{"bad flag", func(r *CatalogImportRow) { r.IsSerialized = "maybe" }, "is_serialized must be true or false"},
+	}
+	for _, tc := range cases {
+		r := valid
+		tc.edit(&r)
+		if _, err := checkImportRow(r); err == nil || err.Error() != tc.want {
+			t.Errorf("%s: checkImportRow error = %v, want %q", tc.name, err, tc.want)
+		}
+	}
+}
+
+func TestErrorReport(t *testing.T) {
+	r := CatalogImportRow{Row: 3, Code: "A", Name: "Tee, large", SellerID: "s1"}
+	report, err := errorReport([][]string{r.errorRecord("seller_id is not a seller of the tenant")})
+	if err != nil {
+		t.Fatalf("errorReport error = %v", err)
+	}
+	want := "row,code,name,seller_id,description,category_id,weight,weight_unit,length,width,height,is_serialized,base_uom,error\n" +
+		"3,A,\"Tee, large\",s1,,,,,,,,,,seller_id is not a seller of the tenant\n"
+	if report != want {
+		t.Errorf("errorReport =\n%s\nwant\n%s", report, want)
+	}
+	if header, _ := errorReport(nil); strings.Count(header, "\n") != 1 {
+		t.Errorf("errorReport(nil) = %q, want the header only", header)
+	}
+}
@@ -0,0 +1,204 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/omniful/go_commons/i18n"
+	"github.com/omniful/go_commons/log"
+	"gorm.io/gorm"
+
+	"github.com/abhirup.dandapat/ims/internal/models"
+	"github.com/abhirup.dandapat/ims/internal/store"
+)
+
+var categoryLogger = log.DefaultLogger()
+
+// errInvalidParent is returned for a parent category that is missing, deleted,
+// of another tenant, or inside the subtree being moved under it.
+var errInvalidParent = errors.New("invalid parent category")
+
+const categoryColumns = `id,tenant_id,parent_id,name,description,created_at,updated_at`
+
+// categoryDescendantsSQL selects the id of a category and of every live
+// category below it; it takes the category id as its only argument.
+const categoryDescendantsSQL = `WITH RECURSIVE sub AS (
+                                   SELECT id FROM categories WHERE id = ?
+                                   UNION ALL
+                                   SELECT c.id FROM categories c JOIN sub ON c.parent_id = sub.id
+                                   WHERE c.deleted_at IS NULL
+                               )
+                               SELECT id FROM sub`
+
+// buildCategoryTree nests the categories of a subtree under rootID. Each
+// level keeps the order the rows came in.
+func buildCategoryTree(rootID string, cats []models.Category) (models.CategoryNode, bool) {
+	children := make(map[string][]models.Category, len(cats))
+	var root *models.Category
+	for i, cat := range cats {
+		if cat.ID == rootID {
+			root = &cats[i]
+			continue
+		}
+		if cat.ParentID != nil {
+			children[*cat.ParentID] = append(children[*cat.ParentID], cat)
+		}
+	}
+	if root == nil {
+		return models.CategoryNode{}, false
+	}
+
+	var build func(cat models.Category) models.CategoryNode
+	build = func(cat models.Category) models.CategoryNode {
+		node := models.CategoryNode{Category: cat, Children: []models.CategoryNode{}}
+		for _, child := range children[cat.ID] {
+			node.Children = append(node.Children, build(child))
+		}
+		return node
+	}
+	return build(*root), true
+}
+
+// getCategoryTree returns a category with every live category below it,
+// nested, children ordered by name.
+func getCategoryTree(c *gin.Context) {
+	id := c.Param("id")
+
+	var cats []models.Category
+	db := store.DB.GetSlaveDB(c.Request.Context())
+	if err := db.Raw(
+		`WITH RECURSIVE tree AS (
+             SELECT `+categoryColumns+` FROM categories WHERE id = ? AND deleted_at IS NULL
+             UNION ALL
+             SELECT c.id,c.tenant_id,c.parent_id,c.name,c.description,c.created_at,c.updated_at
+             FROM categories c JOIN tree t ON c.parent_id = t.id
+             WHERE c.deleted_at IS NULL
+         )
+         SELECT `+categoryColumns+` FROM tree ORDER BY name, id`, id,
+	).Scan(&cats).Error; err != nil {
+		categoryLogger.Errorf("getCategoryTree DB error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.category_tree_failed")})
+		return
+	}
+
+	tree, ok := buildCategoryTree(id, cats)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.Translate(c, "error.category_not_found")})
+		return
+	}
+	c.JSON(http.StatusOK, tree)
+}
+
+// getCategoryBreadcrumbs returns the path from the root down to a category,
+// the category itself last.
+func getCategoryBreadcrumbs(c *gin.Context) {
+	var cats []models.Category
+	db := store.DB.GetSlaveDB(c.Request.Context())
+	if err := db.Raw(
+		`WITH RECURSIVE chain AS (
+             SELECT `+categoryColumns+`, 0 AS depth FROM categories WHERE id = ? AND deleted_at IS NULL
+             UNION ALL
+             SELECT c.id,c.tenant_id,c.parent_id,c.name,c.description,c.created_at,c.updated_at, ch.depth + 1
+             FROM categories c JOIN chain ch ON c.id = ch.parent_id
+         )
+         SELECT `+categoryColumns+` FROM chain ORDER BY depth DESC`, c.Param("id"),
+	).Scan(&cats).Error; err != nil {
+		categoryLogger.Errorf("getCategoryBreadcrumbs DB error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.category_tree_failed")})
+		return
+	}
+	if len(cats) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.Translate(c, "error.category_not_found")})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"breadcrumbs": cats})
+}
+
+// CategoryMoveRequest moves a category, with its subtree, under another
+// parent; a null or empty parent_id makes it a root.
+type CategoryMoveRequest struct {
+	ParentID *string `json:"parent_id"`
+}
+
+func moveCategory(c *gin.Context) {
+	var req CategoryMoveRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_request")})
+		return
+	}
+	if req.ParentID != nil && *req.ParentID == "" {
+		req.ParentID = nil
+	}
+
+	tx := store.DB.GetMasterDB(c.Request.Context()).Begin()
+	if tx.Error != nil {
+		categoryLogger.Errorf("moveCategory begin tx error: %v", tx.Error)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.move_category_failed")})
+		return
+	}
+	defer tx.Rollback()
+
+	cat, err := reparentCategory(tx, c.Param("id"), req.ParentID, time.Now().UTC())
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.Translate(c, "error.category_not_found")})
+		return
+	case errors.Is(err, errInvalidParent):
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_category_parent")})
+		return
+	case err != nil:
+		categoryLogger.Errorf("moveCategory error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.move_category_failed")})
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		categoryLogger.Errorf("moveCategory commit error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.move_category_failed")})
+		return
+	}
+	c.JSON(http.StatusOK, cat)
+}
+
+// reparentCategory sets the parent of category id. Moves within a tenant are
+// serialized on the tenant row, so two concurrent moves cannot close a cycle
+// that neither sees on its own; NO KEY UPDATE leaves inserts that reference
+// the tenant unblocked.
+func reparentCategory(tx *gorm.DB, id string, parentID *string, now time.Time) (models.Category, error) {
+	var cat models.Category
+	res := tx.Raw(`SELECT `+categoryColumns+` FROM categories WHERE id = ? AND deleted_at IS NULL`, id).Scan(&cat)
+	if res.Error != nil {
+		return cat, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return cat, gorm.ErrRecordNotFound
+	}
+	var locked string
+	if err := tx.Raw(`SELECT id FROM tenants WHERE id = ? FOR NO KEY UPDATE`, cat.TenantID).Scan(&locked).Error; err != nil {
+		return cat, err
+	}
+
+	if parentID != nil {
+		var ok bool
+		if err := tx.Raw(
+			`SELECT EXISTS(SELECT 1 FROM categories WHERE id = ? AND tenant_id = ? AND deleted_at IS NULL)
+                AND CAST(? AS UUID) NOT IN (`+categoryDescendantsSQL+`)`,
+			*parentID, cat.TenantID, *parentID, id,
+		).Scan(&ok).Error; err != nil {
+			return cat, err
+		}
+		if !ok {
+			return cat, errInvalidParent
+		}
+	}
+
+	if err := tx.Exec(
+		`UPDATE categories SET parent_id = ?, updated_at = ? WHERE id = ?`, parentID, now, id,
+	).Error; err != nil {
+		return cat, err
+	}
+	cat.ParentID, cat.UpdatedAt = parentID, now
+	return cat, nil
+}
@@ -0,0 +1,35 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/abhirup.dandapat/ims/internal/models"
+)
+
+func TestBuildCategoryTree(t *testing.T) {
+	parent := func(id string) *string { return &id }
+	cats := []models.Category{
+		{ID: "apparel", Name: "Apparel", ParentID: parent("root")},
+		{ID: "men", Name: "Men", ParentID: parent("apparel")},
+		{ID: "shirts", Name: "Shirts", ParentID: parent("men")},
+		{ID: "women", Name: "Women", ParentID: parent("apparel")},
+	}
+
+	tree, ok := buildCategoryTree("apparel", cats)
+	if !ok {
+		t.Fatal("buildCategoryTree() found no root")
+	}
+	if tree.ID != "apparel" || len(tree.Children) != 2 {
+		t.Fatalf("root = %s with %d children, want apparel with 2", tree.ID, len(tree.Children))
+	}
+	if men := tree.Children[0]; men.ID != "men" || len(men.Children) != 1 || men.Children[0].ID != "shirts" {
+		t.Errorf("first child = %+v, want men > shirts", men)
+	}
+	if women := tree.Children[1]; women.ID != "women" || women.Children == nil || len(women.Children) != 0 {
+		t.Errorf("second child = %+v, want women with an empty child list", women)
+	}
+
+	if _, ok := buildCategoryTree("missing", cats); ok {
+		t.Error("buildCategoryTree() found a root that is not in the rows")
+	}
+}
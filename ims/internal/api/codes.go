@@ -0,0 +1,130 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/omniful/go_commons/i18n"
+	"github.com/omniful/go_commons/log"
+	"gorm.io/gorm"
+)
+
+var codeLogger = log.DefaultLogger()
+
+// errInvalidRef is returned when a request names a hub or SKU both by id and
+// by code, by neither, or by code without a tenant.
+var errInvalidRef = errors.New("hub or SKU needs an id, or a code and a tenant")
+
+// resolveCodes maps codes to the ids of the tenant's live rows of table,
+// "hubs" or "skus". Codes with no such row are left out.
+func resolveCodes(db *gorm.DB, table, tenantID string, codes []string) (map[string]string, error) {
+	ids := make(map[string]string, len(codes))
+	if len(codes) == 0 {
+		return ids, nil
+	}
+	ph := strings.Repeat("?,", len(codes))
+	args := []interface{}{tenantID}
+	for _, code := range codes {
+		args = append(args, code)
+	}
+
+	var rows []struct {
+		ID   string `gorm:"column:id"`
+		Code string `gorm:"column:code"`
+	}
+	if err := db.Raw(
+		fmt.Sprintf(`SELECT id,code FROM %s WHERE tenant_id = ? AND code IN (%s) AND deleted_at IS NULL`, table, ph[:len(ph)-1]),
+		args...,
+	).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, r := range rows {
+		ids[r.Code] = r.ID
+	}
+	return ids, nil
+}
+
+// resolveRef returns the id a request gives for a hub or SKU, looking the
+// code up among the tenant's live rows of table when it gives a code
+// instead. An unknown code, or an id that is not a live row (of the tenant,
+// when one is given), is gorm.ErrRecordNotFound.
+func resolveRef(db *gorm.DB, table, tenantID, id, code string) (string, error) {
+	if (id == "") == (code == "") {
+		return "", errInvalidRef
+	}
+	if id != "" {
+		where := "id = ? AND deleted_at IS NULL"
+		args := []interface{}{id}
+		if tenantID != "" {
+			where += " AND tenant_id = ?"
+			args = append(args, tenantID)
+		}
+		var ids []string
+		if err := db.Raw(fmt.Sprintf(`SELECT id FROM %s WHERE %s`, table, where), args...).Scan(&ids).Error; err != nil {
+			return "", err
+		}
+		if len(ids) == 0 {
+			return "", gorm.ErrRecordNotFound
+		}
+		return id, nil
+	}
+	if tenantID == "" {
+		return "", errInvalidRef
+	}
+	ids, err := resolveCodes(db, table, tenantID, []string{code})
+	if err != nil {
+		return "", err
+	}
+	if ids[code] == "" {
+		return "", gorm.ErrRecordNotFound
+	}
+	return ids[code], nil
+}
+
+// refError answers a request whose hub or SKU reference did not resolve:
+// 400 for an invalid reference, 404 with notFound for an unknown code and
+// 500 with failed otherwise.
+func refError(c *gin.Context, err error, notFound, failed string) {
+	switch {
+	case errors.Is(err, errInvalidRef):
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_request")})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.Translate(c, notFound)})
+	default:
+		codeLogger.Errorf("%s %s code lookup error: %v", c.Request.Method, c.FullPath(), err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, failed)})
+	}
+}
+
+// requireLiveHubSKU answers the request through refError and returns false
+// unless hubID and skuID are live rows of the tenant.
+func requireLiveHubSKU(c *gin.Context, db *gorm.DB, tenantID, hubID, skuID, failed string) bool {
+	if _, err := resolveRef(db, "hubs", tenantID, hubID, ""); err != nil {
+		refError(c, err, "error.hub_not_found", failed)
+		return false
+	}
+	if _, err := resolveRef(db, "skus", tenantID, skuID, ""); err != nil {
+		refError(c, err, "error.sku_not_found", failed)
+		return false
+	}
+	return true
+}
+
+// liveIDs returns the ids of the tenant's live rows of table, e.g. "sellers"
+// or "categories".
+func liveIDs(db *gorm.DB, table, tenantID string) (map[string]bool, error) {
+	var ids []string
+	if err := db.Raw(
+		fmt.Sprintf(`SELECT id FROM %s WHERE tenant_id = ? AND deleted_at IS NULL`, table), tenantID,
+	).Scan(&ids).Error; err != nil {
+		return nil, err
+	}
+	live := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		live[id] = true
+	}
+	return live, nil
+}
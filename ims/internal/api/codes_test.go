@@ -0,0 +1,46 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestResolveRefInvalid(t *testing.T) {
+	cases := []struct {
+		name, tenantID, id, code string
+	}{
+		{name: "neither", tenantID: "t1"},
+		{name: "both", tenantID: "t1", id: "hub-1", code: "BLR-1"},
+		{name: "code without a tenant", code: "BLR-1"},
+	}
+	for _, tc := range cases {
+		// These are refused before any lookup, so no database is needed.
+		if _, err := resolveRef(nil, "hubs", tc.tenantID, tc.id, tc.code); err != errInvalidRef {
+			t.Errorf("%s: resolveRef error = %v, want errInvalidRef", tc.name, err)
+		}
+	}
+}
+
+type sqlStateError string
+
+func (e sqlStateError) Error() string    { return "sqlstate " + string(e) }
+func (e sqlStateError) SQLState() string { return string(e) }
+
+func TestIsUniqueViolation(t *testing.T) {
+	cases := []struct {
+		err  error
+		want bool
+	}{
+		{sqlStateError("23505"), true},
+		{fmt.Errorf("update hub: %w", sqlStateError("23505")), true},
+		{sqlStateError("23503"), false},
+		{errors.New("duplicate key"), false},
+		{nil, false},
+	}
+	for _, tc := range cases {
+		if got := isUniqueViolation(tc.err); got != tc.want {
+			t.Errorf("isUniqueViolation(%v) = %v, want %v", tc.err, got, tc.want)
+		}
+	}
+}
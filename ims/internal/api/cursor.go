@@ -0,0 +1,77 @@
+package api
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// errInvalidCursor is returned for a page cursor that was not issued by the
+// listing it is given to.
+var errInvalidCursor = errors.New("invalid cursor")
+
+// encodeCursor makes the opaque cursor of the page that follows a row from
+// the values the listing is ordered by. The values may not contain commas.
+func encodeCursor(parts ...string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strings.Join(parts, ",")))
+}
+
+// decodeCursor reads back the n values of a cursor made by encodeCursor. A
+// cursor with another number of values, or an empty one, is errInvalidCursor.
+func decodeCursor(cursor string, n int) ([]string, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+	parts := strings.Split(string(b), ",")
+	if len(parts) != n {
+		return nil, errInvalidCursor
+	}
+	for _, p := range parts {
+		if p == "" {
+			return nil, errInvalidCursor
+		}
+	}
+	return parts, nil
+}
+
+// encodeCreatedAtCursor is the cursor of a listing ordered newest first by
+// (created_at, id).
+func encodeCreatedAtCursor(createdAt time.Time, id string) string {
+	return encodeCursor(createdAt.UTC().Format(time.RFC3339Nano), id)
+}
+
+// decodeCreatedAtCursor reads back a cursor made by encodeCreatedAtCursor.
+// The id must be a UUID, so a forged cursor is refused before it reaches the
+// database.
+func decodeCreatedAtCursor(cursor string) (time.Time, string, error) {
+	parts, err := decodeCursor(cursor, 2)
+	if err != nil {
+		return time.Time{}, "", err
+	}
+	createdAt, err := time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil {
+		return time.Time{}, "", errInvalidCursor
+	}
+	if _, err := uuid.Parse(parts[1]); err != nil {
+		return time.Time{}, "", errInvalidCursor
+	}
+	return createdAt, parts[1], nil
+}
+
+// parsePageLimit reads the limit query parameter of a listing: def when it
+// is absent, capped at max, and an error unless it is a positive integer.
+func parsePageLimit(v string, def, max int) (int, error) {
+	if v == "" {
+		return def, nil
+	}
+	limit, err := strconv.Atoi(v)
+	if err != nil || limit <= 0 {
+		return 0, errors.New("invalid limit")
+	}
+	return min(limit, max), nil
+}
@@ -0,0 +1,59 @@
+package api
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCursor(t *testing.T) {
+	cursor := encodeCursor("2024-03-01T10:30:00.123456789Z", "tx-1")
+	parts, err := decodeCursor(cursor, 2)
+	if err != nil || !reflect.DeepEqual(parts, []string{"2024-03-01T10:30:00.123456789Z", "tx-1"}) {
+		t.Fatalf("decodeCursor(%q, 2) = %q, %v", cursor, parts, err)
+	}
+
+	for _, bad := range []string{"not base64!", encodeCursor("hub-1"), encodeCursor("hub-1", "sku-1", "x"), encodeCursor("", "sku-1"), encodeCursor("hub-1", "")} {
+		if _, err := decodeCursor(bad, 2); err != errInvalidCursor {
+			t.Errorf("decodeCursor(%q, 2) error = %v, want errInvalidCursor", bad, err)
+		}
+	}
+}
+
+func TestCreatedAtCursor(t *testing.T) {
+	at := time.Date(2024, 3, 1, 10, 30, 0, 123456000, time.UTC)
+	id := "6f1c2f0e-8a4b-4c1d-9a57-3e2b1d0c9f10"
+	gotAt, gotID, err := decodeCreatedAtCursor(encodeCreatedAtCursor(at.In(time.FixedZone("IST", 19800)), id))
+	if err != nil || !gotAt.Equal(at) || gotID != id {
+		t.Errorf("decodeCreatedAtCursor() = %v, %q, %v", gotAt, gotID, err)
+	}
+	for _, bad := range []string{
+		encodeCursor("yesterday", id),
+		encodeCursor(at.Format(time.RFC3339Nano), "tx-1"),
+	} {
+		if _, _, err := decodeCreatedAtCursor(bad); err != errInvalidCursor {
+			t.Errorf("decodeCreatedAtCursor(%q) error = %v, want errInvalidCursor", bad, err)
+		}
+	}
+}
+
+func TestParsePageLimit(t *testing.T) {
+	cases := []struct {
+		in      string
+		want    int
+		wantErr bool
+	}{
+		{"", 100, false},
+		{"25", 25, false},
+		{"5000", 1000, false},
+		{"0", 0, true},
+		{"-3", 0, true},
+		{"ten", 0, true},
+	}
+	for _, tc := range cases {
+		got, err := parsePageLimit(tc.in, 100, 1000)
+		if got != tc.want || (err != nil) != tc.wantErr {
+			t.Errorf("parsePageLimit(%q) = %d, %v; want %d, error %v", tc.in, got, err, tc.want, tc.wantErr)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
@@ -11,7 +12,10 @@ import (
 	"github.com/google/uuid"
 	"github.com/omniful/go_commons/i18n"
 	"github.com/omniful/go_commons/log"
+	"gorm.io/gorm"
 
+	"github.com/abhirup.dandapat/ims/constants"
+	"github.com/abhirup.dandapat/ims/internal/ledger"
 	"github.com/abhirup.dandapat/ims/internal/models"
 	"github.com/abhirup.dandapat/ims/internal/store"
 )
@@ -23,6 +27,15 @@ func createTenant(c *gin.Context) {
 		return
 	}
 
+	switch t.CostingMethod {
+	case "":
+		t.CostingMethod = constants.CostingMethodFIFO
+	case constants.CostingMethodFIFO, constants.CostingMethodAverage:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_request")})
+		return
+	}
+
 	t.ID = uuid.New().String()
 	now := time.Now().UTC()
 	t.CreatedAt, t.UpdatedAt = now, now
@@ -36,9 +49,9 @@ func createTenant(c *gin.Context) {
 
 	db := store.DB.GetMasterDB(c.Request.Context())
 	if err := db.Exec(
-		`INSERT INTO tenants(id,name,metadata,created_at,updated_at)
-           VALUES(?,?,?,?,?)`,
-		t.ID, t.Name, metaBytes, t.CreatedAt, t.UpdatedAt,
+		`INSERT INTO tenants(id,name,metadata,allow_negative_inventory,costing_method,created_at,updated_at)
+           VALUES(?,?,?,?,?,?,?)`,
+		t.ID, t.Name, metaBytes, t.AllowNegativeInventory, t.CostingMethod, t.CreatedAt, t.UpdatedAt,
 	).Error; err != nil {
 		log.DefaultLogger().Errorf("createTenant DB error: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.create_tenant_failed")})
@@ -53,11 +66,14 @@ func getTenant(c *gin.Context) {
 	var t models.Tenant
 
 	db := store.DB.GetSlaveDB(c.Request.Context())
-	if err := db.Raw(
-		`SELECT id,name,metadata,created_at,updated_at
-         FROM tenants WHERE id = ?`, id,
-	).Scan(&t).Error; err != nil {
-		log.DefaultLogger().Errorf("getTenant DB error: %v", err)
+	res := db.Raw(
+		`SELECT id,name,metadata,allow_negative_inventory,costing_method,created_at,updated_at
+         FROM tenants WHERE id = ? AND deleted_at IS NULL`, id,
+	).Scan(&t)
+	if res.Error != nil {
+		log.DefaultLogger().Errorf("getTenant DB error: %v", res.Error)
+	}
+	if res.Error != nil || res.RowsAffected == 0 {
 		c.JSON(http.StatusNotFound, gin.H{"error": i18n.Translate(c, "error.tenant_not_found")})
 		return
 	}
@@ -101,11 +117,14 @@ func getSeller(c *gin.Context) {
 	var s models.Seller
 
 	db := store.DB.GetSlaveDB(c.Request.Context())
-	if err := db.Raw(
+	res := db.Raw(
 		`SELECT id,tenant_id,name,metadata,created_at,updated_at
-         FROM sellers WHERE id = ?`, id,
-	).Scan(&s).Error; err != nil {
-		log.DefaultLogger().Errorf("getSeller DB error: %v", err)
+         FROM sellers WHERE id = ? AND deleted_at IS NULL`, id,
+	).Scan(&s)
+	if res.Error != nil {
+		log.DefaultLogger().Errorf("getSeller DB error: %v", res.Error)
+	}
+	if res.Error != nil || res.RowsAffected == 0 {
 		c.JSON(http.StatusNotFound, gin.H{"error": i18n.Translate(c, "error.seller_not_found")})
 		return
 	}
@@ -123,16 +142,29 @@ func createCategory(c *gin.Context) {
 	now := time.Now().UTC()
 	cat.CreatedAt, cat.UpdatedAt = now, now
 
+	if cat.ParentID != nil && *cat.ParentID == "" {
+		cat.ParentID = nil
+	}
+
+	// A parent must be a live category of the same tenant.
 	db := store.DB.GetMasterDB(c.Request.Context())
-	if err := db.Exec(
-		`INSERT INTO categories(id,tenant_id,name,description,created_at,updated_at)
-         VALUES(?,?,?,?,?,?)`,
-		cat.ID, cat.TenantID, cat.Name, cat.Description, cat.CreatedAt, cat.UpdatedAt,
-	).Error; err != nil {
-		log.DefaultLogger().Errorf("createCategory DB error: %v", err)
+	res := db.Exec(
+		`INSERT INTO categories(id,tenant_id,parent_id,name,description,created_at,updated_at)
+         SELECT ?,?,?,?,?,?,?
+         WHERE CAST(? AS UUID) IS NULL
+            OR EXISTS(SELECT 1 FROM categories WHERE id = ? AND tenant_id = ? AND deleted_at IS NULL FOR SHARE)`,
+		cat.ID, cat.TenantID, cat.ParentID, cat.Name, cat.Description, cat.CreatedAt, cat.UpdatedAt,
+		cat.ParentID, cat.ParentID, cat.TenantID,
+	)
+	if res.Error != nil {
+		log.DefaultLogger().Errorf("createCategory DB error: %v", res.Error)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.create_category_failed")})
 		return
 	}
+	if res.RowsAffected == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_category_parent")})
+		return
+	}
 
 	c.JSON(http.StatusCreated, cat)
 }
@@ -142,11 +174,14 @@ func getCategory(c *gin.Context) {
 	var cat models.Category
 
 	db := store.DB.GetSlaveDB(c.Request.Context())
-	if err := db.Raw(
-		`SELECT id,tenant_id,name,description,created_at,updated_at
-         FROM categories WHERE id = ?`, id,
-	).Scan(&cat).Error; err != nil {
-		log.DefaultLogger().Errorf("getCategory DB error: %v", err)
+	res := db.Raw(
+		`SELECT id,tenant_id,parent_id,name,description,created_at,updated_at
+         FROM categories WHERE id = ? AND deleted_at IS NULL`, id,
+	).Scan(&cat)
+	if res.Error != nil {
+		log.DefaultLogger().Errorf("getCategory DB error: %v", res.Error)
+	}
+	if res.Error != nil || res.RowsAffected == 0 {
 		c.JSON(http.StatusNotFound, gin.H{"error": i18n.Translate(c, "error.category_not_found")})
 		return
 	}
@@ -161,22 +196,53 @@ func createHub(c *gin.Context) {
 		return
 	}
 	h.ID = uuid.New().String()
+	if h.Code != nil && *h.Code == "" {
+		h.Code = nil
+	}
+	if h.IsActive == nil {
+		active := true
+		h.IsActive = &active
+	}
 	now := time.Now().UTC()
 	h.CreatedAt, h.UpdatedAt = now, now
 
+	// The seller must be a live seller of the tenant; it is locked so that it
+	// cannot be deleted until the hub is in.
 	db := store.DB.GetMasterDB(c.Request.Context())
-	if err := db.Exec(
-		`INSERT INTO hubs(id,tenant_id,seller_id,name,location,address,contact_email,contact_phone,timezone,created_at,updated_at)
-         VALUES(?,?,?,?,?,?,?,?,?,?,?)`,
-		h.ID, h.TenantID, h.SellerID, h.Name, h.Location,
-		h.Address, h.ContactEmail, h.ContactPhone, h.Timezone,
+	res := db.Exec(
+		`INSERT INTO hubs(id,tenant_id,seller_id,code,name,location,address,contact_email,contact_phone,timezone,is_active,created_at,updated_at)
+         SELECT ?,?,?,?,?,?,?,?,?,?,?,?,?
+         WHERE EXISTS(SELECT 1 FROM sellers WHERE id = ? AND tenant_id = ? AND deleted_at IS NULL FOR SHARE)
+         ON CONFLICT DO NOTHING`,
+		h.ID, h.TenantID, h.SellerID, h.Code, h.Name, h.Location,
+		h.Address, h.ContactEmail, h.ContactPhone, h.Timezone, h.IsActive,
 		h.CreatedAt, h.UpdatedAt,
-	).Error; err != nil {
-		log.DefaultLogger().Errorf("createHub DB error: %v", err)
+		h.SellerID, h.TenantID,
+	)
+	if res.Error != nil {
+		log.DefaultLogger().Errorf("createHub DB error: %v", res.Error)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.create_hub_failed")})
 		return
 	}
+	if res.RowsAffected == 0 {
+		var live bool
+		if err := db.Raw(
+			`SELECT EXISTS(SELECT 1 FROM sellers WHERE id = ? AND tenant_id = ? AND deleted_at IS NULL)`,
+			h.SellerID, h.TenantID,
+		).Scan(&live).Error; err != nil {
+			log.DefaultLogger().Errorf("createHub seller lookup error: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.create_hub_failed")})
+			return
+		}
+		if !live {
+			c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_hub_reference")})
+			return
+		}
+		c.JSON(http.StatusConflict, gin.H{"error": i18n.Translate(c, "error.hub_code_conflict")})
+		return
+	}
 
+	invalidateInventoryCache(c.Request.Context(), h.TenantID, now)
 	c.JSON(http.StatusCreated, h)
 }
 
@@ -190,11 +256,14 @@ func getHub(c *gin.Context) {
 
 	var h models.Hub
 	db := store.DB.GetSlaveDB(c.Request.Context())
-	if err := db.Raw(
-		`SELECT id,tenant_id,seller_id,name,location,address,contact_email,contact_phone,timezone,created_at,updated_at
-         FROM hubs WHERE id = ?`, id,
-	).Scan(&h).Error; err != nil {
-		log.DefaultLogger().Errorf("getHub DB error: %v", err)
+	res := db.Raw(
+		`SELECT id,tenant_id,seller_id,code,name,location,address,contact_email,contact_phone,timezone,is_active,created_at,updated_at
+         FROM hubs WHERE id = ? AND deleted_at IS NULL`, id,
+	).Scan(&h)
+	if res.Error != nil {
+		log.DefaultLogger().Errorf("getHub DB error: %v", res.Error)
+	}
+	if res.Error != nil || res.RowsAffected == 0 {
 		c.JSON(http.StatusNotFound, gin.H{"error": i18n.Translate(c, "error.hub_not_found")})
 		return
 	}
@@ -215,15 +284,27 @@ func updateHub(c *gin.Context) {
 	}
 	h.UpdatedAt = time.Now().UTC()
 
+	// code keeps its stored value when left out, and may not be the code of
+	// another live hub of the tenant.
 	db := store.DB.GetMasterDB(c.Request.Context())
-	if err := db.Exec(
-		`UPDATE hubs SET name=?,location=?,address=?,contact_email=?,contact_phone=?,timezone=?,updated_at=? WHERE id=?`,
-		h.Name, h.Location, h.Address, h.ContactEmail, h.ContactPhone, h.Timezone, h.UpdatedAt, id,
-	).Error; err != nil {
-		log.DefaultLogger().Errorf("updateHub DB error: %v", err)
+	res := db.Exec(
+		`UPDATE hubs SET code=COALESCE(?,code),name=?,location=?,address=?,contact_email=?,contact_phone=?,timezone=?,
+             is_active=COALESCE(?,is_active),updated_at=? WHERE id=? AND deleted_at IS NULL`,
+		h.Code, h.Name, h.Location, h.Address, h.ContactEmail, h.ContactPhone, h.Timezone, h.IsActive, h.UpdatedAt, id,
+	)
+	if isUniqueViolation(res.Error) {
+		c.JSON(http.StatusConflict, gin.H{"error": i18n.Translate(c, "error.hub_code_conflict")})
+		return
+	}
+	if res.Error != nil {
+		log.DefaultLogger().Errorf("updateHub DB error: %v", res.Error)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.update_hub_failed")})
 		return
 	}
+	if res.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.Translate(c, "error.hub_not_found")})
+		return
+	}
 
 	_, _ = store.RedisClient.Del(c.Request.Context(), "hub:"+id)
 	c.Status(http.StatusOK)
@@ -232,14 +313,24 @@ func updateHub(c *gin.Context) {
 func deleteHub(c *gin.Context) {
 	id := c.Param("id")
 
+	// Soft delete: stock and ledger rows of the hub are kept.
+	var tenantID string
+	now := time.Now().UTC()
 	db := store.DB.GetMasterDB(c.Request.Context())
-	if err := db.Exec(`DELETE FROM hubs WHERE id = ?`, id).Error; err != nil {
+	if err := db.Raw(
+		`UPDATE hubs SET deleted_at = ?, updated_at = ? WHERE id = ? AND deleted_at IS NULL RETURNING tenant_id`, now, now, id,
+	).Scan(&tenantID).Error; err != nil {
 		log.DefaultLogger().Errorf("deleteHub DB error: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.delete_hub_failed")})
 		return
 	}
+	if tenantID == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.Translate(c, "error.hub_not_found")})
+		return
+	}
 
 	_, _ = store.RedisClient.Del(c.Request.Context(), "hub:"+id)
+	invalidateInventoryCache(c.Request.Context(), tenantID, now)
 	c.Status(http.StatusNoContent)
 }
 
@@ -258,8 +349,14 @@ func listHubs(c *gin.Context) {
 		args = append(args, sellerID)
 	}
 
+	where, err := liveFilter(c, where)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_request")})
+		return
+	}
+
 	sqlStr := fmt.Sprintf(
-		`SELECT id,tenant_id,seller_id,name,location,address,contact_email,contact_phone,timezone,created_at,updated_at
+		`SELECT id,tenant_id,seller_id,code,name,location,address,contact_email,contact_phone,timezone,is_active,created_at,updated_at,deleted_at
            FROM hubs WHERE %s`, strings.Join(where, " AND "),
 	)
 
@@ -284,6 +381,12 @@ func listHubs(c *gin.Context) {
 	c.JSON(http.StatusOK, hubs)
 }
 
+// skuRefsLive holds when a SKU's seller (seller_id, tenant_id) and category
+// (category_id twice, tenant_id; empty for none) are live rows of its tenant.
+// It locks them, so a concurrent delete waits for the statement's transaction.
+const skuRefsLive = `EXISTS(SELECT 1 FROM sellers WHERE id = ? AND tenant_id = ? AND deleted_at IS NULL FOR SHARE)
+           AND (? = '' OR EXISTS(SELECT 1 FROM categories WHERE id = CAST(NULLIF(?, '') AS UUID) AND tenant_id = ? AND deleted_at IS NULL FOR SHARE))`
+
 func createSKU(c *gin.Context) {
 	var s models.SKU
 	if err := c.ShouldBindJSON(&s); err != nil {
@@ -291,22 +394,47 @@ func createSKU(c *gin.Context) {
 		return
 	}
 	s.ID = uuid.New().String()
+	if s.BaseUOM == "" {
+		s.BaseUOM = constants.DefaultBaseUOM
+	}
 	now := time.Now().UTC()
 	s.CreatedAt, s.UpdatedAt = now, now
 
+	// Codes are unique among the tenant's live SKUs. The seller, and the
+	// category if any, must be live rows of the tenant; they are locked so
+	// that they cannot be deleted until the SKU is in.
 	db := store.DB.GetMasterDB(c.Request.Context())
-	if err := db.Exec(
-		`INSERT INTO skus(id,tenant_id,seller_id,code,name,description,category_id,weight,weight_unit,length,width,height,created_at,updated_at)
-         VALUES(?,?,?,?,?,?,?,?,?,?,?,?,?,?)`,
+	res := db.Exec(
+		`INSERT INTO skus(id,tenant_id,seller_id,code,name,description,category_id,weight,weight_unit,length,width,height,is_serialized,base_uom,created_at,updated_at)
+         SELECT ?,?,?,?,?,?,CAST(NULLIF(?, '') AS UUID),?,?,?,?,?,?,?,?,?
+         WHERE `+skuRefsLive+`
+         ON CONFLICT DO NOTHING`,
 		s.ID, s.TenantID, s.SellerID, s.Code, s.Name, s.Description,
 		s.CategoryID, s.Weight, s.WeightUnit, s.Length, s.Width, s.Height,
-		s.CreatedAt, s.UpdatedAt,
-	).Error; err != nil {
-		log.DefaultLogger().Errorf("createSKU DB error: %v", err)
+		s.IsSerialized, s.BaseUOM, s.CreatedAt, s.UpdatedAt,
+		s.SellerID, s.TenantID, s.CategoryID, s.CategoryID, s.TenantID,
+	)
+	if res.Error != nil {
+		log.DefaultLogger().Errorf("createSKU DB error: %v", res.Error)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.create_sku_failed")})
 		return
 	}
+	if res.RowsAffected == 0 {
+		var live bool
+		if err := db.Raw(`SELECT `+skuRefsLive, s.SellerID, s.TenantID, s.CategoryID, s.CategoryID, s.TenantID).Scan(&live).Error; err != nil {
+			log.DefaultLogger().Errorf("createSKU reference lookup error: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.create_sku_failed")})
+			return
+		}
+		if !live {
+			c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_sku_reference")})
+			return
+		}
+		c.JSON(http.StatusConflict, gin.H{"error": i18n.Translate(c, "error.sku_code_conflict")})
+		return
+	}
 
+	invalidateInventoryCache(c.Request.Context(), s.TenantID, now)
 	c.JSON(http.StatusCreated, s)
 }
 
@@ -320,11 +448,14 @@ func getSKU(c *gin.Context) {
 
 	var s models.SKU
 	db := store.DB.GetSlaveDB(c.Request.Context())
-	if err := db.Raw(
-		`SELECT id,tenant_id,seller_id,code,name,description,category_id,weight,weight_unit,length,width,height,created_at,updated_at
-         FROM skus WHERE id = ?`, id,
-	).Scan(&s).Error; err != nil {
-		log.DefaultLogger().Errorf("getSKU DB error: %v", err)
+	res := db.Raw(
+		`SELECT id,tenant_id,seller_id,code,name,description,category_id,weight,weight_unit,length,width,height,is_serialized,base_uom,product_id,variant_attributes,created_at,updated_at
+         FROM skus WHERE id = ? AND deleted_at IS NULL`, id,
+	).Scan(&s)
+	if res.Error != nil {
+		log.DefaultLogger().Errorf("getSKU DB error: %v", res.Error)
+	}
+	if res.Error != nil || res.RowsAffected == 0 {
 		c.JSON(http.StatusNotFound, gin.H{"error": i18n.Translate(c, "error.sku_not_found")})
 		return
 	}
@@ -336,42 +467,103 @@ func getSKU(c *gin.Context) {
 	c.JSON(http.StatusOK, s)
 }
 
+// SKUUpdateRequest is a SKU whose is_serialized may be left out, keeping the
+// stored flag.
+type SKUUpdateRequest struct {
+	models.SKU
+	IsSerialized *bool `json:"is_serialized"`
+}
+
 func updateSKU(c *gin.Context) {
 	id := c.Param("id")
-	var s models.SKU
+	var s SKUUpdateRequest
 	if err := c.ShouldBindJSON(&s); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_request")})
 		return
 	}
 	s.UpdatedAt = time.Now().UTC()
 
+	// is_serialized keeps its stored value when left out, and cannot change
+	// once the SKU has stock or serials or is part of a kit.
 	db := store.DB.GetMasterDB(c.Request.Context())
-	if err := db.Exec(
-		`UPDATE skus SET code=?,name=?,description=?,category_id=?,weight=?,weight_unit=?,length=?,width=?,height=?,updated_at=? WHERE id=?`,
+	res := db.Exec(
+		`UPDATE skus SET code=?,name=?,description=?,category_id=?,weight=?,weight_unit=?,length=?,width=?,height=?,
+             is_serialized=COALESCE(?,is_serialized),updated_at=?
+         WHERE id=? AND deleted_at IS NULL AND (COALESCE(?,is_serialized) = is_serialized OR NOT `+serialFlagFixed+`)`,
 		s.Code, s.Name, s.Description, s.CategoryID,
 		s.Weight, s.WeightUnit, s.Length, s.Width, s.Height,
-		s.UpdatedAt, id,
-	).Error; err != nil {
-		log.DefaultLogger().Errorf("updateSKU DB error: %v", err)
+		s.IsSerialized, s.UpdatedAt, id, s.IsSerialized,
+	)
+	if isUniqueViolation(res.Error) {
+		c.JSON(http.StatusConflict, gin.H{"error": i18n.Translate(c, "error.sku_code_conflict")})
+		return
+	}
+	if res.Error != nil {
+		log.DefaultLogger().Errorf("updateSKU DB error: %v", res.Error)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.update_sku_failed")})
 		return
 	}
+	if res.RowsAffected == 0 {
+		var live bool
+		if err := db.Raw(`SELECT EXISTS(SELECT 1 FROM skus WHERE id = ? AND deleted_at IS NULL)`, id).Scan(&live).Error; err != nil {
+			log.DefaultLogger().Errorf("updateSKU lookup error: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.update_sku_failed")})
+			return
+		}
+		if !live {
+			c.JSON(http.StatusNotFound, gin.H{"error": i18n.Translate(c, "error.sku_not_found")})
+			return
+		}
+		c.JSON(http.StatusConflict, gin.H{"error": i18n.Translate(c, "error.sku_serialization_fixed")})
+		return
+	}
 
 	_, _ = store.RedisClient.Del(c.Request.Context(), "sku:"+id)
+	forgetSKUBarcodes(c.Request.Context(), db, id)
 	c.Status(http.StatusOK)
 }
 
 func deleteSKU(c *gin.Context) {
 	id := c.Param("id")
 
-	db := store.DB.GetMasterDB(c.Request.Context())
-	if err := db.Exec(`DELETE FROM skus WHERE id = ?`, id).Error; err != nil {
+	// Soft delete: stock and ledger rows of the SKU are kept; its barcodes
+	// are freed in the same transaction.
+	tx := store.DB.GetMasterDB(c.Request.Context()).Begin()
+	if tx.Error != nil {
+		log.DefaultLogger().Errorf("deleteSKU begin tx error: %v", tx.Error)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.delete_sku_failed")})
+		return
+	}
+	defer tx.Rollback()
+
+	var tenantID string
+	now := time.Now().UTC()
+	if err := tx.Raw(
+		`UPDATE skus SET deleted_at = ?, updated_at = ? WHERE id = ? AND deleted_at IS NULL RETURNING tenant_id`, now, now, id,
+	).Scan(&tenantID).Error; err != nil {
 		log.DefaultLogger().Errorf("deleteSKU DB error: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.delete_sku_failed")})
 		return
 	}
+	if tenantID == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.Translate(c, "error.sku_not_found")})
+		return
+	}
+	released, err := releaseSKUBarcodes(tx, id)
+	if err != nil {
+		log.DefaultLogger().Errorf("deleteSKU release barcodes error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.delete_sku_failed")})
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		log.DefaultLogger().Errorf("deleteSKU commit error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.delete_sku_failed")})
+		return
+	}
 
 	_, _ = store.RedisClient.Del(c.Request.Context(), "sku:"+id)
+	forgetBarcodes(c.Request.Context(), released)
+	invalidateInventoryCache(c.Request.Context(), tenantID, now)
 	c.Status(http.StatusNoContent)
 }
 
@@ -399,9 +591,28 @@ func listSKUs(c *gin.Context) {
 			args = append(args, code)
 		}
 	}
+	if categoryID := c.Query("category_id"); categoryID != "" {
+		descendants, err := parseBoolQuery(c, "include_descendants")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_request")})
+			return
+		}
+		if descendants {
+			where = append(where, "category_id IN ("+categoryDescendantsSQL+")")
+		} else {
+			where = append(where, "category_id = ?")
+		}
+		args = append(args, categoryID)
+	}
+
+	where, err := liveFilter(c, where)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_request")})
+		return
+	}
 
 	sqlStr := fmt.Sprintf(
-		`SELECT id,tenant_id,seller_id,code,name,description,category_id,weight,weight_unit,length,width,height,created_at,updated_at
+		`SELECT id,tenant_id,seller_id,code,name,description,category_id,weight,weight_unit,length,width,height,is_serialized,base_uom,product_id,variant_attributes,created_at,updated_at,deleted_at
            FROM skus WHERE %s`, strings.Join(where, " AND "),
 	)
 
@@ -426,11 +637,17 @@ func listSKUs(c *gin.Context) {
 	c.JSON(http.StatusOK, skus)
 }
 
+// InventoryUpsertRequest names the hub by hub_id or hub_code and the SKU by
+// sku_id or sku_code; codes are looked up among the tenant's live rows.
 type InventoryUpsertRequest struct {
 	TenantID string `json:"tenant_id" binding:"required"`
-	HubID    string `json:"hub_id"    binding:"required"`
-	SKUID    string `json:"sku_id"    binding:"required"`
+	HubID    string `json:"hub_id"`
+	HubCode  string `json:"hub_code"`
+	SKUID    string `json:"sku_id"`
+	SKUCode  string `json:"sku_code"`
 	Quantity int64  `json:"quantity"  binding:"required"`
+	// UnitCost values stock added by the upsert; see ledger.Append.
+	UnitCost *float64 `json:"unit_cost" binding:"omitempty,gte=0"`
 }
 
 func upsertInventory(c *gin.Context) {
@@ -449,70 +666,189 @@ func upsertInventory(c *gin.Context) {
 	}
 	defer tx.Rollback()
 
-	if err := tx.Exec(
-		`INSERT INTO inventory(hub_id,sku_id,quantity_on_hand,quantity_reserved,min_threshold,max_threshold,updated_at)
-         VALUES(?,?,?,?,?,?,?)
-         ON CONFLICT (hub_id,sku_id) DO UPDATE SET quantity_on_hand = EXCLUDED.quantity_on_hand, updated_at = EXCLUDED.updated_at`,
-		req.HubID, req.SKUID, req.Quantity, 0, 0, 0, now,
-	).Error; err != nil {
-		log.DefaultLogger().Errorf("upsertInventory exec error: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.inventory_upsert_failed")})
+	hubID, err := resolveRef(tx, "hubs", req.TenantID, req.HubID, req.HubCode)
+	if err != nil {
+		refError(c, err, "error.hub_not_found", "error.inventory_upsert_failed")
+		return
+	}
+	skuID, err := resolveRef(tx, "skus", req.TenantID, req.SKUID, req.SKUCode)
+	if err != nil {
+		refError(c, err, "error.sku_not_found", "error.inventory_upsert_failed")
 		return
 	}
 
-	transID := uuid.New().String()
-	if err := tx.Exec(
-		`INSERT INTO inventory_transactions(id,tenant_id,hub_id,sku_id,delta,transaction_type,reference_id,created_at)
-         VALUES(?,?,?,?,?,?,?,?)`,
-		transID, req.TenantID, req.HubID, req.SKUID, req.Quantity, "upsert", "", now,
-	).Error; err != nil {
-		log.DefaultLogger().Errorf("upsertInventory trans error: %v", err)
+	inv, err := upsertInventoryQuantity(tx, req.TenantID, hubID, skuID, req.Quantity, req.UnitCost, now)
+	if errors.Is(err, errHubFrozen) {
+		c.JSON(http.StatusLocked, gin.H{"error": i18n.Translate(c, "error.hub_frozen")})
+		return
+	}
+	if errors.Is(err, errSerialsRequired) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.serial_numbers_required")})
+		return
+	}
+	if errors.Is(err, errKitStock) {
+		c.JSON(http.StatusConflict, gin.H{"error": i18n.Translate(c, "error.sku_is_kit")})
+		return
+	}
+	if errors.Is(err, errNegativeInventory) {
+		c.JSON(http.StatusConflict, gin.H{"error": i18n.Translate(c, "error.insufficient_inventory"), "current": inv})
+		return
+	}
+	if errors.Is(err, errBinRequired) {
+		c.JSON(http.StatusConflict, gin.H{"error": i18n.Translate(c, "error.bin_required")})
+		return
+	}
+	if err != nil {
+		log.DefaultLogger().Errorf("upsertInventory exec error: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.inventory_upsert_failed")})
 		return
 	}
 
-	if err := tx.Commit().Error; err != nil {
+	if err := commitInventoryChange(c.Request.Context(), tx, req.TenantID); err != nil {
 		log.DefaultLogger().Errorf("upsertInventory commit error: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.inventory_upsert_failed")})
 		return
 	}
 
+	c.JSON(http.StatusOK, inv)
+}
+
+// upsertInventoryQuantity sets quantity_on_hand to an absolute value and posts
+// the ledger row inside tx. It is shared by the single and batch upsert paths,
+// and refuses serialized SKUs, whose units it cannot tell apart, and kits.
+// The row is locked before the write so the ledger records the true change
+// from the previous quantity; an upsert that changes nothing posts no row.
+// Unless the tenant allows negative stock, the quantity may not drop below
+// what reservations hold, or errNegativeInventory is returned.
+func upsertInventoryQuantity(tx *gorm.DB, tenantID, hubID, skuID string, quantity int64, unitCost *float64, now time.Time) (models.Inventory, error) {
 	var inv models.Inventory
-	db := store.DB.GetMasterDB(c.Request.Context())
-	if err := db.Raw(
-		`SELECT hub_id,sku_id,quantity_on_hand,quantity_reserved,min_threshold,max_threshold,updated_at
-         FROM inventory WHERE hub_id = ? AND sku_id = ?`,
-		req.HubID, req.SKUID,
+	if err := ensureHubNotFrozen(tx, hubID); err != nil {
+		return inv, err
+	}
+	if err := refuseSerialized(tx, skuID); err != nil {
+		return inv, err
+	}
+	if err := refuseKits(tx, skuID); err != nil {
+		return inv, err
+	}
+
+	if err := tx.Exec(
+		`INSERT INTO inventory(hub_id,sku_id,updated_at) VALUES(?,?,?)
+         ON CONFLICT (hub_id,sku_id) DO NOTHING`,
+		hubID, skuID, now,
+	).Error; err != nil {
+		return inv, err
+	}
+
+	var current models.Inventory
+	if err := tx.Raw(
+		`SELECT hub_id,sku_id,quantity_on_hand,quantity_reserved,quantity_in_transit,min_threshold,max_threshold,safety_stock,version,updated_at
+         FROM inventory WHERE hub_id = ? AND sku_id = ? FOR UPDATE`,
+		hubID, skuID,
+	).Scan(&current).Error; err != nil {
+		return inv, err
+	}
+	previous := current.QuantityOnHand
+	if quantity < previous && quantity < current.QuantityReserved {
+		var allowNegative bool
+		if err := tx.Raw(
+			`SELECT allow_negative_inventory FROM tenants WHERE id = ?`, tenantID,
+		).Scan(&allowNegative).Error; err != nil {
+			return inv, err
+		}
+		if !allowNegative {
+			return current, errNegativeInventory
+		}
+	}
+
+	if err := tx.Raw(
+		`UPDATE inventory SET quantity_on_hand = ?, version = version + 1, updated_at = ?
+         WHERE hub_id = ? AND sku_id = ?
+         RETURNING hub_id,sku_id,quantity_on_hand,quantity_reserved,quantity_in_transit,min_threshold,max_threshold,safety_stock,version,updated_at`,
+		quantity, now, hubID, skuID,
 	).Scan(&inv).Error; err != nil {
-		log.DefaultLogger().Errorf("upsertInventory fetch error: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.inventory_upsert_failed")})
-		return
+		return inv, err
 	}
 
-	c.JSON(http.StatusOK, inv)
+	if quantity < previous {
+		if err := drainLots(tx, inv, previous-quantity, now); err != nil {
+			return inv, err
+		}
+	}
+
+	if delta := quantity - previous; delta != 0 {
+		if err := ledger.Append(tx, models.InventoryTransaction{
+			ID:              uuid.New().String(),
+			TenantID:        tenantID,
+			HubID:           hubID,
+			SKUID:           skuID,
+			Delta:           delta,
+			TransactionType: constants.TransactionTypeUpsert,
+			UnitCost:        unitCost,
+			CreatedAt:       now,
+		}); err != nil {
+			return inv, err
+		}
+	}
+
+	if err := afterInventoryChange(tx, tenantID, inv, now); err != nil {
+		return inv, err
+	}
+
+	return inv, nil
 }
 
+// listInventory returns a hub's stock rows. The hub is given by hub_id, or by
+// hub_code with tenant_id; SKUs by sku_ids, or by sku_codes with tenant_id.
 func listInventory(c *gin.Context) {
-	hubID := c.Query("hub_id")
+	tenantID := c.Query("tenant_id")
+	db := store.DB.GetSlaveDB(c.Request.Context())
+
+	hubID, err := resolveRef(db, "hubs", tenantID, c.Query("hub_id"), c.Query("hub_code"))
+	if err != nil {
+		refError(c, err, "error.hub_not_found", "error.list_inventory_failed")
+		return
+	}
 	skuIDs := strings.Split(c.Query("sku_ids"), ",")
+	if codes := splitIDs(c.Query("sku_codes")); len(codes) > 0 {
+		if tenantID == "" || skuIDs[0] != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_request")})
+			return
+		}
+		ids, err := resolveCodes(db, "skus", tenantID, codes)
+		if err != nil {
+			refError(c, err, "error.sku_not_found", "error.list_inventory_failed")
+			return
+		}
+		// Unknown codes match nothing, as unknown ids do.
+		if len(ids) == 0 {
+			c.JSON(http.StatusOK, []models.Inventory{})
+			return
+		}
+		skuIDs = skuIDs[:0]
+		for _, id := range ids {
+			skuIDs = append(skuIDs, id)
+		}
+	}
 
-	where := []string{"hub_id = ?"}
+	where := []string{"i.hub_id = ?", "h.deleted_at IS NULL", "s.deleted_at IS NULL"}
 	args := []interface{}{hubID}
 	if len(skuIDs) > 0 && skuIDs[0] != "" {
 		ph := strings.Repeat("?,", len(skuIDs))
 		ph = ph[:len(ph)-1]
-		where = append(where, fmt.Sprintf("sku_id IN (%s)", ph))
+		where = append(where, fmt.Sprintf("i.sku_id IN (%s)", ph))
 		for _, id := range skuIDs {
 			args = append(args, id)
 		}
 	}
 
 	sqlStr := fmt.Sprintf(
-		`SELECT hub_id,sku_id,quantity_on_hand,quantity_reserved,min_threshold,max_threshold,updated_at
-           FROM inventory WHERE %s`, strings.Join(where, " AND "),
+		`SELECT i.hub_id,i.sku_id,i.quantity_on_hand,i.quantity_reserved,i.quantity_in_transit,i.min_threshold,i.max_threshold,
+                i.safety_stock,i.version,i.updated_at,h.code AS hub_code,s.code AS sku_code
+           FROM inventory i JOIN hubs h ON h.id = i.hub_id JOIN skus s ON s.id = i.sku_id
+          WHERE %s`, strings.Join(where, " AND "),
 	)
 
-	db := store.DB.GetSlaveDB(c.Request.Context())
 	rows, err := db.Raw(sqlStr, args...).Rows()
 	if err != nil {
 		log.DefaultLogger().Errorf("listInventory DB error: %v", err)
@@ -531,6 +867,23 @@ func listInventory(c *gin.Context) {
 		invs = append(invs, inv)
 	}
 
+	if err := attachLots(db, hubID, invs); err != nil {
+		log.DefaultLogger().Errorf("listInventory lots error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.list_inventory_failed")})
+		return
+	}
+
+	var kitIDs []string
+	if skuIDs[0] != "" {
+		kitIDs = skuIDs
+	}
+	invs, err = attachKits(db, hubID, kitIDs, invs)
+	if err != nil {
+		log.DefaultLogger().Errorf("listInventory kits error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.list_inventory_failed")})
+		return
+	}
+
 	c.JSON(http.StatusOK, invs)
 }
 
@@ -0,0 +1,134 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/omniful/go_commons/i18n"
+	"github.com/omniful/go_commons/log"
+
+	"github.com/abhirup.dandapat/ims/constants"
+	"github.com/abhirup.dandapat/ims/internal/models"
+	"github.com/abhirup.dandapat/ims/internal/store"
+)
+
+var locationLogger = log.DefaultLogger()
+
+// parentLocationType is the type a location of each type must sit in; zones
+// sit directly in the hub.
+var parentLocationType = map[string]string{
+	constants.LocationTypeZone:  "",
+	constants.LocationTypeAisle: constants.LocationTypeZone,
+	constants.LocationTypeBin:   constants.LocationTypeAisle,
+}
+
+type HubLocationRequest struct {
+	Type     string `json:"type"      binding:"required"`
+	Code     string `json:"code"      binding:"required"`
+	ParentID string `json:"parent_id"`
+}
+
+// createHubLocation adds a zone, aisle or bin to a live hub. Codes are unique
+// per hub so pickers can address a bin by its code alone.
+func createHubLocation(c *gin.Context) {
+	var req HubLocationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_request")})
+		return
+	}
+	wantParent, ok := parentLocationType[req.Type]
+	if !ok || (wantParent == "") != (req.ParentID == "") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_location_parent")})
+		return
+	}
+
+	hubID := c.Param("id")
+	db := store.DB.GetMasterDB(c.Request.Context())
+	if _, err := resolveRef(db, "hubs", "", hubID, ""); err != nil {
+		refError(c, err, "error.hub_not_found", "error.create_location_failed")
+		return
+	}
+
+	loc := models.HubLocation{
+		ID:        uuid.New().String(),
+		HubID:     hubID,
+		Type:      req.Type,
+		Code:      req.Code,
+		CreatedAt: time.Now().UTC(),
+	}
+	if req.ParentID != "" {
+		var parentType string
+		if err := db.Raw(
+			`SELECT location_type FROM hub_locations WHERE id = ? AND hub_id = ?`, req.ParentID, hubID,
+		).Scan(&parentType).Error; err != nil {
+			locationLogger.Errorf("createHubLocation parent lookup error: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.create_location_failed")})
+			return
+		}
+		if parentType != wantParent {
+			c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_location_parent")})
+			return
+		}
+		loc.ParentID = &req.ParentID
+	}
+
+	res := db.Exec(
+		`INSERT INTO hub_locations(id,hub_id,parent_id,location_type,code,created_at)
+         VALUES(?,?,?,?,?,?)
+         ON CONFLICT (hub_id,code) DO NOTHING`,
+		loc.ID, loc.HubID, loc.ParentID, loc.Type, loc.Code, loc.CreatedAt,
+	)
+	if res.Error != nil {
+		locationLogger.Errorf("createHubLocation DB error: %v", res.Error)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.create_location_failed")})
+		return
+	}
+	if res.RowsAffected == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": i18n.Translate(c, "error.location_code_taken")})
+		return
+	}
+
+	c.JSON(http.StatusCreated, loc)
+}
+
+// listHubLocations returns a hub's locations ordered by code, optionally of
+// one type or under one parent.
+func listHubLocations(c *gin.Context) {
+	where := []string{"hub_id = ?"}
+	args := []interface{}{c.Param("id")}
+	if v := c.Query("type"); v != "" {
+		where = append(where, "location_type = ?")
+		args = append(args, v)
+	}
+	if v := c.Query("parent_id"); v != "" {
+		where = append(where, "parent_id = ?")
+		args = append(args, v)
+	}
+
+	sqlStr := `SELECT id,hub_id,parent_id,location_type,code,created_at
+               FROM hub_locations WHERE ` + strings.Join(where, " AND ") + ` ORDER BY code`
+
+	db := store.DB.GetSlaveDB(c.Request.Context())
+	rows, err := db.Raw(sqlStr, args...).Rows()
+	if err != nil {
+		locationLogger.Errorf("listHubLocations DB error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.list_locations_failed")})
+		return
+	}
+	defer rows.Close()
+
+	var locs []models.HubLocation
+	for rows.Next() {
+		var l models.HubLocation
+		if err := db.ScanRows(rows, &l); err != nil {
+			locationLogger.Warnf("scan hub_location row: %v", err)
+			continue
+		}
+		locs = append(locs, l)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"locations": locs})
+}
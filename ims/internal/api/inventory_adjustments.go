@@ -0,0 +1,335 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/omniful/go_commons/i18n"
+	"github.com/omniful/go_commons/log"
+	"gorm.io/gorm"
+
+	"github.com/abhirup.dandapat/ims/constants"
+	"github.com/abhirup.dandapat/ims/internal/ledger"
+	"github.com/abhirup.dandapat/ims/internal/models"
+	"github.com/abhirup.dandapat/ims/internal/store"
+)
+
+var adjustLogger = log.DefaultLogger()
+
+var (
+	errVersionConflict   = errors.New("inventory version conflict")
+	errNegativeInventory = errors.New("inventory would go below zero")
+)
+
+type InventoryAdjustRequest struct {
+	TenantID        string `json:"tenant_id"        binding:"required"`
+	HubID           string `json:"hub_id"           binding:"required"`
+	SKUID           string `json:"sku_id"           binding:"required"`
+	Delta           int64  `json:"delta"`
+	ReferenceID     string `json:"reference_id"`
+	ExpectedVersion *int64 `json:"expected_version"`
+	LotNumber       string `json:"lot_number"`
+	LocationID      string `json:"location_id"`
+	UOM             string `json:"uom"`
+	// UnitCost is per uom and only read for a positive delta.
+	UnitCost *float64 `json:"unit_cost" binding:"omitempty,gte=0"`
+	// SerialNumbers name the units received or written off; serialized SKUs
+	// need one per base unit of the delta.
+	SerialNumbers []string `json:"serial_numbers"`
+}
+
+// InventoryAdjustResponse is the adjusted inventory row plus, when the delta
+// was given in another unit, that unit and the delta as sent.
+type InventoryAdjustResponse struct {
+	models.Inventory
+	UOM      string `json:"uom,omitempty"`
+	UOMDelta int64  `json:"uom_delta,omitempty"`
+}
+
+// inventoryDelta describes a signed change to quantity_on_hand and the ledger
+// row that records it. A decrement may not eat into stock held by
+// reservations unless the tenant allows negative stock; with KeepReserved it
+// may not even then. With
+// LotNumber the same change is applied to that lot, and with LocationID it
+// goes into or comes out of that bin; a decrement without a lot drains lots
+// first-expiring-first-out once the stock outside lots runs out. UOM and
+// UOMQuantity record the unit and quantity the caller used; Delta is always in
+// base units.
+type inventoryDelta struct {
+	TenantID        string
+	HubID           string
+	SKUID           string
+	Delta           int64
+	TransactionType string
+	ReferenceID     string
+	ExpectedVersion *int64
+	KeepReserved    bool
+	ReasonCode      string
+	LotNumber       string
+	LocationID      string
+	SerialNumbers   []string
+	UOM             string
+	UOMQuantity     int64
+	UnitCost        *float64
+}
+
+// applyInventoryDelta adds d.Delta to quantity_on_hand in a single conditional
+// UPDATE and posts the matching ledger row, both inside tx. It returns
+// errVersionConflict when d.ExpectedVersion no longer matches,
+// errNegativeInventory when there is not enough stock for the decrement and
+// errHubFrozen while a freezing count is open at the hub.
+func applyInventoryDelta(tx *gorm.DB, d inventoryDelta, now time.Time) (models.Inventory, error) {
+	var inv models.Inventory
+
+	if err := ensureHubNotFrozen(tx, d.HubID); err != nil {
+		return inv, err
+	}
+
+	if err := tx.Exec(
+		`INSERT INTO inventory(hub_id,sku_id,updated_at) VALUES(?,?,?)
+         ON CONFLICT (hub_id,sku_id) DO NOTHING`,
+		d.HubID, d.SKUID, now,
+	).Error; err != nil {
+		return inv, err
+	}
+
+	allowNegative := false
+	if d.Delta < 0 {
+		if err := tx.Raw(
+			`SELECT allow_negative_inventory FROM tenants WHERE id = ?`, d.TenantID,
+		).Scan(&allowNegative).Error; err != nil {
+			return inv, err
+		}
+	}
+
+	where := []string{"hub_id = ?", "sku_id = ?"}
+	args := []interface{}{d.Delta, now, d.HubID, d.SKUID}
+	if d.ExpectedVersion != nil {
+		where = append(where, "version = ?")
+		args = append(args, *d.ExpectedVersion)
+	}
+	if !allowNegative {
+		where = append(where, "quantity_on_hand + ? >= 0")
+		args = append(args, d.Delta)
+	}
+	if d.Delta < 0 && (d.KeepReserved || !allowNegative) {
+		where = append(where, "quantity_on_hand - quantity_reserved + ? >= 0")
+		args = append(args, d.Delta)
+	}
+
+	res := tx.Raw(
+		`UPDATE inventory SET quantity_on_hand = quantity_on_hand + ?, version = version + 1, updated_at = ?
+         WHERE `+strings.Join(where, " AND ")+`
+         RETURNING hub_id,sku_id,quantity_on_hand,quantity_reserved,quantity_in_transit,min_threshold,max_threshold,safety_stock,version,updated_at`,
+		args...,
+	).Scan(&inv)
+	if res.Error != nil {
+		return inv, res.Error
+	}
+	if res.RowsAffected == 0 {
+		var current models.Inventory
+		if err := tx.Raw(
+			`SELECT hub_id,sku_id,quantity_on_hand,quantity_reserved,quantity_in_transit,min_threshold,max_threshold,safety_stock,version,updated_at
+             FROM inventory WHERE hub_id = ? AND sku_id = ?`,
+			d.HubID, d.SKUID,
+		).Scan(&current).Error; err != nil {
+			return inv, err
+		}
+		if d.ExpectedVersion != nil && current.Version != *d.ExpectedVersion {
+			return current, errVersionConflict
+		}
+		return current, errNegativeInventory
+	}
+
+	if d.LotNumber != "" {
+		if err := applyLotDelta(tx, d.HubID, d.SKUID, d.LotNumber, d.Delta, now); err != nil {
+			return inv, err
+		}
+	} else if d.Delta < 0 {
+		if err := drainLots(tx, inv, -d.Delta, now); err != nil {
+			return inv, err
+		}
+	}
+
+	if d.LocationID != "" {
+		if err := applyBinDelta(tx, d, now); err != nil {
+			return inv, err
+		}
+	}
+
+	if err := ledger.Append(tx, models.InventoryTransaction{
+		ID:              uuid.New().String(),
+		TenantID:        d.TenantID,
+		HubID:           d.HubID,
+		SKUID:           d.SKUID,
+		Delta:           d.Delta,
+		TransactionType: d.TransactionType,
+		ReferenceID:     d.ReferenceID,
+		ReasonCode:      d.ReasonCode,
+		LotNumber:       d.LotNumber,
+		SerialNumbers:   d.SerialNumbers,
+		UOM:             d.UOM,
+		UOMQuantity:     d.UOMQuantity,
+		UnitCost:        d.UnitCost,
+		CreatedAt:       now,
+	}); err != nil {
+		return inv, err
+	}
+
+	if err := afterInventoryChange(tx, d.TenantID, inv, now); err != nil {
+		return inv, err
+	}
+
+	return inv, nil
+}
+
+// parseIfMatch reads an If-Match header carrying an inventory version, with or
+// without the quotes and weak prefix of an ETag.
+func parseIfMatch(header string) (*int64, error) {
+	if header == "" {
+		return nil, nil
+	}
+	v, err := strconv.ParseInt(strings.Trim(strings.TrimPrefix(header, "W/"), `"`), 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// adjustInventory applies a signed delta to quantity_on_hand. A precondition
+// can be given as expected_version in the body or as an If-Match header; a
+// stale version or a change that would go negative answers 409. A delta given
+// in a uom is converted to the SKU's base unit. A serialized SKU's delta moves
+// the units named by serial_numbers into or out of stock at the hub.
+func adjustInventory(c *gin.Context) {
+	var req InventoryAdjustRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Delta == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_request")})
+		return
+	}
+	if req.ExpectedVersion == nil {
+		v, err := parseIfMatch(c.GetHeader("If-Match"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_request")})
+			return
+		}
+		req.ExpectedVersion = v
+	}
+	now := time.Now().UTC()
+
+	tx := store.DB.GetMasterDB(c.Request.Context()).Begin()
+	if tx.Error != nil {
+		adjustLogger.Errorf("adjustInventory begin tx error: %v", tx.Error)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.inventory_adjust_failed")})
+		return
+	}
+	defer tx.Rollback()
+
+	if !requireLiveHubSKU(c, tx, req.TenantID, req.HubID, req.SKUID, "error.inventory_adjust_failed") {
+		return
+	}
+	if err := refuseKits(tx, req.SKUID); err != nil {
+		kitError(c, err, "error.inventory_adjust_failed")
+		return
+	}
+	delta, err := toBaseUnits(tx, req.SKUID, req.UOM, req.Delta)
+	if errors.Is(err, errUnknownUOM) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_uom")})
+		return
+	}
+	if err != nil {
+		adjustLogger.Errorf("adjustInventory uom error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.inventory_adjust_failed")})
+		return
+	}
+	units := delta
+	if units < 0 {
+		units = -units
+	}
+	serialized, err := requireSerials(tx, req.SKUID, req.SerialNumbers, units)
+	switch {
+	case err == nil && serialized && delta > 0:
+		err = receiveSerials(tx, req.TenantID, req.HubID, req.SKUID, req.SerialNumbers, now)
+	case err == nil && serialized:
+		err = shipSerials(tx, req.HubID, req.SKUID, req.SerialNumbers, now)
+	}
+	switch {
+	case errors.Is(err, errInvalidSerials):
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_serials")})
+		return
+	case errors.Is(err, errSerialConflict):
+		c.JSON(http.StatusConflict, gin.H{"error": i18n.Translate(c, "error.serial_conflict")})
+		return
+	case err != nil:
+		adjustLogger.Errorf("adjustInventory serial error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.inventory_adjust_failed")})
+		return
+	}
+
+	resp := InventoryAdjustResponse{}
+	unitCost := req.UnitCost
+	if req.UOM != "" {
+		resp.UOM, resp.UOMDelta = req.UOM, req.Delta
+		if unitCost != nil {
+			perBase := *unitCost * float64(req.Delta) / float64(delta)
+			unitCost = &perBase
+		}
+	}
+
+	inv, err := applyInventoryDelta(tx, inventoryDelta{
+		TenantID:        req.TenantID,
+		HubID:           req.HubID,
+		SKUID:           req.SKUID,
+		Delta:           delta,
+		TransactionType: constants.TransactionTypeAdjustment,
+		ReferenceID:     req.ReferenceID,
+		ExpectedVersion: req.ExpectedVersion,
+		LotNumber:       req.LotNumber,
+		LocationID:      req.LocationID,
+		SerialNumbers:   req.SerialNumbers,
+		UOM:             resp.UOM,
+		UOMQuantity:     resp.UOMDelta,
+		UnitCost:        unitCost,
+	}, now)
+	switch {
+	case errors.Is(err, errVersionConflict):
+		c.Header("ETag", fmt.Sprintf(`"%d"`, inv.Version))
+		c.JSON(http.StatusConflict, gin.H{"error": i18n.Translate(c, "error.inventory_version_conflict"), "current": inv})
+		return
+	case errors.Is(err, errNegativeInventory):
+		c.JSON(http.StatusConflict, gin.H{"error": i18n.Translate(c, "error.insufficient_inventory"), "current": inv})
+		return
+	case errors.Is(err, errHubFrozen):
+		c.JSON(http.StatusLocked, gin.H{"error": i18n.Translate(c, "error.hub_frozen")})
+		return
+	case errors.Is(err, errLotNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.Translate(c, "error.lot_not_found")})
+		return
+	case errors.Is(err, errBinNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.Translate(c, "error.bin_not_found")})
+		return
+	case errors.Is(err, errBinRequired):
+		c.JSON(http.StatusConflict, gin.H{"error": i18n.Translate(c, "error.bin_required")})
+		return
+	case err != nil:
+		adjustLogger.Errorf("adjustInventory apply error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.inventory_adjust_failed")})
+		return
+	}
+
+	if err := commitInventoryChange(c.Request.Context(), tx, req.TenantID); err != nil {
+		adjustLogger.Errorf("adjustInventory commit error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.inventory_adjust_failed")})
+		return
+	}
+
+	resp.Inventory = inv
+	c.Header("ETag", fmt.Sprintf(`"%d"`, inv.Version))
+	c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,40 @@
+package api
+
+import "testing"
+
+func TestParseIfMatch(t *testing.T) {
+	cases := []struct {
+		header  string
+		want    int64
+		wantNil bool
+		wantErr bool
+	}{
+		{header: "", wantNil: true},
+		{header: "7", want: 7},
+		{header: `"12"`, want: 12},
+		{header: `W/"3"`, want: 3},
+		{header: "abc", wantErr: true},
+	}
+	for _, tc := range cases {
+		got, err := parseIfMatch(tc.header)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("parseIfMatch(%q): expected error", tc.header)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseIfMatch(%q): unexpected error %v", tc.header, err)
+			continue
+		}
+		if tc.wantNil {
+			if got != nil {
+				t.Errorf("parseIfMatch(%q) = %d, want nil", tc.header, *got)
+			}
+			continue
+		}
+		if got == nil || *got != tc.want {
+			t.Errorf("parseIfMatch(%q) = %v, want %d", tc.header, got, tc.want)
+		}
+	}
+}
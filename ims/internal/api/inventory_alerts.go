@@ -0,0 +1,215 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/omniful/go_commons/i18n"
+	"github.com/omniful/go_commons/log"
+	"gorm.io/gorm"
+
+	"github.com/abhirup.dandapat/ims/constants"
+	"github.com/abhirup.dandapat/ims/internal/models"
+	"github.com/abhirup.dandapat/ims/internal/store"
+)
+
+var alertLogger = log.DefaultLogger()
+
+const (
+	defaultAlertPageSize = 100
+	maxAlertPageSize     = 1000
+)
+
+type InventoryThresholdRequest struct {
+	TenantID     string `json:"tenant_id"     binding:"required"`
+	HubID        string `json:"hub_id"        binding:"required"`
+	SKUID        string `json:"sku_id"        binding:"required"`
+	MinThreshold int64  `json:"min_threshold" binding:"gte=0"`
+	MaxThreshold int64  `json:"max_threshold" binding:"gte=0"`
+	// SafetyStock is left unchanged when omitted.
+	SafetyStock *int64 `json:"safety_stock" binding:"omitempty,gte=0"`
+}
+
+type thresholdCheck struct {
+	alertType string
+	breached  bool
+	quantity  int64
+	threshold int64
+}
+
+// thresholdChecks compares a row against its thresholds. Low stock looks at
+// what can still be promised (on hand minus reserved); over stock looks at
+// what is physically on hand. A zero threshold disables that check.
+func thresholdChecks(inv models.Inventory) []thresholdCheck {
+	available := inv.QuantityOnHand - inv.QuantityReserved
+	return []thresholdCheck{
+		{
+			alertType: constants.AlertTypeLowStock,
+			breached:  inv.MinThreshold > 0 && available < inv.MinThreshold,
+			quantity:  available,
+			threshold: inv.MinThreshold,
+		},
+		{
+			alertType: constants.AlertTypeOverStock,
+			breached:  inv.MaxThreshold > 0 && inv.QuantityOnHand > inv.MaxThreshold,
+			quantity:  inv.QuantityOnHand,
+			threshold: inv.MaxThreshold,
+		},
+	}
+}
+
+// evaluateInventoryAlerts raises or resolves alerts for inv inside tx. At most
+// one alert per hub/SKU/type stays open, so repeated breaches do not pile up;
+// only alerts opened by this call are returned.
+func evaluateInventoryAlerts(tx *gorm.DB, tenantID string, inv models.Inventory, now time.Time) ([]models.InventoryAlert, error) {
+	var raised []models.InventoryAlert
+	for _, chk := range thresholdChecks(inv) {
+		if !chk.breached {
+			if err := tx.Exec(
+				`UPDATE inventory_alerts SET status = ?, resolved_at = ?, updated_at = ?
+                 WHERE hub_id = ? AND sku_id = ? AND alert_type = ? AND status = ?`,
+				constants.AlertStatusResolved, now, now,
+				inv.HubID, inv.SKUID, chk.alertType, constants.AlertStatusOpen,
+			).Error; err != nil {
+				return raised, err
+			}
+			continue
+		}
+
+		var a models.InventoryAlert
+		res := tx.Raw(
+			`INSERT INTO inventory_alerts(id,tenant_id,hub_id,sku_id,alert_type,quantity,threshold,status,created_at,updated_at)
+             VALUES(?,?,?,?,?,?,?,?,?,?)
+             ON CONFLICT (hub_id,sku_id,alert_type) WHERE status = 'open' DO NOTHING
+             RETURNING id,tenant_id,hub_id,sku_id,alert_type,quantity,threshold,status,created_at,updated_at,resolved_at`,
+			uuid.New().String(), tenantID, inv.HubID, inv.SKUID, chk.alertType,
+			chk.quantity, chk.threshold, constants.AlertStatusOpen, now, now,
+		).Scan(&a)
+		if res.Error != nil {
+			return raised, res.Error
+		}
+		if res.RowsAffected > 0 {
+			raised = append(raised, a)
+		}
+	}
+	return raised, nil
+}
+
+// setInventoryThresholds stores min/max thresholds and optionally the safety
+// stock for a hub/SKU, creating the inventory row if needed, and evaluates
+// alerts against the new values.
+func setInventoryThresholds(c *gin.Context) {
+	var req InventoryThresholdRequest
+	if err := c.ShouldBindJSON(&req); err != nil ||
+		(req.MaxThreshold > 0 && req.MaxThreshold < req.MinThreshold) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_request")})
+		return
+	}
+	now := time.Now().UTC()
+
+	tx := store.DB.GetMasterDB(c.Request.Context()).Begin()
+	if tx.Error != nil {
+		alertLogger.Errorf("setInventoryThresholds begin tx error: %v", tx.Error)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.inventory_threshold_failed")})
+		return
+	}
+	defer tx.Rollback()
+
+	if !requireLiveHubSKU(c, tx, req.TenantID, req.HubID, req.SKUID, "error.inventory_threshold_failed") {
+		return
+	}
+
+	var inv models.Inventory
+	if err := tx.Raw(
+		`INSERT INTO inventory(hub_id,sku_id,min_threshold,max_threshold,safety_stock,updated_at)
+         VALUES(?,?,?,?,COALESCE(?, 0),?)
+         ON CONFLICT (hub_id,sku_id) DO UPDATE SET min_threshold = EXCLUDED.min_threshold, max_threshold = EXCLUDED.max_threshold,
+             safety_stock = COALESCE(?, inventory.safety_stock),
+             version = inventory.version + 1, updated_at = EXCLUDED.updated_at
+         RETURNING hub_id,sku_id,quantity_on_hand,quantity_reserved,quantity_in_transit,min_threshold,max_threshold,safety_stock,version,updated_at`,
+		req.HubID, req.SKUID, req.MinThreshold, req.MaxThreshold, req.SafetyStock, now, req.SafetyStock,
+	).Scan(&inv).Error; err != nil {
+		alertLogger.Errorf("setInventoryThresholds exec error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.inventory_threshold_failed")})
+		return
+	}
+
+	if err := afterInventoryChange(tx, req.TenantID, inv, now); err != nil {
+		alertLogger.Errorf("setInventoryThresholds side effects error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.inventory_threshold_failed")})
+		return
+	}
+
+	if err := commitInventoryChange(c.Request.Context(), tx, req.TenantID); err != nil {
+		alertLogger.Errorf("setInventoryThresholds commit error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.inventory_threshold_failed")})
+		return
+	}
+
+	c.JSON(http.StatusOK, inv)
+}
+
+// listInventoryAlerts pages through alerts, newest first, open ones unless
+// status says otherwise.
+func listInventoryAlerts(c *gin.Context) {
+	status := c.DefaultQuery("status", constants.AlertStatusOpen)
+
+	where := []string{"status = ?"}
+	args := []interface{}{status}
+	for _, f := range []string{"tenant_id", "hub_id", "sku_id", "alert_type"} {
+		if v := c.Query(f); v != "" {
+			where = append(where, f+" = ?")
+			args = append(args, v)
+		}
+	}
+	limit, err := parsePageLimit(c.Query("limit"), defaultAlertPageSize, maxAlertPageSize)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_request")})
+		return
+	}
+	if v := c.Query("cursor"); v != "" {
+		createdAt, id, err := decodeCreatedAtCursor(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_request")})
+			return
+		}
+		where = append(where, "(created_at, id) < (?, ?)")
+		args = append(args, createdAt, id)
+	}
+	args = append(args, limit+1)
+
+	sqlStr := `SELECT id,tenant_id,hub_id,sku_id,alert_type,quantity,threshold,status,created_at,updated_at,resolved_at
+               FROM inventory_alerts WHERE ` + strings.Join(where, " AND ") + `
+               ORDER BY created_at DESC, id DESC LIMIT ?`
+
+	db := store.DB.GetSlaveDB(c.Request.Context())
+	rows, err := db.Raw(sqlStr, args...).Rows()
+	if err != nil {
+		alertLogger.Errorf("listInventoryAlerts DB error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.list_inventory_alerts_failed")})
+		return
+	}
+	defer rows.Close()
+
+	var alerts []models.InventoryAlert
+	for rows.Next() {
+		var a models.InventoryAlert
+		if err := db.ScanRows(rows, &a); err != nil {
+			alertLogger.Warnf("scan inventory_alert row: %v", err)
+			continue
+		}
+		alerts = append(alerts, a)
+	}
+
+	resp := gin.H{"alerts": alerts}
+	if len(alerts) > limit {
+		alerts = alerts[:limit]
+		last := alerts[limit-1]
+		resp["alerts"] = alerts
+		resp["next_cursor"] = encodeCreatedAtCursor(last.CreatedAt, last.ID)
+	}
+	c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,57 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/abhirup.dandapat/ims/constants"
+	"github.com/abhirup.dandapat/ims/internal/models"
+)
+
+func TestThresholdChecks(t *testing.T) {
+	cases := []struct {
+		name              string
+		inv               models.Inventory
+		wantLow, wantOver bool
+		wantLowQty        int64
+	}{
+		{name: "no thresholds", inv: models.Inventory{QuantityOnHand: 0}},
+		{
+			name:       "low counts reserved stock out",
+			inv:        models.Inventory{QuantityOnHand: 10, QuantityReserved: 8, MinThreshold: 5},
+			wantLow:    true,
+			wantLowQty: 2,
+		},
+		{
+			name:       "at the minimum is not low",
+			inv:        models.Inventory{QuantityOnHand: 5, MinThreshold: 5},
+			wantLowQty: 5,
+		},
+		{
+			name:       "over looks at on hand",
+			inv:        models.Inventory{QuantityOnHand: 12, QuantityReserved: 4, MaxThreshold: 10},
+			wantOver:   true,
+			wantLowQty: 8,
+		},
+		{
+			name:       "within both",
+			inv:        models.Inventory{QuantityOnHand: 7, MinThreshold: 5, MaxThreshold: 10},
+			wantLowQty: 7,
+		},
+	}
+	for _, tc := range cases {
+		checks := thresholdChecks(tc.inv)
+		if len(checks) != 2 ||
+			checks[0].alertType != constants.AlertTypeLowStock || checks[1].alertType != constants.AlertTypeOverStock {
+			t.Fatalf("%s: unexpected checks %+v", tc.name, checks)
+		}
+		low, over := checks[0], checks[1]
+		if low.breached != tc.wantLow || over.breached != tc.wantOver {
+			t.Errorf("%s: breached low=%v over=%v, want low=%v over=%v",
+				tc.name, low.breached, over.breached, tc.wantLow, tc.wantOver)
+		}
+		if low.quantity != tc.wantLowQty || over.quantity != tc.inv.QuantityOnHand {
+			t.Errorf("%s: quantities low=%d over=%d, want low=%d over=%d",
+				tc.name, low.quantity, over.quantity, tc.wantLowQty, tc.inv.QuantityOnHand)
+		}
+	}
+}
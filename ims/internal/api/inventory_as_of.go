@@ -0,0 +1,55 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/omniful/go_commons/i18n"
+	"github.com/omniful/go_commons/log"
+
+	"github.com/abhirup.dandapat/ims/internal/ledger"
+	"github.com/abhirup.dandapat/ims/internal/models"
+	"github.com/abhirup.dandapat/ims/internal/store"
+)
+
+var asOfLogger = log.DefaultLogger()
+
+// getInventoryAsOf answers what a hub held at a past instant by replaying the
+// ledger from the nearest snapshot. Requested SKUs without any history at that
+// time are returned with zero quantities.
+func getInventoryAsOf(c *gin.Context) {
+	hubID := c.Query("hub_id")
+	at, err := time.Parse(time.RFC3339, c.Query("at"))
+	if hubID == "" || err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_request")})
+		return
+	}
+	at = at.UTC()
+
+	var skuIDs []string
+	if v := c.Query("sku_ids"); v != "" {
+		skuIDs = strings.Split(v, ",")
+	}
+
+	balances, err := ledger.BalancesAsOf(store.DB.GetSlaveDB(c.Request.Context()), hubID, skuIDs, at)
+	if err != nil {
+		asOfLogger.Errorf("getInventoryAsOf DB error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.list_inventory_failed")})
+		return
+	}
+
+	found := make(map[string]bool, len(balances))
+	for _, b := range balances {
+		found[b.SKUID] = true
+	}
+	for _, id := range skuIDs {
+		if !found[id] {
+			found[id] = true
+			balances = append(balances, models.InventoryBalance{HubID: hubID, SKUID: id, AsOf: at})
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"hub_id": hubID, "as_of": at, "inventory": balances})
+}
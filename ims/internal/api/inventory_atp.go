@@ -0,0 +1,186 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/omniful/go_commons/i18n"
+	"github.com/omniful/go_commons/log"
+	"gorm.io/gorm"
+
+	"github.com/abhirup.dandapat/ims/internal/models"
+	"github.com/abhirup.dandapat/ims/internal/store"
+)
+
+var atpLogger = log.DefaultLogger()
+
+// atpOptions selects which hubs count and whether safety stock is held back.
+type atpOptions struct {
+	TenantID        string
+	SKUIDs          []string
+	SubtractSafety  bool
+	IncludeInactive bool
+}
+
+// atpAvailable is what a hub can promise: on hand less reserved and, when
+// asked, less safety stock, never below zero.
+func atpAvailable(onHand, reserved, safety int64, subtractSafety bool) int64 {
+	available := onHand - reserved
+	if subtractSafety {
+		available -= safety
+	}
+	if available < 0 {
+		return 0
+	}
+	return available
+}
+
+// getInventoryATP returns, for each requested SKU, what every hub of the
+// tenant can promise and the total. Inactive hubs are left out unless
+// include_inactive is set; safety stock is only held back when
+// subtract_safety_stock is set. Kits are promised from their components.
+func getInventoryATP(c *gin.Context) {
+	opts := atpOptions{TenantID: c.Query("tenant_id")}
+	if v := c.Query("sku_ids"); v != "" {
+		opts.SKUIDs = strings.Split(v, ",")
+	}
+	var err error
+	if opts.SubtractSafety, err = parseBoolQuery(c, "subtract_safety_stock"); err == nil {
+		opts.IncludeInactive, err = parseBoolQuery(c, "include_inactive")
+	}
+	if opts.TenantID == "" || len(opts.SKUIDs) == 0 || err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_request")})
+		return
+	}
+
+	atp, err := inventoryATP(store.DB.GetSlaveDB(c.Request.Context()), opts)
+	if err != nil {
+		atpLogger.Errorf("getInventoryATP DB error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.inventory_atp_failed")})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"tenant_id": opts.TenantID, "skus": atp})
+}
+
+func parseBoolQuery(c *gin.Context, name string) (bool, error) {
+	v := c.Query(name)
+	if v == "" {
+		return false, nil
+	}
+	return strconv.ParseBool(v)
+}
+
+// inventoryATP builds one entry per requested SKU, in request order; SKUs
+// without stock anywhere get an entry with no hubs.
+func inventoryATP(db *gorm.DB, opts atpOptions) ([]models.InventoryATP, error) {
+	ph := strings.Repeat("?,", len(opts.SKUIDs))
+	ph = ph[:len(ph)-1]
+	hubFilter := " AND h.is_active"
+	if opts.IncludeInactive {
+		hubFilter = ""
+	}
+	args := []interface{}{opts.TenantID}
+	for _, id := range opts.SKUIDs {
+		args = append(args, id)
+	}
+
+	var stock []struct {
+		HubID            string `gorm:"column:hub_id"`
+		SKUID            string `gorm:"column:sku_id"`
+		QuantityOnHand   int64  `gorm:"column:quantity_on_hand"`
+		QuantityReserved int64  `gorm:"column:quantity_reserved"`
+		SafetyStock      int64  `gorm:"column:safety_stock"`
+	}
+	if err := db.Raw(
+		fmt.Sprintf(`SELECT i.hub_id, i.sku_id, i.quantity_on_hand, i.quantity_reserved, i.safety_stock
+                     FROM inventory i JOIN hubs h ON h.id = i.hub_id
+                     WHERE h.tenant_id = ? AND h.deleted_at IS NULL AND i.sku_id IN (%s)%s
+                     ORDER BY i.sku_id, i.hub_id`, ph, hubFilter),
+		args...,
+	).Scan(&stock).Error; err != nil {
+		return nil, err
+	}
+
+	// Kit components at every hub that stocks at least one of them.
+	var components []struct {
+		KitSKUID         string `gorm:"column:kit_sku_id"`
+		HubID            string `gorm:"column:hub_id"`
+		SKUID            string `gorm:"column:sku_id"`
+		Quantity         int64  `gorm:"column:quantity"`
+		QuantityOnHand   int64  `gorm:"column:quantity_on_hand"`
+		QuantityReserved int64  `gorm:"column:quantity_reserved"`
+		SafetyStock      int64  `gorm:"column:safety_stock"`
+	}
+	if err := db.Raw(
+		fmt.Sprintf(`WITH kit_hubs AS (
+                         SELECT DISTINCT k.kit_sku_id, i.hub_id
+                         FROM sku_kit_components k
+                         JOIN inventory i ON i.sku_id = k.component_sku_id
+                         JOIN hubs h ON h.id = i.hub_id
+                         WHERE h.tenant_id = ? AND h.deleted_at IS NULL AND k.kit_sku_id IN (%s)%s
+                     )
+                     SELECT kh.kit_sku_id, kh.hub_id, k.component_sku_id AS sku_id, k.quantity,
+                            COALESCE(i.quantity_on_hand, 0) AS quantity_on_hand,
+                            COALESCE(i.quantity_reserved, 0) AS quantity_reserved,
+                            COALESCE(i.safety_stock, 0) AS safety_stock
+                     FROM kit_hubs kh
+                     JOIN sku_kit_components k ON k.kit_sku_id = kh.kit_sku_id
+                     LEFT JOIN inventory i ON i.hub_id = kh.hub_id AND i.sku_id = k.component_sku_id
+                     ORDER BY kh.kit_sku_id, kh.hub_id, k.component_sku_id`, ph, hubFilter),
+		args...,
+	).Scan(&components).Error; err != nil {
+		return nil, err
+	}
+
+	idx := make(map[string]int, len(opts.SKUIDs))
+	atp := make([]models.InventoryATP, 0, len(opts.SKUIDs))
+	for _, id := range opts.SKUIDs {
+		if _, ok := idx[id]; ok {
+			continue
+		}
+		idx[id] = len(atp)
+		atp = append(atp, models.InventoryATP{SKUID: id, Hubs: []models.InventoryATPHub{}})
+	}
+
+	for _, s := range stock {
+		a := &atp[idx[s.SKUID]]
+		h := models.InventoryATPHub{
+			HubID:            s.HubID,
+			QuantityOnHand:   s.QuantityOnHand,
+			QuantityReserved: s.QuantityReserved,
+			SafetyStock:      s.SafetyStock,
+			Available:        atpAvailable(s.QuantityOnHand, s.QuantityReserved, s.SafetyStock, opts.SubtractSafety),
+		}
+		a.Hubs = append(a.Hubs, h)
+		a.Available += h.Available
+	}
+
+	// A kit holds no stock of its own, so its entry is rebuilt from the
+	// components, each counted at what the hub can promise of it.
+	var kitHubs []models.InventoryKitComponent
+	for i, comp := range components {
+		kitHubs = append(kitHubs, models.InventoryKitComponent{
+			SKUID:          comp.SKUID,
+			Quantity:       comp.Quantity,
+			QuantityOnHand: atpAvailable(comp.QuantityOnHand, comp.QuantityReserved, comp.SafetyStock, opts.SubtractSafety),
+		})
+		if next := i + 1; next < len(components) &&
+			components[next].KitSKUID == comp.KitSKUID && components[next].HubID == comp.HubID {
+			continue
+		}
+		a := &atp[idx[comp.KitSKUID]]
+		if !a.IsKit {
+			a.IsKit, a.Available, a.Hubs = true, 0, []models.InventoryATPHub{}
+		}
+		h := models.InventoryATPHub{HubID: comp.HubID, Available: kitAvailable(kitHubs)}
+		a.Hubs = append(a.Hubs, h)
+		a.Available += h.Available
+		kitHubs = nil
+	}
+
+	return atp, nil
+}
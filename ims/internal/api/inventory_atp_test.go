@@ -0,0 +1,22 @@
+package api
+
+import "testing"
+
+func TestATPAvailable(t *testing.T) {
+	cases := []struct {
+		onHand, reserved, safety int64
+		subtractSafety           bool
+		want                     int64
+	}{
+		{onHand: 10, reserved: 3, safety: 2, want: 7},
+		{onHand: 10, reserved: 3, safety: 2, subtractSafety: true, want: 5},
+		{onHand: 4, reserved: 3, safety: 2, subtractSafety: true, want: 0},
+		{onHand: -2, reserved: 0, want: 0},
+	}
+	for _, tc := range cases {
+		if got := atpAvailable(tc.onHand, tc.reserved, tc.safety, tc.subtractSafety); got != tc.want {
+			t.Errorf("atpAvailable(%d, %d, %d, %v) = %d, want %d",
+				tc.onHand, tc.reserved, tc.safety, tc.subtractSafety, got, tc.want)
+		}
+	}
+}
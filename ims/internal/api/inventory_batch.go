@@ -0,0 +1,231 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/omniful/go_commons/i18n"
+	"github.com/omniful/go_commons/log"
+	"gorm.io/gorm"
+
+	"github.com/abhirup.dandapat/ims/constants"
+	"github.com/abhirup.dandapat/ims/internal/models"
+	"github.com/abhirup.dandapat/ims/internal/store"
+)
+
+var batchLogger = log.DefaultLogger()
+
+const maxInventoryBatchRows = 5000
+
+var (
+	errBatchHubNotFound = errors.New("hub not found")
+	errBatchSKUNotFound = errors.New("sku not found")
+)
+
+// InventoryBatchRow carries either an absolute quantity (upsert) or a signed
+// delta (adjust) for one hub/SKU pair, never both.
+type InventoryBatchRow struct {
+	HubID           string   `json:"hub_id"`
+	SKUID           string   `json:"sku_id"`
+	Quantity        *int64   `json:"quantity"`
+	Delta           *int64   `json:"delta"`
+	ReferenceID     string   `json:"reference_id"`
+	ExpectedVersion *int64   `json:"expected_version"`
+	UnitCost        *float64 `json:"unit_cost"`
+}
+
+type InventoryBatchRequest struct {
+	TenantID string              `json:"tenant_id" binding:"required"`
+	Mode     string              `json:"mode"`
+	Rows     []InventoryBatchRow `json:"rows"      binding:"required,min=1"`
+}
+
+type InventoryBatchResult struct {
+	Index     int               `json:"index"`
+	HubID     string            `json:"hub_id"`
+	SKUID     string            `json:"sku_id"`
+	Status    string            `json:"status"`
+	Error     string            `json:"error,omitempty"`
+	Inventory *models.Inventory `json:"inventory,omitempty"`
+}
+
+func validateBatchRow(row InventoryBatchRow) bool {
+	if row.HubID == "" || row.SKUID == "" {
+		return false
+	}
+	if (row.Quantity == nil) == (row.Delta == nil) {
+		return false
+	}
+	if row.Quantity != nil && *row.Quantity < 0 {
+		return false
+	}
+	if row.UnitCost != nil && *row.UnitCost < 0 {
+		return false
+	}
+	return row.Delta == nil || *row.Delta != 0
+}
+
+func applyBatchRow(tx *gorm.DB, tenantID string, row InventoryBatchRow, now time.Time) (models.Inventory, error) {
+	_, err := resolveRef(tx, "hubs", tenantID, row.HubID, "")
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return models.Inventory{}, errBatchHubNotFound
+	}
+	if err == nil {
+		_, err = resolveRef(tx, "skus", tenantID, row.SKUID, "")
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.Inventory{}, errBatchSKUNotFound
+		}
+	}
+	if err != nil {
+		return models.Inventory{}, err
+	}
+	if err := refuseSerialized(tx, row.SKUID); err != nil {
+		return models.Inventory{}, err
+	}
+	if err := refuseKits(tx, row.SKUID); err != nil {
+		return models.Inventory{}, err
+	}
+	if row.Quantity != nil {
+		return upsertInventoryQuantity(tx, tenantID, row.HubID, row.SKUID, *row.Quantity, row.UnitCost, now)
+	}
+	return applyInventoryDelta(tx, inventoryDelta{
+		TenantID:        tenantID,
+		HubID:           row.HubID,
+		SKUID:           row.SKUID,
+		Delta:           *row.Delta,
+		TransactionType: constants.TransactionTypeAdjustment,
+		ReferenceID:     row.ReferenceID,
+		ExpectedVersion: row.ExpectedVersion,
+		UnitCost:        row.UnitCost,
+	}, now)
+}
+
+// batchRowError maps a row failure to the error key returned to the caller and
+// the status an all_or_nothing batch answers with: 409 for a conflict the
+// caller can fix by retrying with fresh data, 423 for a hub frozen by a count
+// and 404 for a hub or SKU that is not a live row of the tenant, as the
+// single-row endpoints answer.
+func batchRowError(err error) (string, int) {
+	switch {
+	case errors.Is(err, errVersionConflict):
+		return "error.inventory_version_conflict", http.StatusConflict
+	case errors.Is(err, errNegativeInventory):
+		return "error.insufficient_inventory", http.StatusConflict
+	case errors.Is(err, errHubFrozen):
+		return "error.hub_frozen", http.StatusLocked
+	case errors.Is(err, errBinRequired):
+		return "error.bin_required", http.StatusConflict
+	case errors.Is(err, errBatchHubNotFound):
+		return "error.hub_not_found", http.StatusNotFound
+	case errors.Is(err, errBatchSKUNotFound):
+		return "error.sku_not_found", http.StatusNotFound
+	case errors.Is(err, errSerialsRequired):
+		return "error.serial_numbers_required", http.StatusBadRequest
+	case errors.Is(err, errKitStock):
+		return "error.sku_is_kit", http.StatusConflict
+	default:
+		return "error.inventory_upsert_failed", http.StatusInternalServerError
+	}
+}
+
+// batchInventory applies many upsert/adjust rows in one transaction. In
+// all_or_nothing mode (the default) the first failing row rolls back the whole
+// batch; in best_effort mode each row runs under its own savepoint so failures
+// are reported per row while the rest commit.
+func batchInventory(c *gin.Context) {
+	var req InventoryBatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil || len(req.Rows) > maxInventoryBatchRows {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_request")})
+		return
+	}
+	if req.Mode == "" {
+		req.Mode = constants.BatchModeAllOrNothing
+	}
+	if req.Mode != constants.BatchModeAllOrNothing && req.Mode != constants.BatchModeBestEffort {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_request")})
+		return
+	}
+	bestEffort := req.Mode == constants.BatchModeBestEffort
+
+	results := make([]InventoryBatchResult, len(req.Rows))
+	invalid := false
+	for i, row := range req.Rows {
+		results[i] = InventoryBatchResult{Index: i, HubID: row.HubID, SKUID: row.SKUID, Status: constants.BatchRowSkipped}
+		if !validateBatchRow(row) {
+			results[i].Status = constants.BatchRowFailed
+			results[i].Error = i18n.Translate(c, "error.invalid_request")
+			invalid = true
+		}
+	}
+	if invalid && !bestEffort {
+		c.JSON(http.StatusBadRequest, gin.H{"mode": req.Mode, "results": results})
+		return
+	}
+	now := time.Now().UTC()
+
+	tx := store.DB.GetMasterDB(c.Request.Context()).Begin()
+	if tx.Error != nil {
+		batchLogger.Errorf("batchInventory begin tx error: %v", tx.Error)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.inventory_batch_failed")})
+		return
+	}
+	defer tx.Rollback()
+
+	applied, failed := 0, 0
+	for i, row := range req.Rows {
+		if results[i].Status == constants.BatchRowFailed {
+			failed++
+			continue
+		}
+
+		savepoint := fmt.Sprintf("batch_row_%d", i)
+		if bestEffort {
+			if err := tx.SavePoint(savepoint).Error; err != nil {
+				batchLogger.Errorf("batchInventory savepoint error: %v", err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.inventory_batch_failed")})
+				return
+			}
+		}
+
+		inv, err := applyBatchRow(tx, req.TenantID, row, now)
+		if err == nil {
+			results[i].Status = constants.BatchRowApplied
+			results[i].Inventory = &inv
+			applied++
+			continue
+		}
+
+		key, status := batchRowError(err)
+		if status == http.StatusInternalServerError {
+			batchLogger.Errorf("batchInventory row %d (hub=%s sku=%s) error: %v", i, row.HubID, row.SKUID, err)
+		}
+		results[i].Status, results[i].Error = constants.BatchRowFailed, i18n.Translate(c, key)
+		failed++
+
+		if bestEffort {
+			if err := tx.RollbackTo(savepoint).Error; err != nil {
+				batchLogger.Errorf("batchInventory rollback to savepoint error: %v", err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.inventory_batch_failed")})
+				return
+			}
+			continue
+		}
+
+		for j := 0; j < i; j++ {
+			results[j].Status, results[j].Inventory = constants.BatchRowRolledBack, nil
+		}
+		c.JSON(status, gin.H{"mode": req.Mode, "applied": 0, "failed": 1, "results": results})
+		return
+	}
+
+	if err := commitInventoryChange(c.Request.Context(), tx, req.TenantID); err != nil {
+		batchLogger.Errorf("batchInventory commit error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.inventory_batch_failed")})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"mode": req.Mode, "applied": applied, "failed": failed, "results": results})
+}
@@ -0,0 +1,56 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestValidateBatchRow(t *testing.T) {
+	n := func(v int64) *int64 { return &v }
+	cost := func(v float64) *float64 { return &v }
+	cases := []struct {
+		name string
+		row  InventoryBatchRow
+		want bool
+	}{
+		{name: "upsert", row: InventoryBatchRow{HubID: "h", SKUID: "s", Quantity: n(0)}, want: true},
+		{name: "adjust", row: InventoryBatchRow{HubID: "h", SKUID: "s", Delta: n(-3)}, want: true},
+		{name: "missing hub", row: InventoryBatchRow{SKUID: "s", Quantity: n(1)}},
+		{name: "neither quantity nor delta", row: InventoryBatchRow{HubID: "h", SKUID: "s"}},
+		{name: "both quantity and delta", row: InventoryBatchRow{HubID: "h", SKUID: "s", Quantity: n(1), Delta: n(1)}},
+		{name: "negative quantity", row: InventoryBatchRow{HubID: "h", SKUID: "s", Quantity: n(-1)}},
+		{name: "zero delta", row: InventoryBatchRow{HubID: "h", SKUID: "s", Delta: n(0)}},
+		{name: "negative cost", row: InventoryBatchRow{HubID: "h", SKUID: "s", Delta: n(1), UnitCost: cost(-1)}},
+	}
+	for _, tc := range cases {
+		if got := validateBatchRow(tc.row); got != tc.want {
+			t.Errorf("%s: validateBatchRow = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestBatchRowError(t *testing.T) {
+	cases := []struct {
+		err        error
+		wantKey    string
+		wantStatus int
+	}{
+		{errVersionConflict, "error.inventory_version_conflict", http.StatusConflict},
+		{errNegativeInventory, "error.insufficient_inventory", http.StatusConflict},
+		{fmt.Errorf("row 3: %w", errHubFrozen), "error.hub_frozen", http.StatusLocked},
+		{errBinRequired, "error.bin_required", http.StatusConflict},
+		{errBatchHubNotFound, "error.hub_not_found", http.StatusNotFound},
+		{errBatchSKUNotFound, "error.sku_not_found", http.StatusNotFound},
+		{errSerialsRequired, "error.serial_numbers_required", http.StatusBadRequest},
+		{errKitStock, "error.sku_is_kit", http.StatusConflict},
+		{errors.New("connection reset"), "error.inventory_upsert_failed", http.StatusInternalServerError},
+	}
+	for _, tc := range cases {
+		key, status := batchRowError(tc.err)
+		if key != tc.wantKey || status != tc.wantStatus {
+			t.Errorf("batchRowError(%v) = %q, %d, want %q, %d", tc.err, key, status, tc.wantKey, tc.wantStatus)
+		}
+	}
+}
@@ -0,0 +1,354 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/omniful/go_commons/i18n"
+	"github.com/omniful/go_commons/log"
+	"gorm.io/gorm"
+
+	"github.com/abhirup.dandapat/ims/constants"
+	"github.com/abhirup.dandapat/ims/internal/models"
+	"github.com/abhirup.dandapat/ims/internal/store"
+)
+
+var binLogger = log.DefaultLogger()
+
+var (
+	errBinNotFound = errors.New("bin not found in hub")
+	// errBinRequired is returned when a decrement that names no bin would
+	// take stock that is in bins.
+	errBinRequired = errors.New("stock is in bins")
+)
+
+const (
+	defaultBinMovePageSize = 100
+	maxBinMovePageSize     = 1000
+)
+
+// InventoryBinMoveRequest moves stock between two bins of a hub. Without
+// from_location_id it puts away stock not yet in any bin; without
+// to_location_id it takes stock out of its bin.
+type InventoryBinMoveRequest struct {
+	TenantID       string `json:"tenant_id"        binding:"required"`
+	HubID          string `json:"hub_id"           binding:"required"`
+	SKUID          string `json:"sku_id"           binding:"required"`
+	FromLocationID string `json:"from_location_id"`
+	ToLocationID   string `json:"to_location_id"`
+	Quantity       int64  `json:"quantity"         binding:"required,gt=0"`
+	ReferenceID    string `json:"reference_id"`
+}
+
+// changeBinQuantity adds delta to the quantity of skuID in a bin of hubID.
+func changeBinQuantity(tx *gorm.DB, hubID, skuID, locationID string, delta int64, now time.Time) error {
+	var isBin bool
+	if err := tx.Raw(
+		`SELECT EXISTS(SELECT 1 FROM hub_locations WHERE id = ? AND hub_id = ? AND location_type = ?)`,
+		locationID, hubID, constants.LocationTypeBin,
+	).Scan(&isBin).Error; err != nil {
+		return err
+	}
+	if !isBin {
+		return errBinNotFound
+	}
+
+	if delta > 0 {
+		return tx.Exec(
+			`INSERT INTO inventory_bins(location_id,hub_id,sku_id,quantity,updated_at)
+             VALUES(?,?,?,?,?)
+             ON CONFLICT (location_id,sku_id) DO UPDATE
+                SET quantity = inventory_bins.quantity + EXCLUDED.quantity, updated_at = EXCLUDED.updated_at`,
+			locationID, hubID, skuID, delta, now,
+		).Error
+	}
+	res := tx.Exec(
+		`UPDATE inventory_bins SET quantity = quantity + ?, updated_at = ?
+         WHERE location_id = ? AND sku_id = ? AND quantity + ? >= 0`,
+		delta, now, locationID, skuID, delta,
+	)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return errNegativeInventory
+	}
+	return nil
+}
+
+func recordBinMove(tx *gorm.DB, m models.InventoryBinMove) error {
+	return tx.Exec(
+		`INSERT INTO inventory_bin_moves(id,tenant_id,hub_id,sku_id,from_location_id,to_location_id,quantity,reference_id,created_at)
+         VALUES(?,?,?,?,?,?,?,?,?)`,
+		m.ID, m.TenantID, m.HubID, m.SKUID, m.FromLocationID, m.ToLocationID, m.Quantity, m.ReferenceID, m.CreatedAt,
+	).Error
+}
+
+// applyBinDelta moves a change of quantity_on_hand into or out of a bin and
+// records the move.
+func applyBinDelta(tx *gorm.DB, d inventoryDelta, now time.Time) error {
+	if err := changeBinQuantity(tx, d.HubID, d.SKUID, d.LocationID, d.Delta, now); err != nil {
+		return err
+	}
+	m := models.InventoryBinMove{
+		ID:          uuid.New().String(),
+		TenantID:    d.TenantID,
+		HubID:       d.HubID,
+		SKUID:       d.SKUID,
+		Quantity:    d.Delta,
+		ReferenceID: d.ReferenceID,
+		CreatedAt:   now,
+	}
+	if d.Delta > 0 {
+		m.ToLocationID = &d.LocationID
+	} else {
+		m.FromLocationID, m.Quantity = &d.LocationID, -d.Delta
+	}
+	return recordBinMove(tx, m)
+}
+
+func binnedQuantity(tx *gorm.DB, hubID, skuID string) (int64, error) {
+	var binned int64
+	err := tx.Raw(
+		`SELECT COALESCE(SUM(quantity),0) FROM inventory_bins WHERE hub_id = ? AND sku_id = ?`,
+		hubID, skuID,
+	).Scan(&binned).Error
+	return binned, err
+}
+
+// checkBins keeps the bins of a hub/SKU within quantity_on_hand. Stock leaving
+// the hub through a path that names no bin (commits, transfers, upserts,
+// counts) may only come from stock outside bins; binned stock is taken out of
+// its bin first, by a bin move or an adjustment with its location_id, and
+// otherwise the change is refused with errBinRequired.
+func checkBins(tx *gorm.DB, inv models.Inventory) error {
+	binned, err := binnedQuantity(tx, inv.HubID, inv.SKUID)
+	if err != nil {
+		return err
+	}
+	if binned > 0 && binned > inv.QuantityOnHand {
+		return errBinRequired
+	}
+	return nil
+}
+
+// moveInventoryBin moves stock into, out of or between bins of a live hub/SKU
+// of the tenant. Hub totals do not change, so no ledger row is posted; the
+// move itself is the record.
+func moveInventoryBin(c *gin.Context) {
+	var req InventoryBinMoveRequest
+	if err := c.ShouldBindJSON(&req); err != nil ||
+		(req.FromLocationID == "" && req.ToLocationID == "") || req.FromLocationID == req.ToLocationID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_request")})
+		return
+	}
+	now := time.Now().UTC()
+
+	tx := store.DB.GetMasterDB(c.Request.Context()).Begin()
+	if tx.Error != nil {
+		binLogger.Errorf("moveInventoryBin begin tx error: %v", tx.Error)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.inventory_bin_move_failed")})
+		return
+	}
+	defer tx.Rollback()
+
+	if !requireLiveHubSKU(c, tx, req.TenantID, req.HubID, req.SKUID, "error.inventory_bin_move_failed") {
+		return
+	}
+	m, err := applyBinMove(tx, req, now)
+	switch {
+	case errors.Is(err, errBinNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.Translate(c, "error.bin_not_found")})
+		return
+	case errors.Is(err, errNegativeInventory):
+		c.JSON(http.StatusConflict, gin.H{"error": i18n.Translate(c, "error.insufficient_inventory")})
+		return
+	case errors.Is(err, errHubFrozen):
+		c.JSON(http.StatusLocked, gin.H{"error": i18n.Translate(c, "error.hub_frozen")})
+		return
+	case err != nil:
+		binLogger.Errorf("moveInventoryBin error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.inventory_bin_move_failed")})
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		binLogger.Errorf("moveInventoryBin commit error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.inventory_bin_move_failed")})
+		return
+	}
+
+	c.JSON(http.StatusCreated, m)
+}
+
+func applyBinMove(tx *gorm.DB, req InventoryBinMoveRequest, now time.Time) (models.InventoryBinMove, error) {
+	m := models.InventoryBinMove{
+		ID:          uuid.New().String(),
+		TenantID:    req.TenantID,
+		HubID:       req.HubID,
+		SKUID:       req.SKUID,
+		Quantity:    req.Quantity,
+		ReferenceID: req.ReferenceID,
+		CreatedAt:   now,
+	}
+	if err := ensureHubNotFrozen(tx, req.HubID); err != nil {
+		return m, err
+	}
+
+	// Locking the inventory row serialises bin changes with every other
+	// write to the hub/SKU.
+	var inv models.Inventory
+	res := tx.Raw(
+		`SELECT hub_id,sku_id,quantity_on_hand,quantity_reserved,quantity_in_transit,min_threshold,max_threshold,safety_stock,version,updated_at
+         FROM inventory WHERE hub_id = ? AND sku_id = ? FOR UPDATE`,
+		req.HubID, req.SKUID,
+	).Scan(&inv)
+	if res.Error != nil {
+		return m, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return m, errNegativeInventory
+	}
+
+	if req.FromLocationID == "" {
+		binned, err := binnedQuantity(tx, req.HubID, req.SKUID)
+		if err != nil {
+			return m, err
+		}
+		if inv.QuantityOnHand-binned < req.Quantity {
+			return m, errNegativeInventory
+		}
+	} else {
+		if err := changeBinQuantity(tx, req.HubID, req.SKUID, req.FromLocationID, -req.Quantity, now); err != nil {
+			return m, err
+		}
+		m.FromLocationID = &req.FromLocationID
+	}
+	if req.ToLocationID != "" {
+		if err := changeBinQuantity(tx, req.HubID, req.SKUID, req.ToLocationID, req.Quantity, now); err != nil {
+			return m, err
+		}
+		m.ToLocationID = &req.ToLocationID
+	}
+
+	return m, recordBinMove(tx, m)
+}
+
+// listInventoryBins returns bin-level stock of a hub. The bins of a hub/SKU add
+// up to at most its quantity_on_hand in GET /inventory; the difference is stock
+// not yet put away.
+func listInventoryBins(c *gin.Context) {
+	hubID := c.Query("hub_id")
+	if hubID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_request")})
+		return
+	}
+
+	where := []string{"b.hub_id = ?", "b.quantity > 0"}
+	args := []interface{}{hubID}
+	if v := c.Query("sku_id"); v != "" {
+		where = append(where, "b.sku_id = ?")
+		args = append(args, v)
+	}
+	if v := c.Query("location_id"); v != "" {
+		where = append(where, "b.location_id = ?")
+		args = append(args, v)
+	}
+
+	sqlStr := `SELECT b.location_id,l.code,b.hub_id,b.sku_id,b.quantity,b.updated_at
+               FROM inventory_bins b JOIN hub_locations l ON l.id = b.location_id
+               WHERE ` + strings.Join(where, " AND ") + `
+               ORDER BY l.code, b.sku_id`
+
+	db := store.DB.GetSlaveDB(c.Request.Context())
+	rows, err := db.Raw(sqlStr, args...).Rows()
+	if err != nil {
+		binLogger.Errorf("listInventoryBins DB error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.list_inventory_bins_failed")})
+		return
+	}
+	defer rows.Close()
+
+	var bins []models.InventoryBin
+	for rows.Next() {
+		var b models.InventoryBin
+		if err := db.ScanRows(rows, &b); err != nil {
+			binLogger.Warnf("scan inventory_bin row: %v", err)
+			continue
+		}
+		bins = append(bins, b)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"bins": bins})
+}
+
+// listInventoryBinMoves pages through the bin moves of a hub, newest first.
+func listInventoryBinMoves(c *gin.Context) {
+	hubID := c.Query("hub_id")
+	if hubID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_request")})
+		return
+	}
+
+	where := []string{"hub_id = ?"}
+	args := []interface{}{hubID}
+	if v := c.Query("sku_id"); v != "" {
+		where = append(where, "sku_id = ?")
+		args = append(args, v)
+	}
+	if v := c.Query("location_id"); v != "" {
+		where = append(where, "(from_location_id = ? OR to_location_id = ?)")
+		args = append(args, v, v)
+	}
+	limit, err := parsePageLimit(c.Query("limit"), defaultBinMovePageSize, maxBinMovePageSize)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_request")})
+		return
+	}
+	if v := c.Query("cursor"); v != "" {
+		createdAt, id, err := decodeCreatedAtCursor(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_request")})
+			return
+		}
+		where = append(where, "(created_at, id) < (?, ?)")
+		args = append(args, createdAt, id)
+	}
+	args = append(args, limit+1)
+
+	sqlStr := `SELECT id,tenant_id,hub_id,sku_id,from_location_id,to_location_id,quantity,reference_id,created_at
+               FROM inventory_bin_moves WHERE ` + strings.Join(where, " AND ") + `
+               ORDER BY created_at DESC, id DESC LIMIT ?`
+
+	db := store.DB.GetSlaveDB(c.Request.Context())
+	rows, err := db.Raw(sqlStr, args...).Rows()
+	if err != nil {
+		binLogger.Errorf("listInventoryBinMoves DB error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.list_inventory_bins_failed")})
+		return
+	}
+	defer rows.Close()
+
+	var moves []models.InventoryBinMove
+	for rows.Next() {
+		var m models.InventoryBinMove
+		if err := db.ScanRows(rows, &m); err != nil {
+			binLogger.Warnf("scan inventory_bin_move row: %v", err)
+			continue
+		}
+		moves = append(moves, m)
+	}
+
+	resp := gin.H{"moves": moves}
+	if len(moves) > limit {
+		moves = moves[:limit]
+		last := moves[limit-1]
+		resp["moves"] = moves
+		resp["next_cursor"] = encodeCreatedAtCursor(last.CreatedAt, last.ID)
+	}
+	c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,462 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/omniful/go_commons/i18n"
+	"github.com/omniful/go_commons/log"
+	"gorm.io/gorm"
+
+	"github.com/abhirup.dandapat/ims/constants"
+	"github.com/abhirup.dandapat/ims/internal/models"
+	"github.com/abhirup.dandapat/ims/internal/store"
+)
+
+var countLogger = log.DefaultLogger()
+
+var errHubFrozen = errors.New("hub is frozen by an open count")
+
+const (
+	defaultCountPageSize = 100
+	maxCountPageSize     = 1000
+)
+
+type InventoryCountRequest struct {
+	TenantID string `json:"tenant_id" binding:"required"`
+	HubID    string `json:"hub_id"    binding:"required"`
+	Freeze   bool   `json:"freeze"`
+}
+
+type InventoryCountLineRequest struct {
+	SKUID           string `json:"sku_id"           binding:"required"`
+	CountedQuantity int64  `json:"counted_quantity" binding:"gte=0"`
+}
+
+type InventoryCountLinesRequest struct {
+	Lines []InventoryCountLineRequest `json:"lines" binding:"required,min=1,dive"`
+}
+
+// ensureHubNotFrozen returns errHubFrozen while the hub has an open count that
+// freezes movements. Approving the count closes it first, so its own
+// adjustments go through.
+func ensureHubNotFrozen(tx *gorm.DB, hubID string) error {
+	var frozen bool
+	if err := tx.Raw(
+		`SELECT EXISTS(SELECT 1 FROM inventory_counts WHERE hub_id = ? AND status = ? AND freeze)`,
+		hubID, constants.CountStatusOpen,
+	).Scan(&frozen).Error; err != nil {
+		return err
+	}
+	if frozen {
+		return errHubFrozen
+	}
+	return nil
+}
+
+// loadCount reads a count and its lines, locking the header when forUpdate is
+// set.
+func loadCount(db *gorm.DB, id string, forUpdate bool) (models.InventoryCount, bool, error) {
+	var ct models.InventoryCount
+	sqlStr := `SELECT id,tenant_id,hub_id,freeze,status,created_at,updated_at,approved_at
+               FROM inventory_counts WHERE id = ?`
+	if forUpdate {
+		sqlStr += ` FOR UPDATE`
+	}
+	res := db.Raw(sqlStr, id).Scan(&ct)
+	if res.Error != nil || res.RowsAffected == 0 {
+		return ct, false, res.Error
+	}
+	if err := db.Raw(
+		`SELECT sku_id,counted_quantity,expected_quantity,counted_quantity - expected_quantity AS variance,counted_at
+         FROM inventory_count_lines WHERE count_id = ? ORDER BY sku_id`, id,
+	).Scan(&ct.Lines).Error; err != nil {
+		return ct, true, err
+	}
+	return ct, true, nil
+}
+
+// lockOpenCount loads and locks the count named in the path, writing the error
+// response and returning false when it is missing or no longer open.
+func lockOpenCount(c *gin.Context, tx *gorm.DB, op string) (models.InventoryCount, bool) {
+	ct, found, err := loadCount(tx, c.Param("id"), true)
+	if err != nil {
+		countLogger.Errorf("%s load error: %v", op, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.inventory_count_failed")})
+		return ct, false
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.Translate(c, "error.count_not_found")})
+		return ct, false
+	}
+	if ct.Status != constants.CountStatusOpen {
+		c.JSON(http.StatusConflict, gin.H{"error": i18n.Translate(c, "error.count_closed"), "count": ct})
+		return ct, false
+	}
+	return ct, true
+}
+
+// createInventoryCount opens a stock-take session for a hub. Only one count per
+// hub can be open; with freeze set, stock movements at the hub answer 423
+// until the count is approved or cancelled.
+func createInventoryCount(c *gin.Context) {
+	var req InventoryCountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_request")})
+		return
+	}
+	now := time.Now().UTC()
+	db := store.DB.GetMasterDB(c.Request.Context())
+
+	var hubs int64
+	if err := db.Raw(
+		`SELECT COUNT(*) FROM hubs WHERE tenant_id = ? AND id = ? AND deleted_at IS NULL`, req.TenantID, req.HubID,
+	).Scan(&hubs).Error; err != nil {
+		countLogger.Errorf("createInventoryCount hub lookup error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.inventory_count_failed")})
+		return
+	}
+	if hubs == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.hub_not_found")})
+		return
+	}
+
+	ct := models.InventoryCount{
+		ID:        uuid.New().String(),
+		TenantID:  req.TenantID,
+		HubID:     req.HubID,
+		Freeze:    req.Freeze,
+		Status:    constants.CountStatusOpen,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	res := db.Exec(
+		`INSERT INTO inventory_counts(id,tenant_id,hub_id,freeze,status,created_at,updated_at)
+         VALUES(?,?,?,?,?,?,?)
+         ON CONFLICT (hub_id) WHERE status = 'open' DO NOTHING`,
+		ct.ID, ct.TenantID, ct.HubID, ct.Freeze, ct.Status, ct.CreatedAt, ct.UpdatedAt,
+	)
+	if res.Error != nil {
+		countLogger.Errorf("createInventoryCount insert error: %v", res.Error)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.inventory_count_failed")})
+		return
+	}
+	if res.RowsAffected == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": i18n.Translate(c, "error.count_already_open")})
+		return
+	}
+
+	c.JSON(http.StatusCreated, ct)
+}
+
+func getInventoryCount(c *gin.Context) {
+	ct, found, err := loadCount(store.DB.GetSlaveDB(c.Request.Context()), c.Param("id"), false)
+	if err != nil {
+		countLogger.Errorf("getInventoryCount DB error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.inventory_count_failed")})
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.Translate(c, "error.count_not_found")})
+		return
+	}
+	c.JSON(http.StatusOK, ct)
+}
+
+// listInventoryCounts pages through count headers, newest first.
+func listInventoryCounts(c *gin.Context) {
+	where := []string{"1=1"}
+	args := []interface{}{}
+	for _, f := range []string{"tenant_id", "hub_id", "status"} {
+		if v := c.Query(f); v != "" {
+			where = append(where, f+" = ?")
+			args = append(args, v)
+		}
+	}
+	limit, err := parsePageLimit(c.Query("limit"), defaultCountPageSize, maxCountPageSize)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_request")})
+		return
+	}
+	if v := c.Query("cursor"); v != "" {
+		createdAt, id, err := decodeCreatedAtCursor(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_request")})
+			return
+		}
+		where = append(where, "(created_at, id) < (?, ?)")
+		args = append(args, createdAt, id)
+	}
+	args = append(args, limit+1)
+
+	sqlStr := `SELECT id,tenant_id,hub_id,freeze,status,created_at,updated_at,approved_at
+               FROM inventory_counts WHERE ` + strings.Join(where, " AND ") + `
+               ORDER BY created_at DESC, id DESC LIMIT ?`
+
+	db := store.DB.GetSlaveDB(c.Request.Context())
+	rows, err := db.Raw(sqlStr, args...).Rows()
+	if err != nil {
+		countLogger.Errorf("listInventoryCounts DB error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.list_inventory_counts_failed")})
+		return
+	}
+	defer rows.Close()
+
+	var counts []models.InventoryCount
+	for rows.Next() {
+		var ct models.InventoryCount
+		if err := db.ScanRows(rows, &ct); err != nil {
+			countLogger.Warnf("scan inventory_count row: %v", err)
+			continue
+		}
+		counts = append(counts, ct)
+	}
+
+	resp := gin.H{"counts": counts}
+	if len(counts) > limit {
+		counts = counts[:limit]
+		last := counts[limit-1]
+		resp["counts"] = counts
+		resp["next_cursor"] = encodeCreatedAtCursor(last.CreatedAt, last.ID)
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// recordInventoryCountLines stores counted quantities. Each line also records
+// quantity_on_hand at that moment as the expected quantity; counting a SKU
+// again replaces its line.
+func recordInventoryCountLines(c *gin.Context) {
+	var req InventoryCountLinesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_request")})
+		return
+	}
+	now := time.Now().UTC()
+
+	tx := store.DB.GetMasterDB(c.Request.Context()).Begin()
+	if tx.Error != nil {
+		countLogger.Errorf("recordInventoryCountLines begin tx error: %v", tx.Error)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.inventory_count_failed")})
+		return
+	}
+	defer tx.Rollback()
+
+	ct, ok := lockOpenCount(c, tx, "recordInventoryCountLines")
+	if !ok {
+		return
+	}
+
+	for _, l := range req.Lines {
+		if err := tx.Exec(
+			`INSERT INTO inventory_count_lines(count_id,sku_id,counted_quantity,expected_quantity,counted_at)
+             VALUES(?,?,?,COALESCE((SELECT quantity_on_hand FROM inventory WHERE hub_id = ? AND sku_id = ?), 0),?)
+             ON CONFLICT (count_id,sku_id) DO UPDATE SET counted_quantity = EXCLUDED.counted_quantity,
+                 expected_quantity = EXCLUDED.expected_quantity, counted_at = EXCLUDED.counted_at`,
+			ct.ID, l.SKUID, l.CountedQuantity, ct.HubID, l.SKUID, now,
+		).Error; err != nil {
+			countLogger.Errorf("recordInventoryCountLines line %s error: %v", l.SKUID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.inventory_count_failed")})
+			return
+		}
+	}
+	if err := tx.Exec(`UPDATE inventory_counts SET updated_at = ? WHERE id = ?`, now, ct.ID).Error; err != nil {
+		countLogger.Errorf("recordInventoryCountLines touch error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.inventory_count_failed")})
+		return
+	}
+
+	ct, _, err := loadCount(tx, ct.ID, false)
+	if err != nil {
+		countLogger.Errorf("recordInventoryCountLines reload error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.inventory_count_failed")})
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		countLogger.Errorf("recordInventoryCountLines commit error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.inventory_count_failed")})
+		return
+	}
+
+	c.JSON(http.StatusOK, ct)
+}
+
+type uncountedInventory struct {
+	SKUID          string `json:"sku_id"           gorm:"column:sku_id"`
+	QuantityOnHand int64  `json:"quantity_on_hand" gorm:"column:quantity_on_hand"`
+}
+
+// getInventoryCountVariance reports counted minus expected per line, plus the
+// SKUs holding stock at the hub that were not counted. Uncounted SKUs are
+// informational; approval only adjusts counted lines.
+func getInventoryCountVariance(c *gin.Context) {
+	db := store.DB.GetSlaveDB(c.Request.Context())
+	ct, found, err := loadCount(db, c.Param("id"), false)
+	if err != nil {
+		countLogger.Errorf("getInventoryCountVariance DB error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.inventory_count_failed")})
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.Translate(c, "error.count_not_found")})
+		return
+	}
+
+	var uncounted []uncountedInventory
+	if err := db.Raw(
+		`SELECT i.sku_id, i.quantity_on_hand FROM inventory i
+         WHERE i.hub_id = ? AND i.quantity_on_hand <> 0
+           AND NOT EXISTS (SELECT 1 FROM inventory_count_lines l WHERE l.count_id = ? AND l.sku_id = i.sku_id)
+         ORDER BY i.sku_id`,
+		ct.HubID, ct.ID,
+	).Scan(&uncounted).Error; err != nil {
+		countLogger.Errorf("getInventoryCountVariance uncounted error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.inventory_count_failed")})
+		return
+	}
+
+	var total, withVariance int64
+	for _, l := range ct.Lines {
+		total += l.Variance
+		if l.Variance != 0 {
+			withVariance++
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"count_id":            ct.ID,
+		"hub_id":              ct.HubID,
+		"status":              ct.Status,
+		"lines":               ct.Lines,
+		"lines_counted":       len(ct.Lines),
+		"lines_with_variance": withVariance,
+		"total_variance":      total,
+		"uncounted":           uncounted,
+	})
+}
+
+// approveInventoryCount closes the count and posts one adjustment row per line
+// with a variance, referencing the count and tagged cycle_count. A variance on
+// a serialized SKU answers 409; those units are adjusted by serial number. So
+// does a variance on a kit, whose stock is its components'.
+func approveInventoryCount(c *gin.Context) {
+	now := time.Now().UTC()
+
+	tx := store.DB.GetMasterDB(c.Request.Context()).Begin()
+	if tx.Error != nil {
+		countLogger.Errorf("approveInventoryCount begin tx error: %v", tx.Error)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.inventory_count_failed")})
+		return
+	}
+	defer tx.Rollback()
+
+	ct, ok := lockOpenCount(c, tx, "approveInventoryCount")
+	if !ok {
+		return
+	}
+
+	ct.Status, ct.UpdatedAt, ct.ApprovedAt = constants.CountStatusApproved, now, &now
+	if err := tx.Exec(
+		`UPDATE inventory_counts SET status = ?, updated_at = ?, approved_at = ? WHERE id = ?`,
+		ct.Status, now, now, ct.ID,
+	).Error; err != nil {
+		countLogger.Errorf("approveInventoryCount status update error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.inventory_count_failed")})
+		return
+	}
+
+	for _, l := range ct.Lines {
+		if l.Variance == 0 {
+			continue
+		}
+		// A count says how many units are missing or found, not which, and a
+		// kit's stock is counted on its components.
+		err := refuseSerialized(tx, l.SKUID)
+		if errors.Is(err, errSerialsRequired) {
+			c.JSON(http.StatusConflict, gin.H{"error": i18n.Translate(c, "error.serial_numbers_required"), "sku_id": l.SKUID})
+			return
+		}
+		if err == nil {
+			err = refuseKits(tx, l.SKUID)
+		}
+		if errors.Is(err, errKitStock) {
+			c.JSON(http.StatusConflict, gin.H{"error": i18n.Translate(c, "error.sku_is_kit"), "sku_id": l.SKUID})
+			return
+		}
+		if err != nil {
+			countLogger.Errorf("approveInventoryCount line %s SKU lookup error: %v", l.SKUID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.inventory_count_failed")})
+			return
+		}
+		inv, err := applyInventoryDelta(tx, inventoryDelta{
+			TenantID:        ct.TenantID,
+			HubID:           ct.HubID,
+			SKUID:           l.SKUID,
+			Delta:           l.Variance,
+			TransactionType: constants.TransactionTypeAdjustment,
+			ReferenceID:     ct.ID,
+			ReasonCode:      constants.ReasonCodeCycleCount,
+		}, now)
+		if errors.Is(err, errNegativeInventory) {
+			c.JSON(http.StatusConflict, gin.H{"error": i18n.Translate(c, "error.insufficient_inventory"), "current": inv})
+			return
+		}
+		if errors.Is(err, errBinRequired) {
+			c.JSON(http.StatusConflict, gin.H{"error": i18n.Translate(c, "error.bin_required"), "sku_id": l.SKUID})
+			return
+		}
+		if err != nil {
+			countLogger.Errorf("approveInventoryCount line %s error: %v", l.SKUID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.inventory_count_failed")})
+			return
+		}
+	}
+
+	if err := commitInventoryChange(c.Request.Context(), tx, ct.TenantID); err != nil {
+		countLogger.Errorf("approveInventoryCount commit error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.inventory_count_failed")})
+		return
+	}
+
+	c.JSON(http.StatusOK, ct)
+}
+
+// cancelInventoryCount closes the count without adjusting anything.
+func cancelInventoryCount(c *gin.Context) {
+	now := time.Now().UTC()
+
+	tx := store.DB.GetMasterDB(c.Request.Context()).Begin()
+	if tx.Error != nil {
+		countLogger.Errorf("cancelInventoryCount begin tx error: %v", tx.Error)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.inventory_count_failed")})
+		return
+	}
+	defer tx.Rollback()
+
+	ct, ok := lockOpenCount(c, tx, "cancelInventoryCount")
+	if !ok {
+		return
+	}
+
+	ct.Status, ct.UpdatedAt = constants.CountStatusCancelled, now
+	if err := tx.Exec(
+		`UPDATE inventory_counts SET status = ?, updated_at = ? WHERE id = ?`,
+		ct.Status, now, ct.ID,
+	).Error; err != nil {
+		countLogger.Errorf("cancelInventoryCount status update error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.inventory_count_failed")})
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		countLogger.Errorf("cancelInventoryCount commit error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.inventory_count_failed")})
+		return
+	}
+
+	c.JSON(http.StatusOK, ct)
+}
@@ -0,0 +1,128 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/omniful/go_commons/i18n"
+	"github.com/omniful/go_commons/log"
+	"gorm.io/gorm"
+
+	"github.com/abhirup.dandapat/ims/constants"
+	"github.com/abhirup.dandapat/ims/internal/models"
+	"github.com/abhirup.dandapat/ims/internal/outbox"
+	"github.com/abhirup.dandapat/ims/internal/store"
+)
+
+var eventLogger = log.DefaultLogger()
+
+const (
+	defaultDeliveryPageSize = 100
+	maxDeliveryPageSize     = 1000
+)
+
+// afterInventoryChange runs the side effects shared by every write to an
+// inventory row: the inventory.updated event, threshold alerts, and an
+// inventory.low_stock / inventory.over_stock event for each newly opened alert.
+// It returns errBinRequired when the change took stock that is in bins. The
+// caller commits tx with commitInventoryChange, which retires the tenant's
+// cached GET /v2/inventory pages.
+func afterInventoryChange(tx *gorm.DB, tenantID string, inv models.Inventory, now time.Time) error {
+	if err := checkBins(tx, inv); err != nil {
+		return err
+	}
+
+	if err := outbox.Enqueue(tx, tenantID, constants.EventInventoryUpdated, inv, now); err != nil {
+		return err
+	}
+
+	raised, err := evaluateInventoryAlerts(tx, tenantID, inv, now)
+	if err != nil {
+		return err
+	}
+	for _, a := range raised {
+		event := constants.EventInventoryLowStock
+		if a.AlertType == constants.AlertTypeOverStock {
+			event = constants.EventInventoryOverStock
+		}
+		if err := outbox.Enqueue(tx, tenantID, event, a, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// commitInventoryChange commits tx, then starts a new GET /v2/inventory cache
+// generation for the tenant. Bumping only after the commit means a page
+// cached under the new generation was read after the write was visible.
+func commitInventoryChange(ctx context.Context, tx *gorm.DB, tenantID string) error {
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+	invalidateInventoryCache(ctx, tenantID, time.Now().UTC())
+	return nil
+}
+
+// listWebhookDeliveries returns the delivery log of a webhook, newest first,
+// optionally filtered by status and event.
+func listWebhookDeliveries(c *gin.Context) {
+	where := []string{"webhook_id = ?"}
+	args := []interface{}{c.Param("id")}
+	for _, f := range []string{"status", "event"} {
+		if v := c.Query(f); v != "" {
+			where = append(where, f+" = ?")
+			args = append(args, v)
+		}
+	}
+	limit, err := parsePageLimit(c.Query("limit"), defaultDeliveryPageSize, maxDeliveryPageSize)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_request")})
+		return
+	}
+	if v := c.Query("cursor"); v != "" {
+		createdAt, id, err := decodeCreatedAtCursor(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_request")})
+			return
+		}
+		where = append(where, "(created_at, id) < (?, ?)")
+		args = append(args, createdAt, id)
+	}
+	args = append(args, limit+1)
+
+	sqlStr := `SELECT id,tenant_id,webhook_id,event,payload,status,attempts,response_status,last_error,
+                      next_attempt_at,delivered_at,created_at,updated_at
+               FROM webhook_deliveries WHERE ` + strings.Join(where, " AND ") + `
+               ORDER BY created_at DESC, id DESC LIMIT ?`
+
+	db := store.DB.GetSlaveDB(c.Request.Context())
+	rows, err := db.Raw(sqlStr, args...).Rows()
+	if err != nil {
+		eventLogger.Errorf("listWebhookDeliveries DB error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.list_webhook_deliveries_failed")})
+		return
+	}
+	defer rows.Close()
+
+	var deliveries []models.WebhookDelivery
+	for rows.Next() {
+		var d models.WebhookDelivery
+		if err := db.ScanRows(rows, &d); err != nil {
+			eventLogger.Warnf("scan webhook_delivery row: %v", err)
+			continue
+		}
+		deliveries = append(deliveries, d)
+	}
+
+	resp := gin.H{"deliveries": deliveries}
+	if len(deliveries) > limit {
+		deliveries = deliveries[:limit]
+		last := deliveries[limit-1]
+		resp["deliveries"] = deliveries
+		resp["next_cursor"] = encodeCreatedAtCursor(last.CreatedAt, last.ID)
+	}
+	c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,408 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/omniful/go_commons/i18n"
+	"github.com/omniful/go_commons/log"
+	"gorm.io/gorm"
+
+	"github.com/abhirup.dandapat/ims/constants"
+	"github.com/abhirup.dandapat/ims/internal/models"
+	"github.com/abhirup.dandapat/ims/internal/store"
+)
+
+var lotLogger = log.DefaultLogger()
+
+var errLotNotFound = errors.New("inventory lot not found")
+
+const lotColumns = `hub_id,sku_id,lot_number,manufactured_at,expires_at,quantity_on_hand,quantity_reserved,created_at,updated_at`
+
+// InventoryLotReceiptRequest receives stock into a lot, creating the lot on
+// first receipt. Dates are YYYY-MM-DD and only read when the lot is created.
+// Serialized SKUs need one serial number per unit received.
+type InventoryLotReceiptRequest struct {
+	TenantID       string   `json:"tenant_id"       binding:"required"`
+	HubID          string   `json:"hub_id"          binding:"required"`
+	SKUID          string   `json:"sku_id"          binding:"required"`
+	LotNumber      string   `json:"lot_number"      binding:"required"`
+	ManufacturedAt string   `json:"manufactured_at"`
+	ExpiresAt      string   `json:"expires_at"`
+	Quantity       int64    `json:"quantity"        binding:"required,gt=0"`
+	ReferenceID    string   `json:"reference_id"`
+	SerialNumbers  []string `json:"serial_numbers"`
+	UnitCost       *float64 `json:"unit_cost"       binding:"omitempty,gte=0"`
+}
+
+// parseLotDate reads an optional YYYY-MM-DD date.
+func parseLotDate(v string) (*time.Time, error) {
+	if v == "" {
+		return nil, nil
+	}
+	d, err := time.Parse("2006-01-02", v)
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// applyLotDelta moves quantity_on_hand of one lot inside tx. A lot never holds
+// less than it has reserved.
+func applyLotDelta(tx *gorm.DB, hubID, skuID, lotNumber string, delta int64, now time.Time) error {
+	var lot models.InventoryLot
+	res := tx.Raw(
+		`SELECT `+lotColumns+` FROM inventory_lots
+         WHERE hub_id = ? AND sku_id = ? AND lot_number = ? FOR UPDATE`,
+		hubID, skuID, lotNumber,
+	).Scan(&lot)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return errLotNotFound
+	}
+	if lot.QuantityOnHand-lot.QuantityReserved+delta < 0 {
+		return errNegativeInventory
+	}
+	return tx.Exec(
+		`UPDATE inventory_lots SET quantity_on_hand = quantity_on_hand + ?, updated_at = ?
+         WHERE hub_id = ? AND sku_id = ? AND lot_number = ?`,
+		delta, now, hubID, skuID, lotNumber,
+	).Error
+}
+
+// drainLots takes a decrement of qty that named no lot, already taken out of
+// inv, out of the hub/SKU's lots first-expiring-first-out, for the part the
+// free stock held outside any lot cannot cover. Reserved lot stock is left
+// alone, so lots never hold more than the hub does unless the decrement went
+// into reserved or negative stock.
+func drainLots(tx *gorm.DB, inv models.Inventory, qty int64, now time.Time) error {
+	var lots []models.InventoryLot
+	if err := tx.Raw(
+		`SELECT `+lotColumns+` FROM inventory_lots
+         WHERE hub_id = ? AND sku_id = ?
+         ORDER BY expires_at ASC NULLS LAST, lot_number
+         FOR UPDATE`,
+		inv.HubID, inv.SKUID,
+	).Scan(&lots).Error; err != nil {
+		return err
+	}
+
+	var lotOnHand, lotReserved int64
+	for _, l := range lots {
+		lotOnHand += l.QuantityOnHand
+		lotReserved += l.QuantityReserved
+	}
+	unlotted := (inv.QuantityOnHand + qty - lotOnHand) - (inv.QuantityReserved - lotReserved)
+	remaining := qty - max(unlotted, 0)
+	for _, l := range lots {
+		if remaining <= 0 {
+			break
+		}
+		take := min(l.QuantityOnHand-l.QuantityReserved, remaining)
+		if take <= 0 {
+			continue
+		}
+		if err := tx.Exec(
+			`UPDATE inventory_lots SET quantity_on_hand = quantity_on_hand - ?, updated_at = ?
+             WHERE hub_id = ? AND sku_id = ? AND lot_number = ?`,
+			take, now, inv.HubID, inv.SKUID, l.LotNumber,
+		).Error; err != nil {
+			return err
+		}
+		remaining -= take
+	}
+	return nil
+}
+
+// allocateLots splits a reservation of qty, already added to inv, across the
+// hub/SKU's lots first-expiring-first-out, skipping expired lots, and then
+// across stock held outside any lot. SKUs without lots are allocated entirely
+// outside lots. It returns errNegativeInventory when unexpired stock is short.
+func allocateLots(tx *gorm.DB, inv models.Inventory, qty int64, now time.Time) ([]models.InventoryReservationLot, error) {
+	var lots []models.InventoryLot
+	if err := tx.Raw(
+		`SELECT `+lotColumns+` FROM inventory_lots
+         WHERE hub_id = ? AND sku_id = ?
+         ORDER BY expires_at ASC NULLS LAST, lot_number
+         FOR UPDATE`,
+		inv.HubID, inv.SKUID,
+	).Scan(&lots).Error; err != nil {
+		return nil, err
+	}
+	if len(lots) == 0 {
+		return []models.InventoryReservationLot{{Quantity: qty}}, nil
+	}
+
+	allocs, err := planLotAllocations(lots, inv, qty, now.Truncate(24*time.Hour))
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range allocs {
+		if a.LotNumber == "" {
+			continue
+		}
+		if err := tx.Exec(
+			`UPDATE inventory_lots SET quantity_reserved = quantity_reserved + ?, updated_at = ?
+             WHERE hub_id = ? AND sku_id = ? AND lot_number = ?`,
+			a.Quantity, now, inv.HubID, inv.SKUID, a.LotNumber,
+		).Error; err != nil {
+			return nil, err
+		}
+	}
+	return allocs, nil
+}
+
+// planLotAllocations picks where a reservation of qty comes from: lots in the
+// order given that have not expired on today, then stock outside any lot. inv
+// already includes qty in its reserved quantity.
+func planLotAllocations(lots []models.InventoryLot, inv models.Inventory, qty int64, today time.Time) ([]models.InventoryReservationLot, error) {
+	remaining := qty
+	var lotOnHand, lotReserved int64
+	var allocs []models.InventoryReservationLot
+	for _, l := range lots {
+		lotOnHand += l.QuantityOnHand
+		lotReserved += l.QuantityReserved
+		if remaining == 0 || l.Expired(today) {
+			continue
+		}
+		take := l.QuantityOnHand - l.QuantityReserved
+		if take <= 0 {
+			continue
+		}
+		if take > remaining {
+			take = remaining
+		}
+		allocs = append(allocs, models.InventoryReservationLot{LotNumber: l.LotNumber, Quantity: take})
+		remaining -= take
+	}
+
+	if remaining > 0 {
+		unlotted := (inv.QuantityOnHand - lotOnHand) - (inv.QuantityReserved - qty - lotReserved)
+		if unlotted > remaining {
+			unlotted = remaining
+		}
+		if unlotted > 0 {
+			allocs = append(allocs, models.InventoryReservationLot{Quantity: unlotted})
+			remaining -= unlotted
+		}
+	}
+	if remaining > 0 {
+		return nil, errNegativeInventory
+	}
+	return allocs, nil
+}
+
+// settleLots takes committed allocations out of their lots' on hand and
+// reserved quantities, or only out of reserved on release.
+func settleLots(tx *gorm.DB, hubID, skuID string, allocs []models.InventoryReservationLot, commit bool, now time.Time) error {
+	for _, a := range allocs {
+		if a.LotNumber == "" {
+			continue
+		}
+		onHand := int64(0)
+		if commit {
+			onHand = -a.Quantity
+		}
+		if err := tx.Exec(
+			`UPDATE inventory_lots SET quantity_on_hand = quantity_on_hand + ?, quantity_reserved = quantity_reserved - ?, updated_at = ?
+             WHERE hub_id = ? AND sku_id = ? AND lot_number = ?`,
+			onHand, a.Quantity, now, hubID, skuID, a.LotNumber,
+		).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func loadReservationLots(db *gorm.DB, reservationID string) ([]models.InventoryReservationLot, error) {
+	var allocs []models.InventoryReservationLot
+	err := db.Raw(
+		`SELECT lot_number,quantity FROM inventory_reservation_lots WHERE reservation_id = ? ORDER BY lot_number`,
+		reservationID,
+	).Scan(&allocs).Error
+	return allocs, err
+}
+
+// attachLots fills the lot breakdown of inventory rows of one hub.
+func attachLots(db *gorm.DB, hubID string, invs []models.Inventory) error {
+	if len(invs) == 0 {
+		return nil
+	}
+	ph := strings.Repeat("?,", len(invs))
+	args := []interface{}{hubID}
+	idx := make(map[string]int, len(invs))
+	for i, inv := range invs {
+		args = append(args, inv.SKUID)
+		idx[inv.SKUID] = i
+	}
+
+	var lots []models.InventoryLot
+	if err := db.Raw(
+		fmt.Sprintf(`SELECT `+lotColumns+` FROM inventory_lots
+                     WHERE hub_id = ? AND sku_id IN (%s)
+                     ORDER BY sku_id, expires_at ASC NULLS LAST, lot_number`, ph[:len(ph)-1]),
+		args...,
+	).Scan(&lots).Error; err != nil {
+		return err
+	}
+	for _, l := range lots {
+		if i, ok := idx[l.SKUID]; ok {
+			invs[i].Lots = append(invs[i].Lots, l)
+		}
+	}
+	return nil
+}
+
+// receiveInventoryLot books stock into a lot, creating it on first receipt,
+// and posts a receipt row carrying the lot number.
+func receiveInventoryLot(c *gin.Context) {
+	var req InventoryLotReceiptRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_request")})
+		return
+	}
+	manufacturedAt, err := parseLotDate(req.ManufacturedAt)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_request")})
+		return
+	}
+	expiresAt, err := parseLotDate(req.ExpiresAt)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_request")})
+		return
+	}
+	now := time.Now().UTC()
+
+	tx := store.DB.GetMasterDB(c.Request.Context()).Begin()
+	if tx.Error != nil {
+		lotLogger.Errorf("receiveInventoryLot begin tx error: %v", tx.Error)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.inventory_lot_failed")})
+		return
+	}
+	defer tx.Rollback()
+
+	if !requireLiveHubSKU(c, tx, req.TenantID, req.HubID, req.SKUID, "error.inventory_lot_failed") {
+		return
+	}
+	if err := refuseKits(tx, req.SKUID); err != nil {
+		kitError(c, err, "error.inventory_lot_failed")
+		return
+	}
+	serialized, err := requireSerials(tx, req.SKUID, req.SerialNumbers, req.Quantity)
+	if err == nil && serialized {
+		err = receiveSerials(tx, req.TenantID, req.HubID, req.SKUID, req.SerialNumbers, now)
+	}
+	if errors.Is(err, errInvalidSerials) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_serials")})
+		return
+	}
+	if errors.Is(err, errSerialConflict) {
+		c.JSON(http.StatusConflict, gin.H{"error": i18n.Translate(c, "error.serial_conflict")})
+		return
+	}
+	if err != nil {
+		lotLogger.Errorf("receiveInventoryLot serials error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.inventory_lot_failed")})
+		return
+	}
+
+	if err := tx.Exec(
+		`INSERT INTO inventory_lots(hub_id,sku_id,lot_number,manufactured_at,expires_at,created_at,updated_at)
+         VALUES(?,?,?,?,?,?,?)
+         ON CONFLICT (hub_id,sku_id,lot_number) DO NOTHING`,
+		req.HubID, req.SKUID, req.LotNumber, manufacturedAt, expiresAt, now, now,
+	).Error; err != nil {
+		lotLogger.Errorf("receiveInventoryLot insert lot error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.inventory_lot_failed")})
+		return
+	}
+
+	inv, err := applyInventoryDelta(tx, inventoryDelta{
+		TenantID:        req.TenantID,
+		HubID:           req.HubID,
+		SKUID:           req.SKUID,
+		Delta:           req.Quantity,
+		TransactionType: constants.TransactionTypeReceipt,
+		ReferenceID:     req.ReferenceID,
+		LotNumber:       req.LotNumber,
+		SerialNumbers:   req.SerialNumbers,
+		UnitCost:        req.UnitCost,
+	}, now)
+	if errors.Is(err, errHubFrozen) {
+		c.JSON(http.StatusLocked, gin.H{"error": i18n.Translate(c, "error.hub_frozen")})
+		return
+	}
+	if err != nil {
+		lotLogger.Errorf("receiveInventoryLot apply error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.inventory_lot_failed")})
+		return
+	}
+
+	invs := []models.Inventory{inv}
+	if err := attachLots(tx, req.HubID, invs); err != nil {
+		lotLogger.Errorf("receiveInventoryLot load lots error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.inventory_lot_failed")})
+		return
+	}
+
+	if err := commitInventoryChange(c.Request.Context(), tx, req.TenantID); err != nil {
+		lotLogger.Errorf("receiveInventoryLot commit error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.inventory_lot_failed")})
+		return
+	}
+
+	c.JSON(http.StatusOK, invs[0])
+}
+
+// listInventoryLots returns the lots of a hub in FEFO order, leaving out
+// expired and empty lots unless include_expired / include_empty are set.
+func listInventoryLots(c *gin.Context) {
+	hubID := c.Query("hub_id")
+	if hubID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_request")})
+		return
+	}
+
+	where := []string{"hub_id = ?"}
+	args := []interface{}{hubID}
+	if v := c.Query("sku_id"); v != "" {
+		where = append(where, "sku_id = ?")
+		args = append(args, v)
+	}
+	if c.Query("include_expired") != "true" {
+		where = append(where, "(expires_at IS NULL OR expires_at >= CURRENT_DATE)")
+	}
+	if c.Query("include_empty") != "true" {
+		where = append(where, "quantity_on_hand > 0")
+	}
+
+	sqlStr := `SELECT ` + lotColumns + ` FROM inventory_lots WHERE ` + strings.Join(where, " AND ") + `
+               ORDER BY sku_id, expires_at ASC NULLS LAST, lot_number`
+
+	db := store.DB.GetSlaveDB(c.Request.Context())
+	rows, err := db.Raw(sqlStr, args...).Rows()
+	if err != nil {
+		lotLogger.Errorf("listInventoryLots DB error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.list_inventory_lots_failed")})
+		return
+	}
+	defer rows.Close()
+
+	var lots []models.InventoryLot
+	for rows.Next() {
+		var l models.InventoryLot
+		if err := db.ScanRows(rows, &l); err != nil {
+			lotLogger.Warnf("scan inventory_lot row: %v", err)
+			continue
+		}
+		lots = append(lots, l)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"lots": lots})
+}
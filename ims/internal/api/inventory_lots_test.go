@@ -0,0 +1,90 @@
+package api
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/abhirup.dandapat/ims/internal/models"
+)
+
+func TestPlanLotAllocations(t *testing.T) {
+	today := time.Date(2026, 5, 10, 0, 0, 0, 0, time.UTC)
+	day := func(d int) *time.Time {
+		v := today.AddDate(0, 0, d)
+		return &v
+	}
+	// Lots come in the order allocateLots reads them: soonest expiry first,
+	// lots without an expiry last.
+	lots := []models.InventoryLot{
+		{LotNumber: "old", ExpiresAt: day(-1), QuantityOnHand: 5},
+		{LotNumber: "soon", ExpiresAt: day(0), QuantityOnHand: 4, QuantityReserved: 1},
+		{LotNumber: "later", ExpiresAt: day(30), QuantityOnHand: 6},
+		{LotNumber: "open", QuantityOnHand: 2},
+	}
+
+	cases := []struct {
+		name string
+		lots []models.InventoryLot
+		inv  models.Inventory
+		qty  int64
+		want []models.InventoryReservationLot
+		err  error
+	}{
+		{
+			name: "soonest unexpired lot first",
+			lots: lots,
+			inv:  models.Inventory{QuantityOnHand: 17, QuantityReserved: 3},
+			qty:  2,
+			want: []models.InventoryReservationLot{{LotNumber: "soon", Quantity: 2}},
+		},
+		{
+			name: "spills into later lots, skipping the expired one",
+			lots: lots,
+			inv:  models.Inventory{QuantityOnHand: 17, QuantityReserved: 11},
+			qty:  10,
+			want: []models.InventoryReservationLot{
+				{LotNumber: "soon", Quantity: 3},
+				{LotNumber: "later", Quantity: 6},
+				{LotNumber: "open", Quantity: 1},
+			},
+		},
+		{
+			name: "stock outside lots after the lots",
+			lots: lots,
+			inv:  models.Inventory{QuantityOnHand: 20, QuantityReserved: 14},
+			qty:  13,
+			want: []models.InventoryReservationLot{
+				{LotNumber: "soon", Quantity: 3},
+				{LotNumber: "later", Quantity: 6},
+				{LotNumber: "open", Quantity: 2},
+				{Quantity: 2},
+			},
+		},
+		{
+			name: "expired stock is not promised",
+			lots: lots,
+			inv:  models.Inventory{QuantityOnHand: 17, QuantityReserved: 13},
+			qty:  12,
+			err:  errNegativeInventory,
+		},
+		{
+			name: "stock outside lots already reserved",
+			lots: []models.InventoryLot{{LotNumber: "a", QuantityOnHand: 2}},
+			inv:  models.Inventory{QuantityOnHand: 5, QuantityReserved: 6},
+			qty:  3,
+			err:  errNegativeInventory,
+		},
+	}
+	for _, tc := range cases {
+		got, err := planLotAllocations(tc.lots, tc.inv, tc.qty, today)
+		if !errors.Is(err, tc.err) {
+			t.Errorf("%s: error = %v, want %v", tc.name, err, tc.err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: allocations = %+v, want %+v", tc.name, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,273 @@
+package api
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/omniful/go_commons/config"
+	"github.com/omniful/go_commons/i18n"
+	"github.com/omniful/go_commons/log"
+	"gorm.io/gorm"
+
+	"github.com/abhirup.dandapat/ims/internal/models"
+	"github.com/abhirup.dandapat/ims/internal/store"
+)
+
+var queryLogger = log.DefaultLogger()
+
+const (
+	defaultInventoryPageSize = 100
+	maxInventoryPageSize     = 1000
+	defaultInventoryCacheTTL = 30 * time.Second
+)
+
+// inventoryQuery is a parsed GET /v2/inventory request. Hub and SKU ids and
+// codes are sorted and deduplicated so equal queries share a cache entry.
+type inventoryQuery struct {
+	TenantID       string
+	HubIDs         []string
+	HubCodes       []string
+	SKUIDs         []string
+	SKUCodes       []string
+	BelowThreshold bool
+	UpdatedSince   *time.Time
+	Limit          int
+	Cursor         string
+}
+
+// splitIDs splits a comma-separated list of ids, dropping blanks and
+// duplicates, in sorted order.
+func splitIDs(v string) []string {
+	var ids []string
+	seen := make(map[string]bool)
+	for _, id := range strings.Split(v, ",") {
+		id = strings.TrimSpace(id)
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func parseInventoryQuery(c *gin.Context) (inventoryQuery, error) {
+	q := inventoryQuery{
+		TenantID: c.Query("tenant_id"),
+		HubIDs:   splitIDs(c.Query("hub_ids")),
+		HubCodes: splitIDs(c.Query("hub_codes")),
+		SKUIDs:   splitIDs(c.Query("sku_ids")),
+		SKUCodes: splitIDs(c.Query("sku_codes")),
+		Limit:    defaultInventoryPageSize,
+		Cursor:   c.Query("cursor"),
+	}
+	if q.TenantID == "" {
+		return q, errors.New("tenant_id is required")
+	}
+
+	var err error
+	if q.BelowThreshold, err = parseBoolQuery(c, "below_threshold"); err != nil {
+		return q, err
+	}
+	if v := c.Query("updated_since"); v != "" {
+		since, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return q, err
+		}
+		since = since.UTC()
+		q.UpdatedSince = &since
+	}
+	if v := c.Query("limit"); v != "" {
+		if q.Limit, err = strconv.Atoi(v); err != nil || q.Limit <= 0 {
+			return q, fmt.Errorf("invalid limit %q", v)
+		}
+		q.Limit = min(q.Limit, maxInventoryPageSize)
+	}
+	if q.Cursor != "" {
+		if _, err := decodeCursor(q.Cursor, 2); err != nil {
+			return q, err
+		}
+	}
+	return q, nil
+}
+
+func inventoryCacheGenerationKey(tenantID string) string {
+	return "inventory:v2:gen:" + tenantID
+}
+
+// cacheKey is the Redis key of the query's result under generation gen of the
+// tenant's inventory.
+func (q inventoryQuery) cacheKey(gen string) string {
+	since := ""
+	if q.UpdatedSince != nil {
+		since = q.UpdatedSince.Format(time.RFC3339Nano)
+	}
+	h := sha1.New()
+	fmt.Fprintf(h, "%s|%s|%s|%s|%t|%s|%d|%s",
+		strings.Join(q.HubIDs, ","), strings.Join(q.HubCodes, ","), strings.Join(q.SKUIDs, ","), strings.Join(q.SKUCodes, ","),
+		q.BelowThreshold, since, q.Limit, q.Cursor)
+	return "inventory:v2:" + q.TenantID + ":" + gen + ":" + hex.EncodeToString(h.Sum(nil))
+}
+
+// invalidateInventoryCache retires every cached v2 inventory page of a tenant
+// by moving it to a new generation; the old entries expire on their own.
+func invalidateInventoryCache(ctx context.Context, tenantID string, now time.Time) {
+	gen := strconv.FormatInt(now.UnixNano(), 10)
+	if _, err := store.RedisClient.Set(ctx, inventoryCacheGenerationKey(tenantID), gen, 0); err != nil {
+		queryLogger.Warnf("invalidate inventory cache for tenant %s: %v", tenantID, err)
+	}
+}
+
+func inventoryCacheTTL(ctx context.Context) time.Duration {
+	if ttl := config.GetDuration(ctx, "cache.inventoryTTL"); ttl > 0 {
+		return ttl
+	}
+	return defaultInventoryCacheTTL
+}
+
+// queryInventory answers GET /v2/inventory: a tenant's inventory ordered by
+// hub and SKU, one page at a time. Hubs and SKUs can be picked by id or by
+// code. With sku_ids or sku_codes, every requested SKU of the tenant gets a
+// row at every hub, zero-filled where nothing is stored. Pages
+// are cached in Redis per tenant generation; inventory writes start a new
+// generation once they commit (see commitInventoryChange). Pages are read
+// from the master, so one cached under the new generation never predates the
+// write, as a lagging replica's could.
+func queryInventory(c *gin.Context) {
+	q, err := parseInventoryQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_request")})
+		return
+	}
+
+	ctx := c.Request.Context()
+	gen, err := store.RedisClient.Get(ctx, inventoryCacheGenerationKey(q.TenantID))
+	if err != nil || gen == "" {
+		gen = "0"
+	}
+	key := q.cacheKey(gen)
+	if cached, err := store.RedisClient.Get(ctx, key); err == nil && cached != "" {
+		c.Data(http.StatusOK, "application/json; charset=utf-8", []byte(cached))
+		return
+	}
+
+	page, err := runInventoryQuery(store.DB.GetMasterDB(ctx), q)
+	if err != nil {
+		queryLogger.Errorf("queryInventory DB error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.list_inventory_failed")})
+		return
+	}
+
+	if b, err := json.Marshal(page); err == nil {
+		_, _ = store.RedisClient.Set(ctx, key, string(b), inventoryCacheTTL(ctx))
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// inFilter is "column IN (?,...)" for values, or "" when there are none.
+func inFilter(column string, values []string, args []interface{}) (string, []interface{}) {
+	if len(values) == 0 {
+		return "", args
+	}
+	for _, v := range values {
+		args = append(args, v)
+	}
+	ph := strings.Repeat("?,", len(values))
+	return fmt.Sprintf("%s IN (%s)", column, ph[:len(ph)-1]), args
+}
+
+// anyOf joins the non-empty conditions with OR.
+func anyOf(conds ...string) string {
+	var parts []string
+	for _, cond := range conds {
+		if cond != "" {
+			parts = append(parts, cond)
+		}
+	}
+	return "(" + strings.Join(parts, " OR ") + ")"
+}
+
+func runInventoryQuery(db *gorm.DB, q inventoryQuery) (models.InventoryQueryPage, error) {
+	var source string
+	args := []interface{}{q.TenantID}
+	if len(q.SKUIDs) > 0 || len(q.SKUCodes) > 0 {
+		var byID, byCode string
+		byID, args = inFilter("s.id", q.SKUIDs, args)
+		byCode, args = inFilter("s.code", q.SKUCodes, args)
+		source = `SELECT h.id AS hub_id, s.id AS sku_id, h.code AS hub_code, s.code AS sku_code,
+                    COALESCE(i.quantity_on_hand, 0) AS quantity_on_hand,
+                    COALESCE(i.quantity_reserved, 0) AS quantity_reserved,
+                    COALESCE(i.quantity_in_transit, 0) AS quantity_in_transit,
+                    COALESCE(i.min_threshold, 0) AS min_threshold,
+                    COALESCE(i.max_threshold, 0) AS max_threshold,
+                    COALESCE(i.safety_stock, 0) AS safety_stock,
+                    COALESCE(i.version, 0) AS version,
+                    i.updated_at
+             FROM hubs h
+             JOIN skus s ON s.tenant_id = h.tenant_id
+             LEFT JOIN inventory i ON i.hub_id = h.id AND i.sku_id = s.id
+             WHERE h.tenant_id = ? AND h.deleted_at IS NULL AND s.deleted_at IS NULL
+               AND ` + anyOf(byID, byCode)
+	} else {
+		source = `SELECT i.hub_id,i.sku_id,h.code AS hub_code,s.code AS sku_code,
+                         i.quantity_on_hand,i.quantity_reserved,i.quantity_in_transit,
+                         i.min_threshold,i.max_threshold,i.safety_stock,i.version,i.updated_at
+                  FROM inventory i JOIN hubs h ON h.id = i.hub_id JOIN skus s ON s.id = i.sku_id
+                  WHERE h.tenant_id = ? AND h.deleted_at IS NULL AND s.deleted_at IS NULL`
+	}
+
+	where := []string{"1=1"}
+	if len(q.HubIDs) > 0 || len(q.HubCodes) > 0 {
+		var byID, byCode string
+		byID, args = inFilter("hub_id", q.HubIDs, args)
+		byCode, args = inFilter("hub_code", q.HubCodes, args)
+		where = append(where, anyOf(byID, byCode))
+	}
+	if q.BelowThreshold {
+		where = append(where, "min_threshold > 0 AND quantity_on_hand - quantity_reserved < min_threshold")
+	}
+	if q.UpdatedSince != nil {
+		where = append(where, "updated_at >= ?")
+		args = append(args, *q.UpdatedSince)
+	}
+	if q.Cursor != "" {
+		after, err := decodeCursor(q.Cursor, 2)
+		if err != nil {
+			return models.InventoryQueryPage{}, err
+		}
+		where = append(where, "(hub_id, sku_id) > (?, ?)")
+		args = append(args, after[0], after[1])
+	}
+	args = append(args, q.Limit+1)
+
+	sqlStr := `SELECT * FROM (` + source + `) q WHERE ` + strings.Join(where, " AND ") +
+		` ORDER BY hub_id, sku_id LIMIT ?`
+
+	var rows []models.InventoryQueryRow
+	if err := db.Raw(sqlStr, args...).Scan(&rows).Error; err != nil {
+		return models.InventoryQueryPage{}, err
+	}
+
+	page := models.InventoryQueryPage{Items: rows}
+	if len(rows) > q.Limit {
+		page.Items = rows[:q.Limit]
+		last := page.Items[q.Limit-1]
+		page.NextCursor = encodeCursor(last.HubID, last.SKUID)
+	}
+	if page.Items == nil {
+		page.Items = []models.InventoryQueryRow{}
+	}
+	return page, nil
+}
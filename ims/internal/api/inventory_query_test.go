@@ -0,0 +1,57 @@
+package api
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitIDs(t *testing.T) {
+	cases := []struct {
+		in   string
+		want []string
+	}{
+		{in: "", want: nil},
+		{in: "b,a", want: []string{"a", "b"}},
+		{in: " a, ,b,a,", want: []string{"a", "b"}},
+	}
+	for _, tc := range cases {
+		if got := splitIDs(tc.in); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("splitIDs(%q) = %v, want %v", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestInventoryQueryCacheKey(t *testing.T) {
+	a := inventoryQuery{TenantID: "t1", SKUIDs: splitIDs("s2,s1"), Limit: 100}
+	b := inventoryQuery{TenantID: "t1", SKUIDs: splitIDs("s1,s2,s1"), Limit: 100}
+	if a.cacheKey("1") != b.cacheKey("1") {
+		t.Errorf("equal queries got different cache keys")
+	}
+	if a.cacheKey("1") == a.cacheKey("2") {
+		t.Errorf("generations share a cache key")
+	}
+	b.BelowThreshold = true
+	if a.cacheKey("1") == b.cacheKey("1") {
+		t.Errorf("different filters share a cache key")
+	}
+	byCode := inventoryQuery{TenantID: "t1", SKUCodes: splitIDs("s2,s1"), Limit: 100}
+	if a.cacheKey("1") == byCode.cacheKey("1") {
+		t.Errorf("ids and codes share a cache key")
+	}
+}
+
+func TestInventoryQueryFilters(t *testing.T) {
+	cond, args := inFilter("s.code", []string{"A", "B"}, []interface{}{"t1"})
+	if cond != "s.code IN (?,?)" || !reflect.DeepEqual(args, []interface{}{"t1", "A", "B"}) {
+		t.Errorf("inFilter = %q, %v", cond, args)
+	}
+	if cond, args := inFilter("s.id", nil, args); cond != "" || len(args) != 3 {
+		t.Errorf("inFilter of no values = %q, %v", cond, args)
+	}
+	if got := anyOf("", "s.code IN (?)"); got != "(s.code IN (?))" {
+		t.Errorf("anyOf = %q", got)
+	}
+	if got := anyOf("s.id IN (?)", "s.code IN (?)"); got != "(s.id IN (?) OR s.code IN (?))" {
+		t.Errorf("anyOf = %q", got)
+	}
+}
@@ -0,0 +1,61 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/omniful/go_commons/i18n"
+	"github.com/omniful/go_commons/log"
+
+	"github.com/abhirup.dandapat/ims/constants"
+	"github.com/abhirup.dandapat/ims/internal/ledger"
+	"github.com/abhirup.dandapat/ims/internal/store"
+)
+
+var reconcileLogger = log.DefaultLogger()
+
+type InventoryReconcileRequest struct {
+	TenantID   string `json:"tenant_id"   form:"tenant_id"`
+	HubID      string `json:"hub_id"      form:"hub_id"`
+	ReasonCode string `json:"reason_code" form:"reason_code"`
+}
+
+// getInventoryReconcile reports the hub/SKUs of a tenant or hub whose balance
+// disagrees with the ledger, without changing anything.
+func getInventoryReconcile(c *gin.Context) {
+	var req InventoryReconcileRequest
+	if err := c.ShouldBindQuery(&req); err != nil || (req.TenantID == "" && req.HubID == "") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_request")})
+		return
+	}
+	runInventoryReconcile(c, ledger.ReconcileOptions{TenantID: req.TenantID, HubID: req.HubID})
+}
+
+// reconcileInventory reports mismatches like getInventoryReconcile and posts a
+// corrective adjustment row, tagged with reason_code, for each on-hand gap.
+func reconcileInventory(c *gin.Context) {
+	var req InventoryReconcileRequest
+	if err := c.ShouldBindJSON(&req); err != nil || (req.TenantID == "" && req.HubID == "") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_request")})
+		return
+	}
+	if req.ReasonCode == "" {
+		req.ReasonCode = constants.ReasonCodeLedgerReconcile
+	}
+	runInventoryReconcile(c, ledger.ReconcileOptions{
+		TenantID:   req.TenantID,
+		HubID:      req.HubID,
+		Fix:        true,
+		ReasonCode: req.ReasonCode,
+	})
+}
+
+func runInventoryReconcile(c *gin.Context, opts ledger.ReconcileOptions) {
+	report, err := ledger.Reconcile(c.Request.Context(), store.DB, opts)
+	if err != nil {
+		reconcileLogger.Errorf("reconcile inventory (tenant=%s hub=%s fix=%t) error: %v", opts.TenantID, opts.HubID, opts.Fix, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.inventory_reconcile_failed")})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
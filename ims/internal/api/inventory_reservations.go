@@ -0,0 +1,478 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/omniful/go_commons/i18n"
+	"github.com/omniful/go_commons/log"
+	"gorm.io/gorm"
+
+	"github.com/abhirup.dandapat/ims/constants"
+	"github.com/abhirup.dandapat/ims/internal/ledger"
+	"github.com/abhirup.dandapat/ims/internal/models"
+	"github.com/abhirup.dandapat/ims/internal/store"
+)
+
+var reservationLogger = log.DefaultLogger()
+
+// errReservationExists is returned when a kit component is already reserved
+// on its own under the kit's reference.
+var errReservationExists = errors.New("reservation already exists")
+
+const reservationColumns = `id,tenant_id,hub_id,sku_id,reference_id,quantity,uom,uom_quantity,status,parent_id,created_at,updated_at`
+
+// InventoryReservationRequest identifies a reservation by hub, SKU and the
+// caller's reference (typically an order ID). Quantity and UOM are only read on
+// reserve, where a quantity in a uom is converted to the SKU's base unit, and
+// SerialNumbers only on commit, where serialized SKUs need one per unit.
+type InventoryReservationRequest struct {
+	TenantID      string   `json:"tenant_id"      binding:"required"`
+	HubID         string   `json:"hub_id"         binding:"required"`
+	SKUID         string   `json:"sku_id"         binding:"required"`
+	ReferenceID   string   `json:"reference_id"   binding:"required"`
+	Quantity      int64    `json:"quantity"`
+	UOM           string   `json:"uom"`
+	SerialNumbers []string `json:"serial_numbers"`
+}
+
+// reserveInventory holds stock against a reference without touching
+// quantity_on_hand. The quantity is allocated first-expiring-first-out across
+// unexpired lots (see allocateLots). Reserving a kit reserves each of its
+// components in the same transaction, so either all of them are held or none.
+// Reserving the same reference twice returns the existing reservation while
+// it is still held, so callers can retry safely. A retry that asks for a
+// different quantity or uom, or comes from another tenant, answers 409
+// reservation_conflict, and one for a reference already committed or released
+// answers 409 reservation_already_settled; both carry the existing reservation
+// as current.
+func reserveInventory(c *gin.Context) {
+	var req InventoryReservationRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Quantity <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_request")})
+		return
+	}
+	now := time.Now().UTC()
+
+	tx := store.DB.GetMasterDB(c.Request.Context()).Begin()
+	if tx.Error != nil {
+		reservationLogger.Errorf("reserveInventory begin tx error: %v", tx.Error)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.inventory_reserve_failed")})
+		return
+	}
+	defer tx.Rollback()
+
+	if !requireLiveHubSKU(c, tx, req.TenantID, req.HubID, req.SKUID, "error.inventory_reserve_failed") {
+		return
+	}
+	quantity, err := toBaseUnits(tx, req.SKUID, req.UOM, req.Quantity)
+	if errors.Is(err, errUnknownUOM) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_uom")})
+		return
+	}
+	if err != nil {
+		reservationLogger.Errorf("reserveInventory uom error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.inventory_reserve_failed")})
+		return
+	}
+
+	r := models.InventoryReservation{
+		ID:          uuid.New().String(),
+		TenantID:    req.TenantID,
+		HubID:       req.HubID,
+		SKUID:       req.SKUID,
+		ReferenceID: req.ReferenceID,
+		Quantity:    quantity,
+		Status:      constants.ReservationStatusReserved,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if req.UOM != "" {
+		r.UOM, r.UOMQuantity = req.UOM, req.Quantity
+	}
+	inserted, err := insertReservation(tx, r)
+	if err != nil {
+		reservationLogger.Errorf("reserveInventory insert error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.inventory_reserve_failed")})
+		return
+	}
+	if !inserted {
+		var existing models.InventoryReservation
+		if err := tx.Raw(
+			`SELECT `+reservationColumns+`
+             FROM inventory_reservations WHERE hub_id = ? AND sku_id = ? AND reference_id = ?`,
+			req.HubID, req.SKUID, req.ReferenceID,
+		).Scan(&existing).Error; err != nil {
+			reservationLogger.Errorf("reserveInventory fetch existing error: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.inventory_reserve_failed")})
+			return
+		}
+		if err := loadReservationDetail(tx, &existing, false); err != nil {
+			reservationLogger.Errorf("reserveInventory fetch existing detail error: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.inventory_reserve_failed")})
+			return
+		}
+		if existing.Status != constants.ReservationStatusReserved {
+			c.JSON(http.StatusConflict, gin.H{"error": i18n.Translate(c, "error.reservation_already_settled"), "current": existing})
+			return
+		}
+		if existing.TenantID != r.TenantID || existing.Quantity != r.Quantity ||
+			existing.UOM != r.UOM || existing.UOMQuantity != r.UOMQuantity {
+			c.JSON(http.StatusConflict, gin.H{"error": i18n.Translate(c, "error.reservation_conflict"), "current": existing})
+			return
+		}
+		c.JSON(http.StatusOK, existing)
+		return
+	}
+
+	components, err := loadKitComponents(tx, req.SKUID)
+	if err == nil {
+		if len(components) == 0 {
+			err = holdStock(tx, &r, now)
+		} else {
+			err = reserveKitComponents(tx, &r, components, now)
+		}
+	}
+	switch {
+	case errors.Is(err, errNegativeInventory):
+		c.JSON(http.StatusConflict, gin.H{"error": i18n.Translate(c, "error.insufficient_inventory")})
+		return
+	case errors.Is(err, errReservationExists):
+		c.JSON(http.StatusConflict, gin.H{"error": i18n.Translate(c, "error.reservation_conflict")})
+		return
+	case errors.Is(err, errUnknownUOM):
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_uom")})
+		return
+	case err != nil:
+		reservationLogger.Errorf("reserveInventory error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.inventory_reserve_failed")})
+		return
+	}
+
+	if err := commitInventoryChange(c.Request.Context(), tx, req.TenantID); err != nil {
+		reservationLogger.Errorf("reserveInventory commit error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.inventory_reserve_failed")})
+		return
+	}
+
+	c.JSON(http.StatusCreated, r)
+}
+
+// insertReservation stores r unless its hub, SKU and reference are already
+// reserved, and reports whether it did.
+func insertReservation(tx *gorm.DB, r models.InventoryReservation) (bool, error) {
+	res := tx.Exec(
+		`INSERT INTO inventory_reservations(`+reservationColumns+`)
+         VALUES(?,?,?,?,?,?,?,?,?,?,?,?)
+         ON CONFLICT (hub_id,sku_id,reference_id) DO NOTHING`,
+		r.ID, r.TenantID, r.HubID, r.SKUID, r.ReferenceID, r.Quantity, r.UOM, r.UOMQuantity, r.Status, r.ParentID,
+		r.CreatedAt, r.UpdatedAt,
+	)
+	return res.RowsAffected > 0, res.Error
+}
+
+// loadReservationDetail fills r's lot allocations, or for a kit its component
+// reservations with theirs. lock takes row locks on the components.
+func loadReservationDetail(tx *gorm.DB, r *models.InventoryReservation, lock bool) error {
+	sqlStr := `SELECT ` + reservationColumns + ` FROM inventory_reservations WHERE parent_id = ? ORDER BY sku_id`
+	if lock {
+		sqlStr += ` FOR UPDATE`
+	}
+	if err := tx.Raw(sqlStr, r.ID).Scan(&r.Components).Error; err != nil {
+		return err
+	}
+	for i := range r.Components {
+		allocs, err := loadReservationLots(tx, r.Components[i].ID)
+		if err != nil {
+			return err
+		}
+		r.Components[i].Allocations = allocs
+	}
+	if len(r.Components) > 0 {
+		return nil
+	}
+	allocs, err := loadReservationLots(tx, r.ID)
+	if err != nil {
+		return err
+	}
+	r.Allocations = allocs
+	return nil
+}
+
+// reserveKitComponents reserves the components of kit reservation r, which
+// holds no stock of its own.
+func reserveKitComponents(tx *gorm.DB, r *models.InventoryReservation, components []models.SKUKitComponent, now time.Time) error {
+	for _, comp := range components {
+		qty, err := convertQuantity(r.Quantity, comp.Quantity)
+		if err != nil {
+			return err
+		}
+		child := models.InventoryReservation{
+			ID:          uuid.New().String(),
+			TenantID:    r.TenantID,
+			HubID:       r.HubID,
+			SKUID:       comp.ComponentSKUID,
+			ReferenceID: r.ReferenceID,
+			Quantity:    qty,
+			Status:      constants.ReservationStatusReserved,
+			ParentID:    &r.ID,
+			CreatedAt:   now,
+			UpdatedAt:   now,
+		}
+		inserted, err := insertReservation(tx, child)
+		if err != nil {
+			return err
+		}
+		if !inserted {
+			return errReservationExists
+		}
+		if err := holdStock(tx, &child, now); err != nil {
+			return err
+		}
+		r.Components = append(r.Components, child)
+	}
+	return nil
+}
+
+// holdStock moves r's quantity into quantity_reserved, allocates it across
+// lots and posts the reserve rows. It fails with errNegativeInventory when the
+// hub has too little available.
+func holdStock(tx *gorm.DB, r *models.InventoryReservation, now time.Time) error {
+	var inv models.Inventory
+	res := tx.Raw(
+		`UPDATE inventory SET quantity_reserved = quantity_reserved + ?, version = version + 1, updated_at = ?
+         WHERE hub_id = ? AND sku_id = ? AND quantity_on_hand - quantity_reserved >= ?
+         RETURNING hub_id,sku_id,quantity_on_hand,quantity_reserved,quantity_in_transit,min_threshold,max_threshold,safety_stock,version,updated_at`,
+		r.Quantity, now, r.HubID, r.SKUID, r.Quantity,
+	).Scan(&inv)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return errNegativeInventory
+	}
+
+	allocs, err := allocateLots(tx, inv, r.Quantity, now)
+	if err != nil {
+		return err
+	}
+	r.Allocations = allocs
+
+	for _, a := range allocs {
+		if err := tx.Exec(
+			`INSERT INTO inventory_reservation_lots(reservation_id,lot_number,quantity) VALUES(?,?,?)`,
+			r.ID, a.LotNumber, a.Quantity,
+		).Error; err != nil {
+			return err
+		}
+		if err := ledger.Append(tx, models.InventoryTransaction{
+			ID:              uuid.New().String(),
+			TenantID:        r.TenantID,
+			HubID:           r.HubID,
+			SKUID:           r.SKUID,
+			Delta:           a.Quantity,
+			TransactionType: constants.TransactionTypeReserve,
+			ReferenceID:     r.ReferenceID,
+			LotNumber:       a.LotNumber,
+			CreatedAt:       now,
+		}); err != nil {
+			return err
+		}
+	}
+
+	return afterInventoryChange(tx, r.TenantID, inv, now)
+}
+
+// commitInventory consumes a reservation, removing the stock from both
+// quantity_on_hand and quantity_reserved. For serialized SKUs the shipped units
+// must be in stock at the hub and are marked shipped. Committing a kit
+// consumes every component reservation in one transaction.
+func commitInventory(c *gin.Context) {
+	settleReservation(c, constants.ReservationStatusCommitted)
+}
+
+// releaseInventory cancels a reservation and returns the stock to available.
+func releaseInventory(c *gin.Context) {
+	settleReservation(c, constants.ReservationStatusReleased)
+}
+
+func settleReservation(c *gin.Context, status string) {
+	var req InventoryReservationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_request")})
+		return
+	}
+	now := time.Now().UTC()
+
+	tx := store.DB.GetMasterDB(c.Request.Context()).Begin()
+	if tx.Error != nil {
+		reservationLogger.Errorf("settleReservation(%s) begin tx error: %v", status, tx.Error)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.inventory_settle_failed")})
+		return
+	}
+	defer tx.Rollback()
+
+	var r models.InventoryReservation
+	res := tx.Raw(
+		`SELECT `+reservationColumns+`
+         FROM inventory_reservations WHERE tenant_id = ? AND hub_id = ? AND sku_id = ? AND reference_id = ?
+         FOR UPDATE`,
+		req.TenantID, req.HubID, req.SKUID, req.ReferenceID,
+	).Scan(&r)
+	if res.Error != nil {
+		reservationLogger.Errorf("settleReservation(%s) fetch error: %v", status, res.Error)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.inventory_settle_failed")})
+		return
+	}
+	if res.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.Translate(c, "error.reservation_not_found")})
+		return
+	}
+	if r.ParentID != nil {
+		// Component reservations are settled through their kit.
+		c.JSON(http.StatusConflict, gin.H{"error": i18n.Translate(c, "error.reservation_in_kit")})
+		return
+	}
+	if r.Status == status {
+		c.JSON(http.StatusOK, r)
+		return
+	}
+	if r.Status != constants.ReservationStatusReserved {
+		c.JSON(http.StatusConflict, gin.H{"error": i18n.Translate(c, "error.reservation_already_settled")})
+		return
+	}
+
+	var children []models.InventoryReservation
+	err := tx.Raw(
+		`SELECT `+reservationColumns+` FROM inventory_reservations WHERE parent_id = ? ORDER BY sku_id FOR UPDATE`, r.ID,
+	).Scan(&children).Error
+	if err == nil {
+		if len(children) == 0 {
+			err = settleStock(tx, &r, status, req.SerialNumbers, now)
+		} else if len(req.SerialNumbers) > 0 {
+			err = errInvalidSerials
+		} else {
+			for i := range children {
+				if err = settleStock(tx, &children[i], status, nil, now); err != nil {
+					break
+				}
+			}
+			r.Components = children
+			if err == nil {
+				err = tx.Exec(
+					`UPDATE inventory_reservations SET status = ?, updated_at = ? WHERE id = ?`,
+					status, now, r.ID,
+				).Error
+			}
+		}
+	}
+	switch {
+	case errors.Is(err, errHubFrozen):
+		c.JSON(http.StatusLocked, gin.H{"error": i18n.Translate(c, "error.hub_frozen")})
+		return
+	case errors.Is(err, errInvalidSerials):
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_serials")})
+		return
+	case errors.Is(err, errSerialConflict):
+		c.JSON(http.StatusConflict, gin.H{"error": i18n.Translate(c, "error.serial_conflict")})
+		return
+	case errors.Is(err, errBinRequired):
+		c.JSON(http.StatusConflict, gin.H{"error": i18n.Translate(c, "error.bin_required")})
+		return
+	case err != nil:
+		reservationLogger.Errorf("settleReservation(%s) error: %v", status, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.inventory_settle_failed")})
+		return
+	}
+
+	if err := commitInventoryChange(c.Request.Context(), tx, r.TenantID); err != nil {
+		reservationLogger.Errorf("settleReservation(%s) commit error: %v", status, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.inventory_settle_failed")})
+		return
+	}
+
+	r.Status, r.UpdatedAt = status, now
+	c.JSON(http.StatusOK, r)
+}
+
+// settleStock commits or releases the stock held by r: a commit takes it out
+// of both quantity_on_hand and quantity_reserved and ships serials, a release
+// only out of quantity_reserved. r's status and lots are updated and one row
+// per lot is posted.
+func settleStock(tx *gorm.DB, r *models.InventoryReservation, status string, serials []string, now time.Time) error {
+	onHandDelta, txType := int64(0), constants.TransactionTypeRelease
+	if status == constants.ReservationStatusCommitted {
+		onHandDelta, txType = -r.Quantity, constants.TransactionTypeCommit
+	}
+	if onHandDelta != 0 {
+		if err := ensureHubNotFrozen(tx, r.HubID); err != nil {
+			return err
+		}
+		serialized, err := requireSerials(tx, r.SKUID, serials, r.Quantity)
+		if err == nil && serialized {
+			err = shipSerials(tx, r.HubID, r.SKUID, serials, now)
+		}
+		if err != nil {
+			return err
+		}
+	} else {
+		serials = nil
+	}
+
+	var inv models.Inventory
+	if err := tx.Raw(
+		`UPDATE inventory SET quantity_on_hand = quantity_on_hand + ?, quantity_reserved = quantity_reserved - ?,
+             version = version + 1, updated_at = ?
+         WHERE hub_id = ? AND sku_id = ?
+         RETURNING hub_id,sku_id,quantity_on_hand,quantity_reserved,quantity_in_transit,min_threshold,max_threshold,safety_stock,version,updated_at`,
+		onHandDelta, r.Quantity, now, r.HubID, r.SKUID,
+	).Scan(&inv).Error; err != nil {
+		return err
+	}
+
+	if err := tx.Exec(
+		`UPDATE inventory_reservations SET status = ?, updated_at = ? WHERE id = ?`,
+		status, now, r.ID,
+	).Error; err != nil {
+		return err
+	}
+	r.Status, r.UpdatedAt = status, now
+
+	allocs, err := loadReservationLots(tx, r.ID)
+	if err != nil {
+		return err
+	}
+	if len(allocs) == 0 {
+		allocs = []models.InventoryReservationLot{{Quantity: r.Quantity}}
+	}
+	if err := settleLots(tx, r.HubID, r.SKUID, allocs, onHandDelta != 0, now); err != nil {
+		return err
+	}
+	r.Allocations = allocs
+
+	for _, a := range allocs {
+		var moved []string
+		if len(serials) > 0 {
+			moved, serials = serials[:a.Quantity], serials[a.Quantity:]
+		}
+		if err := ledger.Append(tx, models.InventoryTransaction{
+			ID:              uuid.New().String(),
+			TenantID:        r.TenantID,
+			HubID:           r.HubID,
+			SKUID:           r.SKUID,
+			Delta:           -a.Quantity,
+			TransactionType: txType,
+			ReferenceID:     r.ReferenceID,
+			LotNumber:       a.LotNumber,
+			SerialNumbers:   moved,
+			CreatedAt:       now,
+		}); err != nil {
+			return err
+		}
+	}
+
+	return afterInventoryChange(tx, r.TenantID, inv, now)
+}
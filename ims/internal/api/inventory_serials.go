@@ -0,0 +1,317 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/omniful/go_commons/i18n"
+	"github.com/omniful/go_commons/log"
+	"gorm.io/gorm"
+
+	"github.com/abhirup.dandapat/ims/constants"
+	"github.com/abhirup.dandapat/ims/internal/models"
+	"github.com/abhirup.dandapat/ims/internal/store"
+)
+
+var serialLogger = log.DefaultLogger()
+
+var (
+	// errInvalidSerials is returned when the serial numbers given for a
+	// movement are missing, duplicated or do not match its quantity, or are
+	// given for a SKU that is not serialized.
+	errInvalidSerials = errors.New("invalid serial numbers")
+	// errSerialConflict is returned when a received unit is already in stock
+	// or a shipped unit is not in stock at the hub.
+	errSerialConflict = errors.New("serial number not in the expected state")
+	// errSerialsRequired is returned when the stock of a serialized SKU
+	// would change on a path that does not move its units.
+	errSerialsRequired = errors.New("serialized SKU moved without serial numbers")
+)
+
+const serialColumns = `sku_id,serial_number,tenant_id,hub_id,status,created_at,updated_at`
+
+// InventorySerialReceiptRequest receives units of a serialized SKU; the
+// received quantity is the number of serial numbers.
+type InventorySerialReceiptRequest struct {
+	TenantID      string   `json:"tenant_id"      binding:"required"`
+	HubID         string   `json:"hub_id"         binding:"required"`
+	SKUID         string   `json:"sku_id"         binding:"required"`
+	SerialNumbers []string `json:"serial_numbers" binding:"required"`
+	ReferenceID   string   `json:"reference_id"`
+	UnitCost      *float64 `json:"unit_cost"      binding:"omitempty,gte=0"`
+}
+
+// checkSerials validates the serial numbers of a movement of qty units.
+func checkSerials(serials []string, qty int64) error {
+	if int64(len(serials)) != qty {
+		return errInvalidSerials
+	}
+	seen := make(map[string]bool, len(serials))
+	for _, sn := range serials {
+		if strings.TrimSpace(sn) == "" || seen[sn] {
+			return errInvalidSerials
+		}
+		seen[sn] = true
+	}
+	return nil
+}
+
+func skuSerialized(db *gorm.DB, skuID string) (bool, error) {
+	var serialized bool
+	err := db.Raw(`SELECT EXISTS(SELECT 1 FROM skus WHERE id = ? AND is_serialized)`, skuID).Scan(&serialized).Error
+	return serialized, err
+}
+
+// serialFlagFixed holds for a SKU whose is_serialized may no longer change:
+// one with stock or registered serial numbers, whose units would stop or
+// start needing serials midway, or a kit or kit component, which must stay
+// unserialized. It is a condition on a row of skus.
+const serialFlagFixed = `(EXISTS(SELECT 1 FROM inventory WHERE sku_id = skus.id
+                                AND (quantity_on_hand <> 0 OR quantity_reserved <> 0 OR quantity_in_transit <> 0))
+     OR EXISTS(SELECT 1 FROM inventory_serials WHERE sku_id = skus.id)
+     OR EXISTS(SELECT 1 FROM sku_kit_components WHERE kit_sku_id = skus.id OR component_sku_id = skus.id))`
+
+// refuseSerialized returns errSerialsRequired when any of skuIDs is
+// serialized.
+func refuseSerialized(db *gorm.DB, skuIDs ...string) error {
+	var serialized bool
+	if err := db.Raw(
+		`SELECT EXISTS(SELECT 1 FROM skus WHERE id IN (?) AND is_serialized)`, skuIDs,
+	).Scan(&serialized).Error; err != nil {
+		return err
+	}
+	if serialized {
+		return errSerialsRequired
+	}
+	return nil
+}
+
+// requireSerials checks that serials fit a movement of qty units of skuID:
+// serialized SKUs need one serial per unit, other SKUs none. It reports
+// whether the SKU is serialized.
+func requireSerials(db *gorm.DB, skuID string, serials []string, qty int64) (bool, error) {
+	serialized, err := skuSerialized(db, skuID)
+	if err != nil {
+		return false, err
+	}
+	if !serialized {
+		if len(serials) > 0 {
+			return false, errInvalidSerials
+		}
+		return false, nil
+	}
+	return true, checkSerials(serials, qty)
+}
+
+// receiveSerials puts units in stock at hubID. A unit is new or was shipped
+// earlier; receiving a unit that is still in stock anywhere is a conflict.
+func receiveSerials(tx *gorm.DB, tenantID, hubID, skuID string, serials []string, now time.Time) error {
+	for _, sn := range serials {
+		res := tx.Exec(
+			`INSERT INTO inventory_serials(sku_id,serial_number,tenant_id,hub_id,status,created_at,updated_at)
+             VALUES(?,?,?,?,?,?,?)
+             ON CONFLICT (sku_id,serial_number) DO UPDATE
+                SET tenant_id = EXCLUDED.tenant_id, hub_id = EXCLUDED.hub_id,
+                    status = EXCLUDED.status, updated_at = EXCLUDED.updated_at
+              WHERE inventory_serials.status = ?`,
+			skuID, sn, tenantID, hubID, constants.SerialStatusInStock, now, now,
+			constants.SerialStatusShipped,
+		)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return errSerialConflict
+		}
+	}
+	return nil
+}
+
+// shipSerials marks units in stock at hubID as shipped.
+func shipSerials(tx *gorm.DB, hubID, skuID string, serials []string, now time.Time) error {
+	ph := strings.Repeat("?,", len(serials))
+	args := []interface{}{constants.SerialStatusShipped, now, hubID, skuID, constants.SerialStatusInStock}
+	for _, sn := range serials {
+		args = append(args, sn)
+	}
+	res := tx.Exec(
+		fmt.Sprintf(`UPDATE inventory_serials SET status = ?, updated_at = ?
+                     WHERE hub_id = ? AND sku_id = ? AND status = ? AND serial_number IN (%s)`, ph[:len(ph)-1]),
+		args...,
+	)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected != int64(len(serials)) {
+		return errSerialConflict
+	}
+	return nil
+}
+
+// receiveInventorySerials receives units of a serialized SKU and posts a
+// receipt row linked to their serial numbers.
+func receiveInventorySerials(c *gin.Context) {
+	var req InventorySerialReceiptRequest
+	if err := c.ShouldBindJSON(&req); err != nil || len(req.SerialNumbers) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_request")})
+		return
+	}
+	now := time.Now().UTC()
+
+	tx := store.DB.GetMasterDB(c.Request.Context()).Begin()
+	if tx.Error != nil {
+		serialLogger.Errorf("receiveInventorySerials begin tx error: %v", tx.Error)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.inventory_serial_failed")})
+		return
+	}
+	defer tx.Rollback()
+
+	if !requireLiveHubSKU(c, tx, req.TenantID, req.HubID, req.SKUID, "error.inventory_serial_failed") {
+		return
+	}
+	inv, err := receiveSerialUnits(tx, req, now)
+	switch {
+	case errors.Is(err, errInvalidSerials):
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_serials")})
+		return
+	case errors.Is(err, errSerialConflict):
+		c.JSON(http.StatusConflict, gin.H{"error": i18n.Translate(c, "error.serial_conflict")})
+		return
+	case errors.Is(err, errHubFrozen):
+		c.JSON(http.StatusLocked, gin.H{"error": i18n.Translate(c, "error.hub_frozen")})
+		return
+	case err != nil:
+		serialLogger.Errorf("receiveInventorySerials error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.inventory_serial_failed")})
+		return
+	}
+
+	if err := commitInventoryChange(c.Request.Context(), tx, req.TenantID); err != nil {
+		serialLogger.Errorf("receiveInventorySerials commit error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.inventory_serial_failed")})
+		return
+	}
+
+	c.JSON(http.StatusOK, inv)
+}
+
+func receiveSerialUnits(tx *gorm.DB, req InventorySerialReceiptRequest, now time.Time) (models.Inventory, error) {
+	qty := int64(len(req.SerialNumbers))
+	serialized, err := requireSerials(tx, req.SKUID, req.SerialNumbers, qty)
+	if err != nil {
+		return models.Inventory{}, err
+	}
+	if !serialized {
+		return models.Inventory{}, errInvalidSerials
+	}
+	if err := receiveSerials(tx, req.TenantID, req.HubID, req.SKUID, req.SerialNumbers, now); err != nil {
+		return models.Inventory{}, err
+	}
+	return applyInventoryDelta(tx, inventoryDelta{
+		TenantID:        req.TenantID,
+		HubID:           req.HubID,
+		SKUID:           req.SKUID,
+		Delta:           qty,
+		TransactionType: constants.TransactionTypeReceipt,
+		ReferenceID:     req.ReferenceID,
+		SerialNumbers:   req.SerialNumbers,
+		UnitCost:        req.UnitCost,
+	}, now)
+}
+
+// getInventorySerial looks a unit up by serial number and returns its current
+// hub and status with the ledger rows that moved it. Serial numbers are only
+// unique per SKU, so every matching unit is returned unless sku_id is given.
+func getInventorySerial(c *gin.Context) {
+	where := []string{"serial_number = ?"}
+	args := []interface{}{c.Param("serial")}
+	if v := c.Query("sku_id"); v != "" {
+		where = append(where, "sku_id = ?")
+		args = append(args, v)
+	}
+	if v := c.Query("tenant_id"); v != "" {
+		where = append(where, "tenant_id = ?")
+		args = append(args, v)
+	}
+
+	db := store.DB.GetSlaveDB(c.Request.Context())
+	var serials []models.InventorySerial
+	if err := db.Raw(
+		`SELECT `+serialColumns+` FROM inventory_serials WHERE `+strings.Join(where, " AND ")+` ORDER BY sku_id`,
+		args...,
+	).Scan(&serials).Error; err != nil {
+		serialLogger.Errorf("getInventorySerial DB error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.inventory_serial_failed")})
+		return
+	}
+	if len(serials) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.Translate(c, "error.serial_not_found")})
+		return
+	}
+
+	for i := range serials {
+		if err := db.Raw(
+			`SELECT t.id,t.tenant_id,t.hub_id,t.sku_id,t.delta,t.transaction_type,t.reference_id,t.reason_code,t.lot_number,t.uom,t.uom_quantity,t.unit_cost,t.total_cost,t.created_at
+             FROM inventory_transactions t
+             JOIN inventory_transaction_serials s ON s.transaction_id = t.id
+             WHERE s.sku_id = ? AND s.serial_number = ?
+             ORDER BY t.created_at, t.id`,
+			serials[i].SKUID, serials[i].SerialNumber,
+		).Scan(&serials[i].History).Error; err != nil {
+			serialLogger.Errorf("getInventorySerial history error: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.inventory_serial_failed")})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"serials": serials})
+}
+
+// listInventorySerials lists the units at a hub, optionally for one SKU and
+// in one status.
+func listInventorySerials(c *gin.Context) {
+	hubID := c.Query("hub_id")
+	if hubID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_request")})
+		return
+	}
+
+	where := []string{"hub_id = ?"}
+	args := []interface{}{hubID}
+	if v := c.Query("sku_id"); v != "" {
+		where = append(where, "sku_id = ?")
+		args = append(args, v)
+	}
+	if v := c.Query("status"); v != "" {
+		where = append(where, "status = ?")
+		args = append(args, v)
+	}
+
+	sqlStr := `SELECT ` + serialColumns + ` FROM inventory_serials WHERE ` + strings.Join(where, " AND ") + `
+               ORDER BY sku_id, serial_number`
+
+	db := store.DB.GetSlaveDB(c.Request.Context())
+	rows, err := db.Raw(sqlStr, args...).Rows()
+	if err != nil {
+		serialLogger.Errorf("listInventorySerials DB error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.list_inventory_serials_failed")})
+		return
+	}
+	defer rows.Close()
+
+	var serials []models.InventorySerial
+	for rows.Next() {
+		var s models.InventorySerial
+		if err := db.ScanRows(rows, &s); err != nil {
+			serialLogger.Warnf("scan inventory_serial row: %v", err)
+			continue
+		}
+		serials = append(serials, s)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"serials": serials})
+}
@@ -0,0 +1,26 @@
+package api
+
+import "testing"
+
+func TestCheckSerials(t *testing.T) {
+	cases := []struct {
+		serials []string
+		qty     int64
+		wantErr bool
+	}{
+		{serials: []string{"A1", "A2"}, qty: 2},
+		{serials: []string{"A1"}, qty: 2, wantErr: true},
+		{serials: []string{"A1", "A1"}, qty: 2, wantErr: true},
+		{serials: []string{"A1", " "}, qty: 2, wantErr: true},
+		{serials: nil, qty: 0},
+	}
+	for _, tc := range cases {
+		err := checkSerials(tc.serials, tc.qty)
+		if tc.wantErr && err == nil {
+			t.Errorf("checkSerials(%q, %d): expected error", tc.serials, tc.qty)
+		}
+		if !tc.wantErr && err != nil {
+			t.Errorf("checkSerials(%q, %d): unexpected error %v", tc.serials, tc.qty, err)
+		}
+	}
+}
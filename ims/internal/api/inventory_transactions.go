@@ -1,10 +1,15 @@
 package api
 
 import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/abhirup.dandapat/ims/internal/ledger"
 	"github.com/abhirup.dandapat/ims/internal/models"
 	"github.com/abhirup.dandapat/ims/internal/store"
 	"github.com/gin-gonic/gin"
@@ -15,13 +20,29 @@ import (
 
 var invTxLogger = log.DefaultLogger()
 
+const (
+	defaultTransactionPageSize = 100
+	maxTransactionPageSize     = 1000
+
+	// exportFlushRows is how many rows an export writes between flushes.
+	exportFlushRows = 500
+
+	transactionColumns = `id,tenant_id,hub_id,sku_id,delta,transaction_type,reference_id,reason_code,lot_number,uom,uom_quantity,unit_cost,total_cost,created_at`
+)
+
+// transactionCSVHeader names the columns of a CSV export, in the order
+// transactionCSVRecord writes them.
+var transactionCSVHeader = strings.Split(transactionColumns, ",")
+
 type InventoryTransactionRequest struct {
-	TenantID        string `json:"tenant_id"        form:"tenant_id"`
-	HubID           string `json:"hub_id"           form:"hub_id"`
-	SKUID           string `json:"sku_id"           form:"sku_id"`
-	Delta           int64  `json:"delta"            form:"delta"`
-	TransactionType string `json:"transaction_type" form:"transaction_type"`
-	ReferenceID     string `json:"reference_id"     form:"reference_id"`
+	TenantID        string   `json:"tenant_id"        form:"tenant_id"`
+	HubID           string   `json:"hub_id"           form:"hub_id"`
+	SKUID           string   `json:"sku_id"           form:"sku_id"`
+	Delta           int64    `json:"delta"            form:"delta"`
+	TransactionType string   `json:"transaction_type" form:"transaction_type"`
+	ReferenceID     string   `json:"reference_id"     form:"reference_id"`
+	ReasonCode      string   `json:"reason_code"      form:"reason_code"`
+	UnitCost        *float64 `json:"unit_cost"      form:"-"`
 }
 
 func createInventoryTransaction(c *gin.Context) {
@@ -39,32 +60,37 @@ func createInventoryTransaction(c *gin.Context) {
 		Delta:           req.Delta,
 		TransactionType: req.TransactionType,
 		ReferenceID:     req.ReferenceID,
+		ReasonCode:      req.ReasonCode,
+		UnitCost:        req.UnitCost,
 		CreatedAt:       time.Now().UTC(),
 	}
 
-	db := store.DB.GetMasterDB(c.Request.Context())
-	if err := db.Exec(
-		`INSERT INTO inventory_transactions
-		 (id,tenant_id,hub_id,sku_id,delta,transaction_type,reference_id,created_at)
-		 VALUES(?,?,?,?,?,?,?,?)`,
-		tx.ID, tx.TenantID, tx.HubID, tx.SKUID,
-		tx.Delta, tx.TransactionType, tx.ReferenceID, tx.CreatedAt,
-	).Error; err != nil {
+	// The cost layers, the row and its event commit together.
+	db := store.DB.GetMasterDB(c.Request.Context()).Begin()
+	if db.Error != nil {
+		invTxLogger.Errorf("createInventoryTransaction begin error: %v", db.Error)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.inventory_transaction_failed")})
+		return
+	}
+	defer db.Rollback()
+	if err := ledger.Append(db, tx); err != nil {
 		invTxLogger.Errorf("createInventoryTransaction DB error: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.inventory_transaction_failed")})
 		return
 	}
+	if err := db.Commit().Error; err != nil {
+		invTxLogger.Errorf("createInventoryTransaction commit error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.inventory_transaction_failed")})
+		return
+	}
 
 	c.JSON(http.StatusCreated, tx)
 }
 
-func listInventoryTransactions(c *gin.Context) {
-	var req InventoryTransactionRequest
-	if err := c.ShouldBindQuery(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_request")})
-		return
-	}
-
+// transactionFilters builds the WHERE clause of a ledger listing: tenant,
+// hub, SKU, reference, one or more comma-separated transaction types and a
+// [from, to) range on created_at.
+func transactionFilters(c *gin.Context, req InventoryTransactionRequest) ([]string, []interface{}, error) {
 	where := []string{"1=1"}
 	args := []interface{}{}
 	if req.TenantID != "" {
@@ -79,11 +105,85 @@ func listInventoryTransactions(c *gin.Context) {
 		where = append(where, "sku_id = ?")
 		args = append(args, req.SKUID)
 	}
+	if req.ReferenceID != "" {
+		where = append(where, "reference_id = ?")
+		args = append(args, req.ReferenceID)
+	}
+	if types := splitIDs(req.TransactionType); len(types) > 0 {
+		ph := strings.Repeat("?,", len(types))
+		where = append(where, fmt.Sprintf("transaction_type IN (%s)", ph[:len(ph)-1]))
+		for _, t := range types {
+			args = append(args, t)
+		}
+	}
+	for _, f := range []struct{ param, op string }{{"from", ">="}, {"to", "<"}} {
+		v := c.Query(f.param)
+		if v == "" {
+			continue
+		}
+		at, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return nil, nil, err
+		}
+		where = append(where, "created_at "+f.op+" ?")
+		args = append(args, at.UTC())
+	}
+	return where, args, nil
+}
+
+// listInventoryTransactions pages through the ledger newest first; pass
+// next_cursor back as cursor for the following page. With format=csv or
+// format=jsonl it instead streams every matching row, oldest first.
+func listInventoryTransactions(c *gin.Context) {
+	var req InventoryTransactionRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_request")})
+		return
+	}
+
+	where, args, err := transactionFilters(c, req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_request")})
+		return
+	}
+
+	switch format := c.Query("format"); format {
+	case "", "json":
+	case "csv", "jsonl":
+		// An export is unbounded, so it must at least stay within a tenant
+		// or a hub.
+		if req.TenantID == "" && req.HubID == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_request")})
+			return
+		}
+		exportInventoryTransactions(c, format, where, args)
+		return
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_request")})
+		return
+	}
+
+	limit, err := parsePageLimit(c.Query("limit"), defaultTransactionPageSize, maxTransactionPageSize)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_request")})
+		return
+	}
+	if v := c.Query("cursor"); v != "" {
+		createdAt, id, err := decodeCreatedAtCursor(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_request")})
+			return
+		}
+		where = append(where, "(created_at, id) < (?, ?)")
+		args = append(args, createdAt, id)
+	}
+	args = append(args, limit+1)
 
-	sql := `SELECT id,tenant_id,hub_id,sku_id,delta,transaction_type,reference_id,created_at
+	sql := `SELECT ` + transactionColumns + `
 	        FROM inventory_transactions
 	        WHERE ` + strings.Join(where, " AND ") + `
-	        ORDER BY created_at DESC`
+	        ORDER BY created_at DESC, id DESC
+	        LIMIT ?`
 
 	db := store.DB.GetSlaveDB(c.Request.Context())
 	rows, err := db.Raw(sql, args...).Rows()
@@ -104,5 +204,120 @@ func listInventoryTransactions(c *gin.Context) {
 		txs = append(txs, t)
 	}
 
-	c.JSON(http.StatusOK, gin.H{"transactions": txs})
+	resp := gin.H{"transactions": txs}
+	if len(txs) > limit {
+		txs = txs[:limit]
+		last := txs[limit-1]
+		resp["transactions"] = txs
+		resp["next_cursor"] = encodeCreatedAtCursor(last.CreatedAt, last.ID)
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// transactionCSVRecord renders a ledger row under transactionCSVHeader. Costs
+// and the unit quantity are left blank when the row has none.
+func transactionCSVRecord(t models.InventoryTransaction) []string {
+	cost := func(v *float64) string {
+		if v == nil {
+			return ""
+		}
+		return strconv.FormatFloat(*v, 'f', -1, 64)
+	}
+	uomQuantity := ""
+	if t.UOM != "" {
+		uomQuantity = strconv.FormatInt(t.UOMQuantity, 10)
+	}
+	return []string{
+		t.ID, t.TenantID, t.HubID, t.SKUID, strconv.FormatInt(t.Delta, 10), t.TransactionType,
+		t.ReferenceID, t.ReasonCode, t.LotNumber, t.UOM, uomQuantity,
+		cost(t.UnitCost), cost(t.TotalCost), t.CreatedAt.UTC().Format(time.RFC3339Nano),
+	}
+}
+
+// exportInventoryTransactions streams the matching ledger rows as CSV or JSON
+// lines, oldest first, straight from the database cursor. Once the first
+// bytes are out the status can no longer change, so a failure midway stops
+// the export and is reported in the trailers instead.
+func exportInventoryTransactions(c *gin.Context, format string, where []string, args []interface{}) {
+	sql := `SELECT ` + transactionColumns + `
+	        FROM inventory_transactions
+	        WHERE ` + strings.Join(where, " AND ") + `
+	        ORDER BY created_at, id`
+
+	db := store.DB.GetSlaveDB(c.Request.Context())
+	rows, err := db.Raw(sql, args...).Rows()
+	if err != nil {
+		invTxLogger.Errorf("exportInventoryTransactions DB error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.inventory_transaction_list_failed")})
+		return
+	}
+	defer rows.Close()
+
+	contentType := "application/x-ndjson"
+	if format == "csv" {
+		contentType = "text/csv"
+	}
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", `attachment; filename="inventory_transactions.`+format+`"`)
+	// The status is sent before the first row, so whether the export ran to
+	// the end is told in trailers: an export without X-Export-Complete: true
+	// is truncated.
+	c.Header("Trailer", "X-Export-Rows, X-Export-Complete")
+	c.Status(http.StatusOK)
+
+	csvw := csv.NewWriter(c.Writer)
+	enc := json.NewEncoder(c.Writer)
+	flush := func() error {
+		if format == "csv" {
+			csvw.Flush()
+			if err := csvw.Error(); err != nil {
+				return err
+			}
+		}
+		c.Writer.Flush()
+		return nil
+	}
+
+	if format == "csv" {
+		if err := csvw.Write(transactionCSVHeader); err != nil {
+			invTxLogger.Errorf("exportInventoryTransactions write error: %v", err)
+			return
+		}
+	}
+	n, complete := 0, false
+	defer func() {
+		c.Writer.Header().Set("X-Export-Rows", strconv.Itoa(n))
+		c.Writer.Header().Set("X-Export-Complete", strconv.FormatBool(complete))
+	}()
+	for rows.Next() {
+		var t models.InventoryTransaction
+		if err := db.ScanRows(rows, &t); err != nil {
+			invTxLogger.Errorf("exportInventoryTransactions scan error after %d rows: %v", n, err)
+			return
+		}
+		if format == "csv" {
+			err = csvw.Write(transactionCSVRecord(t))
+		} else {
+			err = enc.Encode(t)
+		}
+		if err != nil {
+			invTxLogger.Errorf("exportInventoryTransactions write error: %v", err)
+			return
+		}
+		if n++; n%exportFlushRows == 0 {
+			if err := flush(); err != nil {
+				invTxLogger.Errorf("exportInventoryTransactions write error: %v", err)
+				return
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		invTxLogger.Errorf("exportInventoryTransactions DB error after %d rows: %v", n, err)
+		return
+	}
+	if err := flush(); err != nil {
+		invTxLogger.Errorf("exportInventoryTransactions write error: %v", err)
+		return
+	}
+	complete = true
 }
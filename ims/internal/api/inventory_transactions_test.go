@@ -0,0 +1,46 @@
+package api
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/abhirup.dandapat/ims/internal/models"
+)
+
+func TestTransactionCSVRecord(t *testing.T) {
+	unitCost, totalCost := 2.5, -25.0
+	at := time.Date(2024, 3, 1, 10, 30, 0, 0, time.UTC)
+	cases := []struct {
+		name string
+		tx   models.InventoryTransaction
+		want []string
+	}{
+		{
+			name: "costed row with a unit",
+			tx: models.InventoryTransaction{
+				ID: "tx-1", TenantID: "t", HubID: "h", SKUID: "s", Delta: -10, TransactionType: "commit",
+				ReferenceID: "order-1", UOM: "case", UOMQuantity: 1, UnitCost: &unitCost, TotalCost: &totalCost, CreatedAt: at,
+			},
+			want: []string{"tx-1", "t", "h", "s", "-10", "commit", "order-1", "", "", "case", "1", "2.5", "-25", "2024-03-01T10:30:00Z"},
+		},
+		{
+			name: "reservation row",
+			tx: models.InventoryTransaction{
+				ID: "tx-2", TenantID: "t", HubID: "h", SKUID: "s", Delta: 3, TransactionType: "reserve", CreatedAt: at,
+			},
+			want: []string{"tx-2", "t", "h", "s", "3", "reserve", "", "", "", "", "", "", "", "2024-03-01T10:30:00Z"},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := transactionCSVRecord(tc.tx)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("transactionCSVRecord() = %v, want %v", got, tc.want)
+			}
+			if len(got) != len(transactionCSVHeader) {
+				t.Errorf("record has %d fields, header %d", len(got), len(transactionCSVHeader))
+			}
+		})
+	}
+}
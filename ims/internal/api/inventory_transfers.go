@@ -0,0 +1,477 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/omniful/go_commons/i18n"
+	"github.com/omniful/go_commons/log"
+	"gorm.io/gorm"
+
+	"github.com/abhirup.dandapat/ims/constants"
+	"github.com/abhirup.dandapat/ims/internal/models"
+	"github.com/abhirup.dandapat/ims/internal/store"
+)
+
+var transferLogger = log.DefaultLogger()
+
+type InventoryTransferItemRequest struct {
+	SKUID    string `json:"sku_id"   binding:"required"`
+	Quantity int64  `json:"quantity" binding:"required,gt=0"`
+}
+
+type InventoryTransferRequest struct {
+	TenantID         string                         `json:"tenant_id"          binding:"required"`
+	SourceHubID      string                         `json:"source_hub_id"      binding:"required"`
+	DestinationHubID string                         `json:"destination_hub_id" binding:"required"`
+	ReferenceID      string                         `json:"reference_id"`
+	Items            []InventoryTransferItemRequest `json:"items"              binding:"required,min=1,dive"`
+}
+
+type InventoryTransferReceiveRequest struct {
+	Items []InventoryTransferItemRequest `json:"items" binding:"required,min=1,dive"`
+}
+
+// uniqueTransferSKUs reports whether every item names a different SKU.
+func uniqueTransferSKUs(items []InventoryTransferItemRequest) bool {
+	seen := make(map[string]bool, len(items))
+	for _, it := range items {
+		if seen[it.SKUID] {
+			return false
+		}
+		seen[it.SKUID] = true
+	}
+	return true
+}
+
+// moveInTransit changes quantity_in_transit of a destination hub/SKU inside tx,
+// creating the row on the first inbound transfer.
+func moveInTransit(tx *gorm.DB, tenantID, hubID, skuID string, delta int64, now time.Time) error {
+	var inv models.Inventory
+	if err := tx.Raw(
+		`INSERT INTO inventory(hub_id,sku_id,quantity_in_transit,updated_at) VALUES(?,?,?,?)
+         ON CONFLICT (hub_id,sku_id) DO UPDATE SET quantity_in_transit = inventory.quantity_in_transit + EXCLUDED.quantity_in_transit,
+             version = inventory.version + 1, updated_at = EXCLUDED.updated_at
+         RETURNING hub_id,sku_id,quantity_on_hand,quantity_reserved,quantity_in_transit,min_threshold,max_threshold,safety_stock,version,updated_at`,
+		hubID, skuID, delta, now,
+	).Scan(&inv).Error; err != nil {
+		return err
+	}
+	return afterInventoryChange(tx, tenantID, inv, now)
+}
+
+// loadTransfer reads a transfer and its items, locking the header when
+// forUpdate is set so receipts and cancellation of one transfer serialize.
+func loadTransfer(db *gorm.DB, id string, forUpdate bool) (models.InventoryTransfer, bool, error) {
+	var t models.InventoryTransfer
+	sqlStr := `SELECT id,tenant_id,source_hub_id,destination_hub_id,reference_id,status,created_at,updated_at
+               FROM inventory_transfers WHERE id = ?`
+	if forUpdate {
+		sqlStr += ` FOR UPDATE`
+	}
+	res := db.Raw(sqlStr, id).Scan(&t)
+	if res.Error != nil || res.RowsAffected == 0 {
+		return t, false, res.Error
+	}
+	if err := db.Raw(
+		`SELECT sku_id,quantity,quantity_received,quantity_cancelled
+         FROM inventory_transfer_items WHERE transfer_id = ? ORDER BY sku_id`, id,
+	).Scan(&t.Items).Error; err != nil {
+		return t, true, err
+	}
+	return t, true, nil
+}
+
+// createInventoryTransfer dispatches stock from one hub to another of the same
+// tenant: the source loses the quantity at once and the destination tracks it
+// as in transit until it is received or the transfer is cancelled.
+func createInventoryTransfer(c *gin.Context) {
+	var req InventoryTransferRequest
+	if err := c.ShouldBindJSON(&req); err != nil ||
+		req.SourceHubID == req.DestinationHubID || !uniqueTransferSKUs(req.Items) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_request")})
+		return
+	}
+	now := time.Now().UTC()
+
+	tx := store.DB.GetMasterDB(c.Request.Context()).Begin()
+	if tx.Error != nil {
+		transferLogger.Errorf("createInventoryTransfer begin tx error: %v", tx.Error)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.inventory_transfer_failed")})
+		return
+	}
+	defer tx.Rollback()
+
+	var hubs int64
+	if err := tx.Raw(
+		`SELECT COUNT(*) FROM hubs WHERE tenant_id = ? AND id IN (?,?) AND deleted_at IS NULL`,
+		req.TenantID, req.SourceHubID, req.DestinationHubID,
+	).Scan(&hubs).Error; err != nil {
+		transferLogger.Errorf("createInventoryTransfer hub lookup error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.inventory_transfer_failed")})
+		return
+	}
+	if hubs != 2 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.hub_not_found")})
+		return
+	}
+	skuIDs := make([]string, len(req.Items))
+	for i, item := range req.Items {
+		skuIDs[i] = item.SKUID
+	}
+	var skus int64
+	if err := tx.Raw(
+		`SELECT COUNT(*) FROM skus WHERE tenant_id = ? AND id IN (?) AND deleted_at IS NULL`,
+		req.TenantID, skuIDs,
+	).Scan(&skus).Error; err != nil {
+		transferLogger.Errorf("createInventoryTransfer sku lookup error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.inventory_transfer_failed")})
+		return
+	}
+	if skus != int64(len(skuIDs)) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.sku_not_found")})
+		return
+	}
+	// Transfers do not track units in transit, so serialized SKUs move as
+	// shipments and receipts of their serial numbers instead.
+	err := refuseSerialized(tx, skuIDs...)
+	if errors.Is(err, errSerialsRequired) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.serial_numbers_required")})
+		return
+	}
+	if err != nil {
+		transferLogger.Errorf("createInventoryTransfer serial lookup error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.inventory_transfer_failed")})
+		return
+	}
+	if err := refuseKits(tx, skuIDs...); err != nil {
+		kitError(c, err, "error.inventory_transfer_failed")
+		return
+	}
+
+	t := models.InventoryTransfer{
+		ID:               uuid.New().String(),
+		TenantID:         req.TenantID,
+		SourceHubID:      req.SourceHubID,
+		DestinationHubID: req.DestinationHubID,
+		ReferenceID:      req.ReferenceID,
+		Status:           constants.TransferStatusInTransit,
+		CreatedAt:        now,
+		UpdatedAt:        now,
+	}
+	if err := tx.Exec(
+		`INSERT INTO inventory_transfers(id,tenant_id,source_hub_id,destination_hub_id,reference_id,status,created_at,updated_at)
+         VALUES(?,?,?,?,?,?,?,?)`,
+		t.ID, t.TenantID, t.SourceHubID, t.DestinationHubID, t.ReferenceID, t.Status, t.CreatedAt, t.UpdatedAt,
+	).Error; err != nil {
+		transferLogger.Errorf("createInventoryTransfer insert error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.inventory_transfer_failed")})
+		return
+	}
+
+	for _, it := range req.Items {
+		inv, err := applyInventoryDelta(tx, inventoryDelta{
+			TenantID:        req.TenantID,
+			HubID:           req.SourceHubID,
+			SKUID:           it.SKUID,
+			Delta:           -it.Quantity,
+			TransactionType: constants.TransactionTypeTransferOut,
+			ReferenceID:     t.ID,
+			KeepReserved:    true,
+		}, now)
+		if errors.Is(err, errNegativeInventory) {
+			c.JSON(http.StatusConflict, gin.H{"error": i18n.Translate(c, "error.insufficient_inventory"), "current": inv})
+			return
+		}
+		if errors.Is(err, errHubFrozen) {
+			c.JSON(http.StatusLocked, gin.H{"error": i18n.Translate(c, "error.hub_frozen")})
+			return
+		}
+		if errors.Is(err, errBinRequired) {
+			c.JSON(http.StatusConflict, gin.H{"error": i18n.Translate(c, "error.bin_required")})
+			return
+		}
+		if err == nil {
+			err = tx.Exec(
+				`INSERT INTO inventory_transfer_items(transfer_id,sku_id,quantity) VALUES(?,?,?)`,
+				t.ID, it.SKUID, it.Quantity,
+			).Error
+		}
+		if err == nil {
+			err = moveInTransit(tx, req.TenantID, req.DestinationHubID, it.SKUID, it.Quantity, now)
+		}
+		if err != nil {
+			transferLogger.Errorf("createInventoryTransfer item %s error: %v", it.SKUID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.inventory_transfer_failed")})
+			return
+		}
+		t.Items = append(t.Items, models.InventoryTransferItem{SKUID: it.SKUID, Quantity: it.Quantity})
+	}
+
+	if err := commitInventoryChange(c.Request.Context(), tx, req.TenantID); err != nil {
+		transferLogger.Errorf("createInventoryTransfer commit error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.inventory_transfer_failed")})
+		return
+	}
+
+	c.JSON(http.StatusCreated, t)
+}
+
+func getInventoryTransfer(c *gin.Context) {
+	t, found, err := loadTransfer(store.DB.GetSlaveDB(c.Request.Context()), c.Param("id"), false)
+	if err != nil {
+		transferLogger.Errorf("getInventoryTransfer DB error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.inventory_transfer_failed")})
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.Translate(c, "error.transfer_not_found")})
+		return
+	}
+	c.JSON(http.StatusOK, t)
+}
+
+// listInventoryTransfers returns transfer headers, newest first. hub_id
+// matches either end of a transfer.
+func listInventoryTransfers(c *gin.Context) {
+	where := []string{"1=1"}
+	args := []interface{}{}
+	for _, f := range []string{"tenant_id", "status"} {
+		if v := c.Query(f); v != "" {
+			where = append(where, f+" = ?")
+			args = append(args, v)
+		}
+	}
+	if v := c.Query("hub_id"); v != "" {
+		where = append(where, "(source_hub_id = ? OR destination_hub_id = ?)")
+		args = append(args, v, v)
+	}
+
+	sqlStr := `SELECT id,tenant_id,source_hub_id,destination_hub_id,reference_id,status,created_at,updated_at
+               FROM inventory_transfers WHERE ` + strings.Join(where, " AND ") + `
+               ORDER BY created_at DESC`
+
+	db := store.DB.GetSlaveDB(c.Request.Context())
+	rows, err := db.Raw(sqlStr, args...).Rows()
+	if err != nil {
+		transferLogger.Errorf("listInventoryTransfers DB error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.list_inventory_transfers_failed")})
+		return
+	}
+	defer rows.Close()
+
+	var transfers []models.InventoryTransfer
+	for rows.Next() {
+		var t models.InventoryTransfer
+		if err := db.ScanRows(rows, &t); err != nil {
+			transferLogger.Warnf("scan inventory_transfer row: %v", err)
+			continue
+		}
+		transfers = append(transfers, t)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"transfers": transfers})
+}
+
+// transferStatus derives the status of an open transfer from its items.
+func transferStatus(items []models.InventoryTransferItem) string {
+	outstanding, received := false, false
+	for _, it := range items {
+		outstanding = outstanding || it.Outstanding() > 0
+		received = received || it.QuantityReceived > 0
+	}
+	switch {
+	case !outstanding:
+		return constants.TransferStatusReceived
+	case received:
+		return constants.TransferStatusPartiallyReceived
+	default:
+		return constants.TransferStatusInTransit
+	}
+}
+
+func transferClosed(t models.InventoryTransfer) bool {
+	return t.Status == constants.TransferStatusReceived || t.Status == constants.TransferStatusCancelled
+}
+
+// receiveInventoryTransfer credits the destination with the received
+// quantities. Receipts may be partial; the transfer stays open until nothing
+// is left in transit.
+func receiveInventoryTransfer(c *gin.Context) {
+	var req InventoryTransferReceiveRequest
+	if err := c.ShouldBindJSON(&req); err != nil || !uniqueTransferSKUs(req.Items) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_request")})
+		return
+	}
+	now := time.Now().UTC()
+
+	tx := store.DB.GetMasterDB(c.Request.Context()).Begin()
+	if tx.Error != nil {
+		transferLogger.Errorf("receiveInventoryTransfer begin tx error: %v", tx.Error)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.inventory_transfer_failed")})
+		return
+	}
+	defer tx.Rollback()
+
+	t, ok := lockTransfer(c, tx, "receiveInventoryTransfer")
+	if !ok {
+		return
+	}
+	if transferClosed(t) {
+		c.JSON(http.StatusConflict, gin.H{"error": i18n.Translate(c, "error.transfer_closed"), "transfer": t})
+		return
+	}
+
+	idx := make(map[string]int, len(t.Items))
+	for i, it := range t.Items {
+		idx[it.SKUID] = i
+	}
+	for _, r := range req.Items {
+		i, found := idx[r.SKUID]
+		if !found {
+			c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_request"), "sku_id": r.SKUID})
+			return
+		}
+		if r.Quantity > t.Items[i].Outstanding() {
+			c.JSON(http.StatusConflict, gin.H{"error": i18n.Translate(c, "error.transfer_quantity_exceeded"), "sku_id": r.SKUID})
+			return
+		}
+
+		_, err := applyInventoryDelta(tx, inventoryDelta{
+			TenantID:        t.TenantID,
+			HubID:           t.DestinationHubID,
+			SKUID:           r.SKUID,
+			Delta:           r.Quantity,
+			TransactionType: constants.TransactionTypeTransferIn,
+			ReferenceID:     t.ID,
+		}, now)
+		if err == nil {
+			err = moveInTransit(tx, t.TenantID, t.DestinationHubID, r.SKUID, -r.Quantity, now)
+		}
+		if err == nil {
+			err = tx.Exec(
+				`UPDATE inventory_transfer_items SET quantity_received = quantity_received + ?
+                 WHERE transfer_id = ? AND sku_id = ?`,
+				r.Quantity, t.ID, r.SKUID,
+			).Error
+		}
+		if errors.Is(err, errHubFrozen) {
+			c.JSON(http.StatusLocked, gin.H{"error": i18n.Translate(c, "error.hub_frozen")})
+			return
+		}
+		if err != nil {
+			transferLogger.Errorf("receiveInventoryTransfer item %s error: %v", r.SKUID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.inventory_transfer_failed")})
+			return
+		}
+		t.Items[i].QuantityReceived += r.Quantity
+	}
+
+	finishTransferUpdate(c, tx, t, transferStatus(t.Items), now, "receiveInventoryTransfer")
+}
+
+// cancelInventoryTransfer returns whatever is still in transit to the source
+// hub. Quantities already received stay at the destination. Cancelling a
+// cancelled transfer is a no-op.
+func cancelInventoryTransfer(c *gin.Context) {
+	now := time.Now().UTC()
+
+	tx := store.DB.GetMasterDB(c.Request.Context()).Begin()
+	if tx.Error != nil {
+		transferLogger.Errorf("cancelInventoryTransfer begin tx error: %v", tx.Error)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.inventory_transfer_failed")})
+		return
+	}
+	defer tx.Rollback()
+
+	t, ok := lockTransfer(c, tx, "cancelInventoryTransfer")
+	if !ok {
+		return
+	}
+	if t.Status == constants.TransferStatusCancelled {
+		c.JSON(http.StatusOK, t)
+		return
+	}
+	if transferClosed(t) {
+		c.JSON(http.StatusConflict, gin.H{"error": i18n.Translate(c, "error.transfer_closed"), "transfer": t})
+		return
+	}
+
+	for i, it := range t.Items {
+		left := it.Outstanding()
+		if left == 0 {
+			continue
+		}
+
+		_, err := applyInventoryDelta(tx, inventoryDelta{
+			TenantID:        t.TenantID,
+			HubID:           t.SourceHubID,
+			SKUID:           it.SKUID,
+			Delta:           left,
+			TransactionType: constants.TransactionTypeTransferCancel,
+			ReferenceID:     t.ID,
+		}, now)
+		if err == nil {
+			err = moveInTransit(tx, t.TenantID, t.DestinationHubID, it.SKUID, -left, now)
+		}
+		if err == nil {
+			err = tx.Exec(
+				`UPDATE inventory_transfer_items SET quantity_cancelled = quantity_cancelled + ?
+                 WHERE transfer_id = ? AND sku_id = ?`,
+				left, t.ID, it.SKUID,
+			).Error
+		}
+		if errors.Is(err, errHubFrozen) {
+			c.JSON(http.StatusLocked, gin.H{"error": i18n.Translate(c, "error.hub_frozen")})
+			return
+		}
+		if err != nil {
+			transferLogger.Errorf("cancelInventoryTransfer item %s error: %v", it.SKUID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.inventory_transfer_failed")})
+			return
+		}
+		t.Items[i].QuantityCancelled += left
+	}
+
+	finishTransferUpdate(c, tx, t, constants.TransferStatusCancelled, now, "cancelInventoryTransfer")
+}
+
+// lockTransfer loads and locks the transfer named in the path, writing the
+// error response and returning false when it cannot.
+func lockTransfer(c *gin.Context, tx *gorm.DB, op string) (models.InventoryTransfer, bool) {
+	t, found, err := loadTransfer(tx, c.Param("id"), true)
+	if err != nil {
+		transferLogger.Errorf("%s load error: %v", op, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.inventory_transfer_failed")})
+		return t, false
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.Translate(c, "error.transfer_not_found")})
+		return t, false
+	}
+	return t, true
+}
+
+func finishTransferUpdate(c *gin.Context, tx *gorm.DB, t models.InventoryTransfer, status string, now time.Time, op string) {
+	t.Status, t.UpdatedAt = status, now
+	if err := tx.Exec(
+		`UPDATE inventory_transfers SET status = ?, updated_at = ? WHERE id = ?`,
+		t.Status, t.UpdatedAt, t.ID,
+	).Error; err != nil {
+		transferLogger.Errorf("%s status update error: %v", op, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.inventory_transfer_failed")})
+		return
+	}
+
+	if err := commitInventoryChange(c.Request.Context(), tx, t.TenantID); err != nil {
+		transferLogger.Errorf("%s commit error: %v", op, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.inventory_transfer_failed")})
+		return
+	}
+
+	c.JSON(http.StatusOK, t)
+}
@@ -0,0 +1,57 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/abhirup.dandapat/ims/constants"
+	"github.com/abhirup.dandapat/ims/internal/models"
+)
+
+func TestTransferStatus(t *testing.T) {
+	cases := []struct {
+		name  string
+		items []models.InventoryTransferItem
+		want  string
+	}{
+		{
+			name:  "nothing received",
+			items: []models.InventoryTransferItem{{SKUID: "a", Quantity: 5}, {SKUID: "b", Quantity: 2}},
+			want:  constants.TransferStatusInTransit,
+		},
+		{
+			name: "part of one item received",
+			items: []models.InventoryTransferItem{
+				{SKUID: "a", Quantity: 5, QuantityReceived: 3},
+				{SKUID: "b", Quantity: 2},
+			},
+			want: constants.TransferStatusPartiallyReceived,
+		},
+		{
+			name: "rest of an item cancelled",
+			items: []models.InventoryTransferItem{
+				{SKUID: "a", Quantity: 5, QuantityReceived: 3, QuantityCancelled: 2},
+				{SKUID: "b", Quantity: 2, QuantityReceived: 2},
+			},
+			want: constants.TransferStatusReceived,
+		},
+		{
+			name:  "everything cancelled",
+			items: []models.InventoryTransferItem{{SKUID: "a", Quantity: 5, QuantityCancelled: 5}},
+			want:  constants.TransferStatusReceived,
+		},
+	}
+	for _, tc := range cases {
+		if got := transferStatus(tc.items); got != tc.want {
+			t.Errorf("%s: transferStatus = %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestUniqueTransferSKUs(t *testing.T) {
+	if !uniqueTransferSKUs([]InventoryTransferItemRequest{{SKUID: "a"}, {SKUID: "b"}}) {
+		t.Error("distinct SKUs reported as duplicates")
+	}
+	if uniqueTransferSKUs([]InventoryTransferItemRequest{{SKUID: "a"}, {SKUID: "b"}, {SKUID: "a"}}) {
+		t.Error("duplicate SKU not reported")
+	}
+}
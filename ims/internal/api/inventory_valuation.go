@@ -0,0 +1,140 @@
+package api
+
+import (
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/omniful/go_commons/i18n"
+	"github.com/omniful/go_commons/log"
+
+	"github.com/abhirup.dandapat/ims/internal/ledger"
+	"github.com/abhirup.dandapat/ims/internal/models"
+	"github.com/abhirup.dandapat/ims/internal/store"
+)
+
+var valuationLogger = log.DefaultLogger()
+
+// valuationTotals adds up the rows of a valuation report.
+func valuationTotals(rows []models.InventoryValuation) models.InventoryValuation {
+	var t models.InventoryValuation
+	for _, r := range rows {
+		t.OpeningQuantity += r.OpeningQuantity
+		t.OpeningValue += r.OpeningValue
+		t.InboundQuantity += r.InboundQuantity
+		t.InboundValue += r.InboundValue
+		t.COGSQuantity += r.COGSQuantity
+		t.COGS += r.COGS
+		t.OtherOutboundQuantity += r.OtherOutboundQuantity
+		t.OtherOutboundValue += r.OtherOutboundValue
+		t.ClosingQuantity += r.ClosingQuantity
+		t.ClosingValue += r.ClosingValue
+	}
+	for _, v := range []*float64{&t.OpeningValue, &t.InboundValue, &t.COGS, &t.OtherOutboundValue, &t.ClosingValue} {
+		*v = math.Round(*v*1e4) / 1e4
+	}
+	return t
+}
+
+// getInventoryValuation reports a tenant's stock value per hub/SKU over
+// [from, to): opening and closing quantity and value, inbound receipts, COGS
+// from commits and other outbound movements. to defaults to now.
+func getInventoryValuation(c *gin.Context) {
+	tenantID := c.Query("tenant_id")
+	from, err := time.Parse(time.RFC3339, c.Query("from"))
+	if tenantID == "" || err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_request")})
+		return
+	}
+	to := time.Now().UTC()
+	if v := c.Query("to"); v != "" {
+		if to, err = time.Parse(time.RFC3339, v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_request")})
+			return
+		}
+	}
+	from, to = from.UTC(), to.UTC()
+	if !from.Before(to) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_request")})
+		return
+	}
+
+	opts := ledger.ValuationOptions{TenantID: tenantID, HubID: c.Query("hub_id"), From: from, To: to}
+	if v := c.Query("sku_ids"); v != "" {
+		opts.SKUIDs = strings.Split(v, ",")
+	}
+
+	db := store.DB.GetSlaveDB(c.Request.Context())
+	var method string
+	if err := db.Raw(`SELECT costing_method FROM tenants WHERE id = ?`, tenantID).Scan(&method).Error; err != nil {
+		valuationLogger.Errorf("getInventoryValuation tenant error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.inventory_valuation_failed")})
+		return
+	}
+	if method == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.Translate(c, "error.tenant_not_found")})
+		return
+	}
+
+	rows, err := ledger.Valuation(db, opts)
+	if err != nil {
+		valuationLogger.Errorf("getInventoryValuation DB error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.inventory_valuation_failed")})
+		return
+	}
+	if rows == nil {
+		rows = []models.InventoryValuation{}
+	}
+
+	c.JSON(http.StatusOK, models.InventoryValuationReport{
+		TenantID:      tenantID,
+		CostingMethod: method,
+		From:          from,
+		To:            to,
+		Rows:          rows,
+		Totals:        valuationTotals(rows),
+	})
+}
+
+// listInventoryCostLayers lists the open cost layers at a hub, oldest first,
+// which is the order outbound stock is drawn from them.
+func listInventoryCostLayers(c *gin.Context) {
+	hubID := c.Query("hub_id")
+	if hubID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_request")})
+		return
+	}
+
+	where := []string{"hub_id = ?", "quantity_remaining > 0"}
+	args := []interface{}{hubID}
+	if v := c.Query("sku_id"); v != "" {
+		where = append(where, "sku_id = ?")
+		args = append(args, v)
+	}
+
+	sqlStr := `SELECT id,tenant_id,hub_id,sku_id,transaction_id,unit_cost,quantity_received,quantity_remaining,created_at,updated_at
+               FROM inventory_cost_layers WHERE ` + strings.Join(where, " AND ") + ` ORDER BY sku_id, created_at, id`
+
+	db := store.DB.GetSlaveDB(c.Request.Context())
+	rows, err := db.Raw(sqlStr, args...).Rows()
+	if err != nil {
+		valuationLogger.Errorf("listInventoryCostLayers DB error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.inventory_valuation_failed")})
+		return
+	}
+	defer rows.Close()
+
+	var layers []models.InventoryCostLayer
+	for rows.Next() {
+		var l models.InventoryCostLayer
+		if err := db.ScanRows(rows, &l); err != nil {
+			valuationLogger.Warnf("scan inventory_cost_layer row: %v", err)
+			continue
+		}
+		layers = append(layers, l)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"layers": layers})
+}
@@ -0,0 +1,718 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/omniful/go_commons/i18n"
+	"github.com/omniful/go_commons/log"
+	"gorm.io/gorm"
+
+	"github.com/abhirup.dandapat/ims/constants"
+	"github.com/abhirup.dandapat/ims/internal/models"
+	"github.com/abhirup.dandapat/ims/internal/store"
+)
+
+var productLogger = log.DefaultLogger()
+
+var (
+	// errInvalidAttributes is returned for attribute definitions with no
+	// attributes, a repeated or empty name, an unknown type, or enum values
+	// that are missing or repeated.
+	errInvalidAttributes = errors.New("invalid product attributes")
+	// errInvalidVariant is returned for variant values that do not match the
+	// product's attributes one to one or do not fit an attribute's type.
+	errInvalidVariant = errors.New("invalid variant attributes")
+	// errMatrixTooLarge is returned for a variant matrix with more than
+	// constants.MaxVariantMatrix combinations.
+	errMatrixTooLarge = errors.New("variant matrix too large")
+	// errInvalidProductRef is returned for a seller or category that is
+	// missing, deleted or of another tenant.
+	errInvalidProductRef = errors.New("invalid product reference")
+)
+
+const productColumns = `id,tenant_id,seller_id,category_id,name,description,created_at,updated_at,deleted_at`
+
+const skuColumns = `id,tenant_id,seller_id,code,name,description,category_id,weight,weight_unit,length,width,height,is_serialized,base_uom,product_id,variant_attributes,created_at,updated_at`
+
+type ProductAttributeRequest struct {
+	Name   string   `json:"name"   binding:"required"`
+	Type   string   `json:"type"   binding:"required"`
+	Values []string `json:"values"`
+}
+
+// ProductCreateRequest defines a product and its variant attributes, in the
+// order variant names and codes list their values. The attributes are fixed
+// once the product exists.
+type ProductCreateRequest struct {
+	TenantID    string                    `json:"tenant_id"   binding:"required"`
+	SellerID    string                    `json:"seller_id"   binding:"required"`
+	CategoryID  *string                   `json:"category_id"`
+	Name        string                    `json:"name"        binding:"required"`
+	Description string                    `json:"description"`
+	Attributes  []ProductAttributeRequest `json:"attributes"  binding:"required,dive"`
+}
+
+// ProductUpdateRequest renames a product; description and category keep
+// their stored value when left out. A new category is passed on to the
+// product's variants.
+type ProductUpdateRequest struct {
+	Name        string  `json:"name"        binding:"required"`
+	Description *string `json:"description"`
+	CategoryID  *string `json:"category_id"`
+}
+
+// VariantSKUFields are the SKU fields a variant does not inherit from its
+// product.
+type VariantSKUFields struct {
+	Description  string  `json:"description"`
+	Weight       float64 `json:"weight"`
+	WeightUnit   string  `json:"weight_unit"`
+	Length       float64 `json:"length"`
+	Width        float64 `json:"width"`
+	Height       float64 `json:"height"`
+	IsSerialized bool    `json:"is_serialized"`
+	BaseUOM      string  `json:"base_uom"`
+}
+
+// ProductVariantRequest creates one variant SKU. Name defaults to the
+// product name followed by the attribute values.
+type ProductVariantRequest struct {
+	Code       string            `json:"code"       binding:"required"`
+	Name       string            `json:"name"`
+	Attributes map[string]string `json:"attributes" binding:"required"`
+	VariantSKUFields
+}
+
+// ProductVariantMatrixRequest creates a variant for every combination of the
+// listed values, one list per product attribute. Each variant's code is
+// code_prefix followed by its values.
+type ProductVariantMatrixRequest struct {
+	CodePrefix string              `json:"code_prefix" binding:"required"`
+	Attributes map[string][]string `json:"attributes"  binding:"required"`
+	VariantSKUFields
+}
+
+// checkProductAttributes validates attribute definitions and numbers them in
+// request order.
+func checkProductAttributes(reqs []ProductAttributeRequest) ([]models.ProductAttribute, error) {
+	if len(reqs) == 0 {
+		return nil, errInvalidAttributes
+	}
+	attrs := make([]models.ProductAttribute, 0, len(reqs))
+	seen := make(map[string]bool, len(reqs))
+	for i, r := range reqs {
+		name := strings.TrimSpace(r.Name)
+		if name == "" || seen[name] {
+			return nil, errInvalidAttributes
+		}
+		seen[name] = true
+
+		switch r.Type {
+		case constants.AttributeTypeString, constants.AttributeTypeNumber:
+			if len(r.Values) > 0 {
+				return nil, errInvalidAttributes
+			}
+		case constants.AttributeTypeEnum:
+			if len(r.Values) == 0 {
+				return nil, errInvalidAttributes
+			}
+			values := make(map[string]bool, len(r.Values))
+			for _, v := range r.Values {
+				if v == "" || values[v] {
+					return nil, errInvalidAttributes
+				}
+				values[v] = true
+			}
+		default:
+			return nil, errInvalidAttributes
+		}
+		attrs = append(attrs, models.ProductAttribute{Name: name, Type: r.Type, Values: r.Values, Position: i})
+	}
+	return attrs, nil
+}
+
+// checkVariantValue reports whether v is a valid value of attribute a.
+func checkVariantValue(a models.ProductAttribute, v string) bool {
+	switch a.Type {
+	case constants.AttributeTypeNumber:
+		_, err := strconv.ParseFloat(v, 64)
+		return err == nil
+	case constants.AttributeTypeEnum:
+		for _, allowed := range a.Values {
+			if v == allowed {
+				return true
+			}
+		}
+		return false
+	}
+	return strings.TrimSpace(v) != ""
+}
+
+// checkVariantAttributes validates a variant's values against the product's
+// attributes: one value per attribute and no others.
+func checkVariantAttributes(defs []models.ProductAttribute, values map[string]string) error {
+	if len(values) != len(defs) {
+		return errInvalidVariant
+	}
+	for _, a := range defs {
+		v, ok := values[a.Name]
+		if !ok || !checkVariantValue(a, v) {
+			return errInvalidVariant
+		}
+	}
+	return nil
+}
+
+// expandVariantMatrix returns every combination of the values in axes, the
+// last attribute varying fastest. axes needs a non-empty list of distinct,
+// valid values for each attribute and no other keys.
+func expandVariantMatrix(defs []models.ProductAttribute, axes map[string][]string) ([]models.VariantAttributes, error) {
+	if len(axes) != len(defs) {
+		return nil, errInvalidVariant
+	}
+	total := 1
+	for _, a := range defs {
+		values, ok := axes[a.Name]
+		if !ok || len(values) == 0 {
+			return nil, errInvalidVariant
+		}
+		seen := make(map[string]bool, len(values))
+		for _, v := range values {
+			if seen[v] || !checkVariantValue(a, v) {
+				return nil, errInvalidVariant
+			}
+			seen[v] = true
+		}
+		if total *= len(values); total > constants.MaxVariantMatrix {
+			return nil, errMatrixTooLarge
+		}
+	}
+
+	combos := []models.VariantAttributes{{}}
+	for _, a := range defs {
+		next := make([]models.VariantAttributes, 0, len(combos)*len(axes[a.Name]))
+		for _, combo := range combos {
+			for _, v := range axes[a.Name] {
+				attrs := make(models.VariantAttributes, len(combo)+1)
+				for k, cv := range combo {
+					attrs[k] = cv
+				}
+				attrs[a.Name] = v
+				next = append(next, attrs)
+			}
+		}
+		combos = next
+	}
+	return combos, nil
+}
+
+// variantValues lists a variant's values in attribute order.
+func variantValues(defs []models.ProductAttribute, attrs models.VariantAttributes) []string {
+	values := make([]string, len(defs))
+	for i, a := range defs {
+		values[i] = attrs[a.Name]
+	}
+	return values
+}
+
+// variantName is the default name of a variant, e.g. "Tee - M / Red".
+func variantName(productName string, defs []models.ProductAttribute, attrs models.VariantAttributes) string {
+	return productName + " - " + strings.Join(variantValues(defs, attrs), " / ")
+}
+
+// variantCode is the code a matrix gives a variant, e.g. "TEE-M-Red". Spaces
+// in values become dashes.
+func variantCode(prefix string, defs []models.ProductAttribute, attrs models.VariantAttributes) string {
+	parts := append([]string{prefix}, variantValues(defs, attrs)...)
+	return strings.Join(strings.Fields(strings.Join(parts, " ")), "-")
+}
+
+// loadProduct reads a live product with its attributes.
+func loadProduct(db *gorm.DB, id string) (models.Product, error) {
+	var p models.Product
+	res := db.Raw(`SELECT `+productColumns+` FROM products WHERE id = ? AND deleted_at IS NULL`, id).Scan(&p)
+	if res.Error != nil {
+		return p, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return p, gorm.ErrRecordNotFound
+	}
+	err := db.Raw(
+		`SELECT name,attribute_type,allowed_values,position FROM product_attributes
+         WHERE product_id = ? ORDER BY position`, id,
+	).Scan(&p.Attributes).Error
+	return p, err
+}
+
+// insertVariant adds a variant SKU of p. It reports false, without an error,
+// when the code or the combination of values is already taken.
+func insertVariant(db *gorm.DB, p models.Product, code, name string, attrs models.VariantAttributes, f VariantSKUFields, now time.Time) (models.SKU, bool, error) {
+	s := models.SKU{
+		ID: uuid.New().String(), TenantID: p.TenantID, SellerID: p.SellerID, Code: code, Name: name,
+		Description: f.Description, Weight: f.Weight, WeightUnit: f.WeightUnit,
+		Length: f.Length, Width: f.Width, Height: f.Height, IsSerialized: f.IsSerialized, BaseUOM: f.BaseUOM,
+		ProductID: &p.ID, VariantAttributes: attrs, CreatedAt: now, UpdatedAt: now,
+	}
+	if s.BaseUOM == "" {
+		s.BaseUOM = constants.DefaultBaseUOM
+	}
+	if p.CategoryID != nil {
+		s.CategoryID = *p.CategoryID
+	}
+
+	res := db.Exec(
+		`INSERT INTO skus(`+skuColumns+`)
+         VALUES(?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?)
+         ON CONFLICT DO NOTHING`,
+		s.ID, s.TenantID, s.SellerID, s.Code, s.Name, s.Description,
+		p.CategoryID, s.Weight, s.WeightUnit, s.Length, s.Width, s.Height,
+		s.IsSerialized, s.BaseUOM, s.ProductID, s.VariantAttributes, s.CreatedAt, s.UpdatedAt,
+	)
+	if res.Error != nil {
+		return s, false, res.Error
+	}
+	return s, res.RowsAffected > 0, nil
+}
+
+func createProduct(c *gin.Context) {
+	var req ProductCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_request")})
+		return
+	}
+	attrs, err := checkProductAttributes(req.Attributes)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_product_attributes")})
+		return
+	}
+	if req.CategoryID != nil && *req.CategoryID == "" {
+		req.CategoryID = nil
+	}
+	now := time.Now().UTC()
+	p := models.Product{
+		ID: uuid.New().String(), TenantID: req.TenantID, SellerID: req.SellerID, CategoryID: req.CategoryID,
+		Name: req.Name, Description: req.Description, Attributes: attrs, CreatedAt: now, UpdatedAt: now,
+	}
+
+	tx := store.DB.GetMasterDB(c.Request.Context()).Begin()
+	if tx.Error != nil {
+		productLogger.Errorf("createProduct begin tx error: %v", tx.Error)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.create_product_failed")})
+		return
+	}
+	defer tx.Rollback()
+
+	err = insertProduct(tx, p)
+	switch {
+	case errors.Is(err, errInvalidProductRef):
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_product_reference")})
+		return
+	case err != nil:
+		productLogger.Errorf("createProduct DB error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.create_product_failed")})
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		productLogger.Errorf("createProduct commit error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.create_product_failed")})
+		return
+	}
+	c.JSON(http.StatusCreated, p)
+}
+
+// insertProduct adds p and its attributes. The seller, and the category if
+// any, must be live rows of p's tenant.
+func insertProduct(tx *gorm.DB, p models.Product) error {
+	res := tx.Exec(
+		`INSERT INTO products(id,tenant_id,seller_id,category_id,name,description,created_at,updated_at)
+         SELECT ?,?,?,?,?,?,?,?
+         WHERE EXISTS(SELECT 1 FROM sellers WHERE id = ? AND tenant_id = ? AND deleted_at IS NULL FOR SHARE)
+           AND (CAST(? AS UUID) IS NULL
+                OR EXISTS(SELECT 1 FROM categories WHERE id = ? AND tenant_id = ? AND deleted_at IS NULL FOR SHARE))`,
+		p.ID, p.TenantID, p.SellerID, p.CategoryID, p.Name, p.Description, p.CreatedAt, p.UpdatedAt,
+		p.SellerID, p.TenantID, p.CategoryID, p.CategoryID, p.TenantID,
+	)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return errInvalidProductRef
+	}
+
+	for _, a := range p.Attributes {
+		if err := tx.Exec(
+			`INSERT INTO product_attributes(product_id,name,attribute_type,allowed_values,position)
+             VALUES(?,?,?,?,?)`,
+			p.ID, a.Name, a.Type, a.Values, a.Position,
+		).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func getProduct(c *gin.Context) {
+	p, err := loadProduct(store.DB.GetSlaveDB(c.Request.Context()), c.Param("id"))
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.Translate(c, "error.product_not_found")})
+		return
+	case err != nil:
+		productLogger.Errorf("getProduct DB error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.get_product_failed")})
+		return
+	}
+	c.JSON(http.StatusOK, p)
+}
+
+func listProducts(c *gin.Context) {
+	where := []string{"1=1"}
+	args := []interface{}{}
+	for _, param := range []string{"tenant_id", "seller_id", "category_id"} {
+		if v := c.Query(param); v != "" {
+			where = append(where, param+" = ?")
+			args = append(args, v)
+		}
+	}
+	where, err := liveFilter(c, where)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_request")})
+		return
+	}
+
+	sqlStr := fmt.Sprintf(
+		`SELECT `+productColumns+` FROM products WHERE %s ORDER BY name, id`, strings.Join(where, " AND "),
+	)
+
+	db := store.DB.GetSlaveDB(c.Request.Context())
+	rows, err := db.Raw(sqlStr, args...).Rows()
+	if err != nil {
+		productLogger.Errorf("listProducts DB error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.list_products_failed")})
+		return
+	}
+	defer rows.Close()
+
+	products := []models.Product{}
+	idx := map[string]int{}
+	for rows.Next() {
+		var p models.Product
+		if err := db.ScanRows(rows, &p); err != nil {
+			productLogger.Errorf("listProducts scan error: %v", err)
+			continue
+		}
+		p.Attributes = []models.ProductAttribute{}
+		idx[p.ID] = len(products)
+		products = append(products, p)
+	}
+	if len(products) == 0 {
+		c.JSON(http.StatusOK, products)
+		return
+	}
+
+	ph := strings.Repeat("?,", len(products))
+	ids := make([]interface{}, len(products))
+	for i, p := range products {
+		ids[i] = p.ID
+	}
+	var attrs []struct {
+		ProductID string `gorm:"column:product_id"`
+		models.ProductAttribute
+	}
+	if err := db.Raw(
+		`SELECT product_id,name,attribute_type,allowed_values,position FROM product_attributes
+         WHERE product_id IN (`+ph[:len(ph)-1]+`) ORDER BY product_id, position`, ids...,
+	).Scan(&attrs).Error; err != nil {
+		productLogger.Errorf("listProducts attributes DB error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.list_products_failed")})
+		return
+	}
+	for _, a := range attrs {
+		p := &products[idx[a.ProductID]]
+		p.Attributes = append(p.Attributes, a.ProductAttribute)
+	}
+	c.JSON(http.StatusOK, products)
+}
+
+func updateProduct(c *gin.Context) {
+	var req ProductUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_request")})
+		return
+	}
+	id := c.Param("id")
+	now := time.Now().UTC()
+
+	tx := store.DB.GetMasterDB(c.Request.Context()).Begin()
+	if tx.Error != nil {
+		productLogger.Errorf("updateProduct begin tx error: %v", tx.Error)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.update_product_failed")})
+		return
+	}
+	defer tx.Rollback()
+
+	p, skuIDs, err := applyProductUpdate(tx, id, req, now)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.Translate(c, "error.product_not_found")})
+		return
+	case errors.Is(err, errInvalidProductRef):
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_product_reference")})
+		return
+	case err != nil:
+		productLogger.Errorf("updateProduct error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.update_product_failed")})
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		productLogger.Errorf("updateProduct commit error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.update_product_failed")})
+		return
+	}
+	db := store.DB.GetMasterDB(c.Request.Context())
+	for _, skuID := range skuIDs {
+		_, _ = store.RedisClient.Del(c.Request.Context(), "sku:"+skuID)
+		forgetSKUBarcodes(c.Request.Context(), db, skuID)
+	}
+	c.JSON(http.StatusOK, p)
+}
+
+// applyProductUpdate updates a live product and, when its category changes,
+// the category of its live variants, whose ids it returns.
+func applyProductUpdate(tx *gorm.DB, id string, req ProductUpdateRequest, now time.Time) (models.Product, []string, error) {
+	if req.CategoryID != nil && *req.CategoryID == "" {
+		req.CategoryID = nil
+	}
+	p, err := loadProduct(tx, id)
+	if err != nil {
+		return p, nil, err
+	}
+	if req.CategoryID != nil {
+		var ok bool
+		if err := tx.Raw(
+			`SELECT EXISTS(SELECT 1 FROM categories WHERE id = ? AND tenant_id = ? AND deleted_at IS NULL)`,
+			*req.CategoryID, p.TenantID,
+		).Scan(&ok).Error; err != nil {
+			return p, nil, err
+		}
+		if !ok {
+			return p, nil, errInvalidProductRef
+		}
+	}
+
+	attrs := p.Attributes
+	res := tx.Raw(
+		`UPDATE products SET name=?,description=COALESCE(?,description),category_id=COALESCE(CAST(? AS UUID),category_id),updated_at=?
+         WHERE id=? AND deleted_at IS NULL
+         RETURNING `+productColumns,
+		req.Name, req.Description, req.CategoryID, now, id,
+	).Scan(&p)
+	if res.Error != nil {
+		return p, nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return p, nil, gorm.ErrRecordNotFound
+	}
+	p.Attributes = attrs
+
+	var skuIDs []string
+	if req.CategoryID != nil {
+		if err := tx.Raw(
+			`UPDATE skus SET category_id=?,updated_at=?
+             WHERE product_id=? AND deleted_at IS NULL AND category_id IS DISTINCT FROM CAST(? AS UUID)
+             RETURNING id`,
+			*req.CategoryID, now, id, *req.CategoryID,
+		).Scan(&skuIDs).Error; err != nil {
+			return p, nil, err
+		}
+	}
+	return p, skuIDs, nil
+}
+
+// deleteProduct soft deletes a product once its variants have been deleted.
+func deleteProduct(c *gin.Context) {
+	db := store.DB.GetMasterDB(c.Request.Context())
+	err := softDelete(db, "products", c.Param("id"), []string{"skus.product_id"}, time.Now().UTC())
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.Translate(c, "error.product_not_found")})
+		return
+	case errors.Is(err, errInUse):
+		c.JSON(http.StatusConflict, gin.H{"error": i18n.Translate(c, "error.product_in_use")})
+		return
+	case err != nil:
+		productLogger.Errorf("deleteProduct DB error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.delete_product_failed")})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// createProductVariant adds one variant SKU to a product. The SKU takes the
+// product's tenant, seller and category.
+func createProductVariant(c *gin.Context) {
+	var req ProductVariantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_request")})
+		return
+	}
+
+	db := store.DB.GetMasterDB(c.Request.Context())
+	p, err := loadProduct(db, c.Param("id"))
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.Translate(c, "error.product_not_found")})
+		return
+	case err != nil:
+		productLogger.Errorf("createProductVariant DB error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.create_variant_failed")})
+		return
+	}
+	if err := checkVariantAttributes(p.Attributes, req.Attributes); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_variant_attributes")})
+		return
+	}
+
+	attrs := models.VariantAttributes(req.Attributes)
+	name := req.Name
+	if name == "" {
+		name = variantName(p.Name, p.Attributes, attrs)
+	}
+	now := time.Now().UTC()
+	s, created, err := insertVariant(db, p, req.Code, name, attrs, req.VariantSKUFields, now)
+	if err != nil {
+		productLogger.Errorf("createProductVariant DB error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.create_variant_failed")})
+		return
+	}
+	if !created {
+		c.JSON(http.StatusConflict, gin.H{"error": i18n.Translate(c, "error.variant_conflict")})
+		return
+	}
+
+	invalidateInventoryCache(c.Request.Context(), p.TenantID, now)
+	c.JSON(http.StatusCreated, s)
+}
+
+// createProductVariantMatrix adds a variant SKU for every combination of the
+// given values in one transaction. Combinations the product already has, and
+// codes already in use, are skipped and listed in the response.
+func createProductVariantMatrix(c *gin.Context) {
+	var req ProductVariantMatrixRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_request")})
+		return
+	}
+
+	tx := store.DB.GetMasterDB(c.Request.Context()).Begin()
+	if tx.Error != nil {
+		productLogger.Errorf("createProductVariantMatrix begin tx error: %v", tx.Error)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.create_variant_failed")})
+		return
+	}
+	defer tx.Rollback()
+
+	p, err := loadProduct(tx, c.Param("id"))
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.Translate(c, "error.product_not_found")})
+		return
+	case err != nil:
+		productLogger.Errorf("createProductVariantMatrix DB error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.create_variant_failed")})
+		return
+	}
+	combos, err := expandVariantMatrix(p.Attributes, req.Attributes)
+	switch {
+	case errors.Is(err, errMatrixTooLarge):
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.variant_matrix_too_large")})
+		return
+	case err != nil:
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_variant_attributes")})
+		return
+	}
+
+	now := time.Now().UTC()
+	created := []models.SKU{}
+	skipped := []gin.H{}
+	for _, attrs := range combos {
+		code := variantCode(req.CodePrefix, p.Attributes, attrs)
+		s, ok, err := insertVariant(tx, p, code, variantName(p.Name, p.Attributes, attrs), attrs, req.VariantSKUFields, now)
+		if err != nil {
+			productLogger.Errorf("createProductVariantMatrix DB error: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.create_variant_failed")})
+			return
+		}
+		if !ok {
+			skipped = append(skipped, gin.H{"code": code, "attributes": attrs})
+			continue
+		}
+		created = append(created, s)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		productLogger.Errorf("createProductVariantMatrix commit error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.create_variant_failed")})
+		return
+	}
+	if len(created) > 0 {
+		invalidateInventoryCache(c.Request.Context(), p.TenantID, now)
+	}
+	c.JSON(http.StatusCreated, gin.H{"product_id": p.ID, "created": created, "skipped": skipped})
+}
+
+// listProductVariants returns a product's live variants with their stock
+// summed over the tenant's live hubs, or at one hub with hub_id. A hub's
+// available stock is on hand less reserved, floored at zero.
+func listProductVariants(c *gin.Context) {
+	db := store.DB.GetSlaveDB(c.Request.Context())
+	p, err := loadProduct(db, c.Param("id"))
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.Translate(c, "error.product_not_found")})
+		return
+	case err != nil:
+		productLogger.Errorf("listProductVariants DB error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.list_variants_failed")})
+		return
+	}
+
+	hubFilter := ""
+	args := []interface{}{}
+	if hubID := c.Query("hub_id"); hubID != "" {
+		hubFilter = " AND h.id = ?"
+		args = append(args, hubID)
+	}
+	args = append(args, p.ID)
+
+	variants := []models.ProductVariant{}
+	if err := db.Raw(
+		`SELECT s.id,s.tenant_id,s.seller_id,s.code,s.name,s.description,s.category_id,s.weight,s.weight_unit,
+                s.length,s.width,s.height,s.is_serialized,s.base_uom,s.product_id,s.variant_attributes,
+                s.created_at,s.updated_at,
+                COALESCE(SUM(i.quantity_on_hand), 0) AS quantity_on_hand,
+                COALESCE(SUM(i.quantity_reserved), 0) AS quantity_reserved,
+                COALESCE(SUM(GREATEST(i.quantity_on_hand - i.quantity_reserved, 0)), 0) AS available
+         FROM skus s
+         LEFT JOIN (inventory i JOIN hubs h ON h.id = i.hub_id AND h.deleted_at IS NULL`+hubFilter+`)
+                ON i.sku_id = s.id
+         WHERE s.product_id = ? AND s.deleted_at IS NULL
+         GROUP BY s.id
+         ORDER BY s.code`,
+		args...,
+	).Scan(&variants).Error; err != nil {
+		productLogger.Errorf("listProductVariants DB error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.list_variants_failed")})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"product": p, "variants": variants})
+}
@@ -0,0 +1,111 @@
+package api
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/abhirup.dandapat/ims/internal/models"
+)
+
+var teeAttributes = []models.ProductAttribute{
+	{Name: "size", Type: "enum", Values: models.StringSlice{"S", "M", "L"}, Position: 0},
+	{Name: "colour", Type: "string", Position: 1},
+	{Name: "chest_cm", Type: "number", Position: 2},
+}
+
+func TestCheckProductAttributes(t *testing.T) {
+	cases := []struct {
+		name    string
+		attrs   []ProductAttributeRequest
+		wantErr bool
+	}{
+		{name: "valid", attrs: []ProductAttributeRequest{{Name: "size", Type: "enum", Values: []string{"S", "M"}}, {Name: "colour", Type: "string"}}},
+		{name: "no attributes", wantErr: true},
+		{name: "repeated name", attrs: []ProductAttributeRequest{{Name: "size", Type: "string"}, {Name: " size", Type: "number"}}, wantErr: true},
+		{name: "unknown type", attrs: []ProductAttributeRequest{{Name: "size", Type: "date"}}, wantErr: true},
+		{name: "enum without values", attrs: []ProductAttributeRequest{{Name: "size", Type: "enum"}}, wantErr: true},
+		{name: "repeated enum value", attrs: []ProductAttributeRequest{{Name: "size", Type: "enum", Values: []string{"S", "S"}}}, wantErr: true},
+		{name: "values on a string", attrs: []ProductAttributeRequest{{Name: "colour", Type: "string", Values: []string{"Red"}}}, wantErr: true},
+	}
+	for _, tc := range cases {
+		attrs, err := checkProductAttributes(tc.attrs)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("%s: checkProductAttributes error = %v, wantErr %v", tc.name, err, tc.wantErr)
+			continue
+		}
+		for i, a := range attrs {
+			if a.Position != i {
+				t.Errorf("%s: attribute %s has position %d, want %d", tc.name, a.Name, a.Position, i)
+			}
+		}
+	}
+}
+
+func TestCheckVariantAttributes(t *testing.T) {
+	cases := []struct {
+		name    string
+		values  map[string]string
+		wantErr bool
+	}{
+		{name: "valid", values: map[string]string{"size": "M", "colour": "Red", "chest_cm": "96.5"}},
+		{name: "value outside enum", values: map[string]string{"size": "XL", "colour": "Red", "chest_cm": "96"}, wantErr: true},
+		{name: "not a number", values: map[string]string{"size": "M", "colour": "Red", "chest_cm": "wide"}, wantErr: true},
+		{name: "blank string", values: map[string]string{"size": "M", "colour": " ", "chest_cm": "96"}, wantErr: true},
+		{name: "missing attribute", values: map[string]string{"size": "M", "colour": "Red"}, wantErr: true},
+		{name: "unknown attribute", values: map[string]string{"size": "M", "colour": "Red", "sleeve": "long"}, wantErr: true},
+	}
+	for _, tc := range cases {
+		if err := checkVariantAttributes(teeAttributes, tc.values); (err != nil) != tc.wantErr {
+			t.Errorf("%s: checkVariantAttributes error = %v, wantErr %v", tc.name, err, tc.wantErr)
+		}
+	}
+}
+
+func TestExpandVariantMatrix(t *testing.T) {
+	defs := teeAttributes[:2]
+	combos, err := expandVariantMatrix(defs, map[string][]string{"size": {"S", "M"}, "colour": {"Red", "Navy Blue"}})
+	if err != nil {
+		t.Fatalf("expandVariantMatrix error = %v", err)
+	}
+	want := []models.VariantAttributes{
+		{"size": "S", "colour": "Red"},
+		{"size": "S", "colour": "Navy Blue"},
+		{"size": "M", "colour": "Red"},
+		{"size": "M", "colour": "Navy Blue"},
+	}
+	if !reflect.DeepEqual(combos, want) {
+		t.Errorf("expandVariantMatrix = %v, want %v", combos, want)
+	}
+
+	if got := variantCode("TEE", defs, combos[1]); got != "TEE-S-Navy-Blue" {
+		t.Errorf("variantCode = %q, want TEE-S-Navy-Blue", got)
+	}
+	if got := variantName("Tee", defs, combos[1]); got != "Tee - S / Navy Blue" {
+		t.Errorf("variantName = %q, want %q", got, "Tee - S / Navy Blue")
+	}
+
+	bad := []map[string][]string{
+		{"size": {"S"}},
+		{"size": {"S"}, "colour": {}},
+		{"size": {"S", "S"}, "colour": {"Red"}},
+		{"size": {"XL"}, "colour": {"Red"}},
+		{"size": {"S"}, "colour": {"Red"}, "sleeve": {"long"}},
+	}
+	for _, axes := range bad {
+		if _, err := expandVariantMatrix(defs, axes); err != errInvalidVariant {
+			t.Errorf("expandVariantMatrix(%v) error = %v, want errInvalidVariant", axes, err)
+		}
+	}
+
+	wide := make([]string, 40)
+	for i := range wide {
+		wide[i] = string(rune('a'+i%26)) + string(rune('a'+i/26))
+	}
+	if _, err := expandVariantMatrix(defs[1:], map[string][]string{"colour": wide}); err != nil {
+		t.Errorf("expandVariantMatrix of %d values error = %v", len(wide), err)
+	}
+	if _, err := expandVariantMatrix([]models.ProductAttribute{defs[1], {Name: "fit", Type: "string"}},
+		map[string][]string{"colour": wide, "fit": wide}); err != errMatrixTooLarge {
+		t.Errorf("expandVariantMatrix of %d combinations error = %v, want errMatrixTooLarge", len(wide)*len(wide), err)
+	}
+}
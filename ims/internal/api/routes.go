@@ -6,32 +6,106 @@ func RegisterRoutes(r *gin.Engine) {
 
 	r.POST("/tenants", createTenant)
 	r.GET("/tenants/:id", getTenant)
+	r.PUT("/tenants/:id", updateTenant)
+	r.DELETE("/tenants/:id", deleteTenant)
+	r.GET("/tenants", listTenants)
 	r.POST("/sellers", createSeller)
 	r.GET("/sellers/:id", getSeller)
+	r.PUT("/sellers/:id", updateSeller)
+	r.DELETE("/sellers/:id", deleteSeller)
+	r.GET("/sellers", listSellers)
 
 	r.POST("/categories", createCategory)
 	r.GET("/categories/:id", getCategory)
+	r.PUT("/categories/:id", updateCategory)
+	r.DELETE("/categories/:id", deleteCategory)
+	r.GET("/categories", listCategories)
+	r.GET("/categories/:id/tree", getCategoryTree)
+	r.GET("/categories/:id/breadcrumbs", getCategoryBreadcrumbs)
+	r.POST("/categories/:id/move", moveCategory)
 
 	r.POST("/hubs", createHub)
 	r.GET("/hubs/:id", getHub)
 	r.PUT("/hubs/:id", updateHub)
 	r.DELETE("/hubs/:id", deleteHub)
 	r.GET("/hubs", listHubs)
+	r.POST("/hubs/:id/locations", createHubLocation)
+	r.GET("/hubs/:id/locations", listHubLocations)
 
 	r.POST("/skus", createSKU)
 	r.GET("/skus/:id", getSKU)
 	r.PUT("/skus/:id", updateSKU)
 	r.DELETE("/skus/:id", deleteSKU)
 	r.GET("/skus", listSKUs)
+	r.PUT("/skus/:id/uoms", putSKUUOM)
+	r.GET("/skus/:id/uoms", listSKUUOMs)
+	r.DELETE("/skus/:id/uoms/:uom", deleteSKUUOM)
+	r.PUT("/skus/:id/components", putSKUKit)
+	r.GET("/skus/:id/components", listSKUKit)
+	r.POST("/skus/:id/barcodes", createSKUBarcode)
+	r.GET("/skus/:id/barcodes", listSKUBarcodes)
+	r.DELETE("/skus/:id/barcodes/:barcode", deleteSKUBarcode)
+	r.GET("/skus/by-barcode/:value", getSKUByBarcode)
+	r.GET("/skus/search", searchSKUs)
+	r.POST("/skus/imports", createCatalogImport)
+	r.GET("/skus/imports", listCatalogImports)
+	r.GET("/skus/imports/:id", getCatalogImport)
+	r.GET("/skus/imports/:id/errors", getCatalogImportErrors)
+
+	r.POST("/products", createProduct)
+	r.GET("/products/:id", getProduct)
+	r.PUT("/products/:id", updateProduct)
+	r.DELETE("/products/:id", deleteProduct)
+	r.GET("/products", listProducts)
+	r.POST("/products/:id/variants", createProductVariant)
+	r.POST("/products/:id/variants/matrix", createProductVariantMatrix)
+	r.GET("/products/:id/variants", listProductVariants)
 
 	r.PUT("/inventory", upsertInventory)
+	r.PUT("/inventory/batch", batchInventory)
 	r.GET("/inventory", listInventory)
+	r.GET("/inventory/as-of", getInventoryAsOf)
+	r.GET("/inventory/atp", getInventoryATP)
+	r.GET("/inventory/valuation", getInventoryValuation)
+	r.GET("/inventory/cost-layers", listInventoryCostLayers)
+	r.POST("/inventory/lots", receiveInventoryLot)
+	r.GET("/inventory/lots", listInventoryLots)
+	r.POST("/inventory/serials", receiveInventorySerials)
+	r.GET("/inventory/serials", listInventorySerials)
+	r.GET("/inventory/serials/:serial", getInventorySerial)
+	r.GET("/inventory/bins", listInventoryBins)
+	r.POST("/inventory/bins/move", moveInventoryBin)
+	r.GET("/inventory/bins/moves", listInventoryBinMoves)
+	r.GET("/inventory/reconcile", getInventoryReconcile)
+	r.POST("/inventory/reconcile", reconcileInventory)
+	r.POST("/inventory/transfers", createInventoryTransfer)
+	r.GET("/inventory/transfers", listInventoryTransfers)
+	r.GET("/inventory/transfers/:id", getInventoryTransfer)
+	r.POST("/inventory/transfers/:id/receive", receiveInventoryTransfer)
+	r.POST("/inventory/transfers/:id/cancel", cancelInventoryTransfer)
+	r.POST("/inventory/counts", createInventoryCount)
+	r.GET("/inventory/counts", listInventoryCounts)
+	r.GET("/inventory/counts/:id", getInventoryCount)
+	r.PUT("/inventory/counts/:id/lines", recordInventoryCountLines)
+	r.GET("/inventory/counts/:id/variance", getInventoryCountVariance)
+	r.POST("/inventory/counts/:id/approve", approveInventoryCount)
+	r.POST("/inventory/counts/:id/cancel", cancelInventoryCount)
+
+	r.PUT("/inventory/thresholds", setInventoryThresholds)
+	r.GET("/inventory/alerts", listInventoryAlerts)
+	r.POST("/inventory/adjust", adjustInventory)
+	r.POST("/inventory/reserve", reserveInventory)
+	r.POST("/inventory/commit", commitInventory)
+	r.POST("/inventory/release", releaseInventory)
 
 	r.POST("/inventory/transactions", createInventoryTransaction)
 	r.GET("/inventory/transactions", listInventoryTransactions)
 
+	r.GET("/v2/inventory", queryInventory)
+
 	r.POST("/webhooks", createWebhook)
 	r.GET("/webhooks/:id", getWebhook)
 	r.PUT("/webhooks/:id", updateWebhook)
 	r.DELETE("/webhooks/:id", deleteWebhook)
+	r.GET("/webhooks/:id/deliveries", listWebhookDeliveries)
 }
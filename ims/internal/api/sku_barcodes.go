@@ -0,0 +1,268 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/omniful/go_commons/i18n"
+	"github.com/omniful/go_commons/log"
+	"gorm.io/gorm"
+
+	"github.com/abhirup.dandapat/ims/constants"
+	"github.com/abhirup.dandapat/ims/internal/models"
+	"github.com/abhirup.dandapat/ims/internal/store"
+)
+
+var barcodeLogger = log.DefaultLogger()
+
+var (
+	// errInvalidBarcode is returned for a barcode that is not 8, 12, 13 or 14
+	// digits or whose check digit is wrong.
+	errInvalidBarcode = errors.New("invalid barcode")
+	// errBarcodeInUse is returned for a barcode another SKU of the tenant
+	// already has.
+	errBarcodeInUse = errors.New("barcode already in use")
+)
+
+const barcodeColumns = `tenant_id,sku_id,barcode,barcode_type,gtin,created_at`
+
+type SKUBarcodeRequest struct {
+	Barcode string `json:"barcode" binding:"required"`
+}
+
+// gtinCheckDigit computes the GS1 check digit of the digits before it:
+// weights 3 and 1 alternate from the rightmost digit.
+func gtinCheckDigit(digits string) byte {
+	sum := 0
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if (len(digits)-1-i)%2 == 0 {
+			d *= 3
+		}
+		sum += d
+	}
+	return byte('0' + (10-sum%10)%10)
+}
+
+// normalizeBarcode validates an EAN-8, UPC-A, EAN-13 or GTIN-14 barcode and
+// returns its type and its 14 digit GTIN.
+func normalizeBarcode(value string) (barcodeType, gtin string, err error) {
+	value = strings.TrimSpace(value)
+	switch len(value) {
+	case 8:
+		barcodeType = constants.BarcodeTypeEAN8
+	case 12:
+		barcodeType = constants.BarcodeTypeUPCA
+	case 13:
+		barcodeType = constants.BarcodeTypeEAN13
+	case 14:
+		barcodeType = constants.BarcodeTypeGTIN14
+	default:
+		return "", "", errInvalidBarcode
+	}
+	for i := 0; i < len(value); i++ {
+		if value[i] < '0' || value[i] > '9' {
+			return "", "", errInvalidBarcode
+		}
+	}
+	if gtinCheckDigit(value[:len(value)-1]) != value[len(value)-1] {
+		return "", "", errInvalidBarcode
+	}
+	return barcodeType, strings.Repeat("0", 14-len(value)) + value, nil
+}
+
+// barcodeCacheKey caches the SKU a tenant's barcode resolves to.
+func barcodeCacheKey(tenantID, gtin string) string {
+	return "sku:barcode:" + tenantID + ":" + gtin
+}
+
+// forgetBarcodes drops the cached lookups of barcodes.
+func forgetBarcodes(ctx context.Context, barcodes []models.SKUBarcode) {
+	for _, b := range barcodes {
+		_, _ = store.RedisClient.Del(ctx, barcodeCacheKey(b.TenantID, b.GTIN))
+	}
+}
+
+// forgetSKUBarcodes drops the cached lookups of every barcode of a SKU, after
+// the SKU changed.
+func forgetSKUBarcodes(ctx context.Context, db *gorm.DB, skuID string) {
+	var barcodes []models.SKUBarcode
+	if err := db.Raw(`SELECT `+barcodeColumns+` FROM sku_barcodes WHERE sku_id = ?`, skuID).Scan(&barcodes).Error; err != nil {
+		barcodeLogger.Errorf("forgetSKUBarcodes DB error: %v", err)
+		return
+	}
+	forgetBarcodes(ctx, barcodes)
+}
+
+// releaseSKUBarcodes removes every barcode of a SKU being deleted, in the
+// transaction of the delete, so the tenant can give them to another SKU. The
+// caller drops the cached lookups of the removed barcodes once it commits.
+func releaseSKUBarcodes(tx *gorm.DB, skuID string) ([]models.SKUBarcode, error) {
+	var removed []models.SKUBarcode
+	err := tx.Raw(`DELETE FROM sku_barcodes WHERE sku_id = ? RETURNING `+barcodeColumns, skuID).Scan(&removed).Error
+	return removed, err
+}
+
+// insertSKUBarcode gives a live SKU a barcode. Adding a barcode the SKU already
+// has returns the stored row with created false.
+func insertSKUBarcode(db *gorm.DB, skuID, value string, now time.Time) (models.SKUBarcode, bool, error) {
+	var b models.SKUBarcode
+	barcodeType, gtin, err := normalizeBarcode(value)
+	if err != nil {
+		return b, false, err
+	}
+
+	res := db.Raw(
+		`INSERT INTO sku_barcodes(tenant_id,gtin,sku_id,barcode,barcode_type,created_at)
+         SELECT tenant_id,?,id,?,?,? FROM skus WHERE id = ? AND deleted_at IS NULL
+         ON CONFLICT (tenant_id,gtin) DO NOTHING
+         RETURNING `+barcodeColumns,
+		gtin, strings.TrimSpace(value), barcodeType, now, skuID,
+	).Scan(&b)
+	if res.Error != nil {
+		return b, false, res.Error
+	}
+	if res.RowsAffected > 0 {
+		return b, true, nil
+	}
+
+	// Nothing inserted: either the SKU is missing or the tenant already has
+	// the barcode, on this SKU or another one.
+	res = db.Raw(
+		`SELECT b.tenant_id,b.sku_id,b.barcode,b.barcode_type,b.gtin,b.created_at
+         FROM skus s JOIN sku_barcodes b ON b.tenant_id = s.tenant_id AND b.gtin = ?
+         WHERE s.id = ? AND s.deleted_at IS NULL`,
+		gtin, skuID,
+	).Scan(&b)
+	switch {
+	case res.Error != nil:
+		return b, false, res.Error
+	case res.RowsAffected == 0:
+		return b, false, gorm.ErrRecordNotFound
+	case b.SKUID != skuID:
+		return b, false, errBarcodeInUse
+	}
+	return b, false, nil
+}
+
+// createSKUBarcode attaches a barcode to a SKU; a SKU can have any number of
+// them.
+func createSKUBarcode(c *gin.Context) {
+	var req SKUBarcodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_request")})
+		return
+	}
+
+	db := store.DB.GetMasterDB(c.Request.Context())
+	b, created, err := insertSKUBarcode(db, c.Param("id"), req.Barcode, time.Now().UTC())
+	switch {
+	case errors.Is(err, errInvalidBarcode):
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_barcode")})
+		return
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.Translate(c, "error.sku_not_found")})
+		return
+	case errors.Is(err, errBarcodeInUse):
+		c.JSON(http.StatusConflict, gin.H{"error": i18n.Translate(c, "error.barcode_in_use")})
+		return
+	case err != nil:
+		barcodeLogger.Errorf("createSKUBarcode DB error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.sku_barcode_failed")})
+		return
+	}
+
+	if !created {
+		c.JSON(http.StatusOK, b)
+		return
+	}
+	c.JSON(http.StatusCreated, b)
+}
+
+func listSKUBarcodes(c *gin.Context) {
+	skuID := c.Param("id")
+	barcodes := []models.SKUBarcode{}
+	db := store.DB.GetSlaveDB(c.Request.Context())
+	if err := db.Raw(
+		`SELECT `+barcodeColumns+` FROM sku_barcodes WHERE sku_id = ? ORDER BY created_at, gtin`, skuID,
+	).Scan(&barcodes).Error; err != nil {
+		barcodeLogger.Errorf("listSKUBarcodes DB error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.sku_barcode_failed")})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"sku_id": skuID, "barcodes": barcodes})
+}
+
+// deleteSKUBarcode removes a barcode from a SKU; the barcode may be given in
+// any of its equivalent forms.
+func deleteSKUBarcode(c *gin.Context) {
+	_, gtin, err := normalizeBarcode(c.Param("barcode"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_barcode")})
+		return
+	}
+
+	var removed []models.SKUBarcode
+	db := store.DB.GetMasterDB(c.Request.Context())
+	if err := db.Raw(
+		`DELETE FROM sku_barcodes WHERE sku_id = ? AND gtin = ? RETURNING `+barcodeColumns, c.Param("id"), gtin,
+	).Scan(&removed).Error; err != nil {
+		barcodeLogger.Errorf("deleteSKUBarcode DB error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.sku_barcode_failed")})
+		return
+	}
+	if len(removed) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.Translate(c, "error.sku_barcode_not_found")})
+		return
+	}
+	forgetBarcodes(c.Request.Context(), removed)
+	c.Status(http.StatusNoContent)
+}
+
+// getSKUByBarcode resolves a scanned barcode to the tenant's live SKU. Like
+// getSKU it reads through Redis; the entry is dropped when the barcode is
+// removed or the SKU updated or deleted.
+func getSKUByBarcode(c *gin.Context) {
+	tenantID := c.Query("tenant_id")
+	if tenantID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_request")})
+		return
+	}
+	_, gtin, err := normalizeBarcode(c.Param("value"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_barcode")})
+		return
+	}
+	key := barcodeCacheKey(tenantID, gtin)
+
+	if cached, err := store.RedisClient.Get(c.Request.Context(), key); err == nil {
+		c.Data(http.StatusOK, "application/json; charset=utf-8", []byte(cached))
+		return
+	}
+
+	var s models.SKU
+	db := store.DB.GetSlaveDB(c.Request.Context())
+	res := db.Raw(
+		`SELECT `+skuColumns+` FROM skus
+         WHERE id = (SELECT sku_id FROM sku_barcodes WHERE tenant_id = ? AND gtin = ?) AND deleted_at IS NULL`,
+		tenantID, gtin,
+	).Scan(&s)
+	if res.Error != nil {
+		barcodeLogger.Errorf("getSKUByBarcode DB error: %v", res.Error)
+	}
+	if res.Error != nil || res.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.Translate(c, "error.sku_not_found")})
+		return
+	}
+
+	if b, err := json.Marshal(s); err == nil {
+		_, _ = store.RedisClient.Set(c.Request.Context(), key, string(b), 5*time.Minute)
+	}
+
+	c.JSON(http.StatusOK, s)
+}
@@ -0,0 +1,29 @@
+package api
+
+import "testing"
+
+func TestNormalizeBarcode(t *testing.T) {
+	cases := []struct {
+		in       string
+		wantType string
+		wantGTIN string
+	}{
+		{in: "96385074", wantType: "ean8", wantGTIN: "00000096385074"},
+		{in: "036000291452", wantType: "upc_a", wantGTIN: "00036000291452"},
+		{in: "0036000291452", wantType: "ean13", wantGTIN: "00036000291452"},
+		{in: "4006381333931", wantType: "ean13", wantGTIN: "04006381333931"},
+		{in: " 10614141000415 ", wantType: "gtin14", wantGTIN: "10614141000415"},
+	}
+	for _, tc := range cases {
+		typ, gtin, err := normalizeBarcode(tc.in)
+		if err != nil || typ != tc.wantType || gtin != tc.wantGTIN {
+			t.Errorf("normalizeBarcode(%q) = %q, %q, %v, want %q, %q", tc.in, typ, gtin, err, tc.wantType, tc.wantGTIN)
+		}
+	}
+
+	for _, bad := range []string{"", "4006381333932", "036000291453", "12345", "40063813339a1", "400638133393100"} {
+		if _, _, err := normalizeBarcode(bad); err != errInvalidBarcode {
+			t.Errorf("normalizeBarcode(%q) error = %v, want errInvalidBarcode", bad, err)
+		}
+	}
+}
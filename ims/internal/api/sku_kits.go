@@ -0,0 +1,294 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/omniful/go_commons/i18n"
+	"github.com/omniful/go_commons/log"
+	"gorm.io/gorm"
+
+	"github.com/abhirup.dandapat/ims/internal/models"
+	"github.com/abhirup.dandapat/ims/internal/store"
+)
+
+var kitLogger = log.DefaultLogger()
+
+// errInvalidKit is returned for a kit definition that lists a component twice,
+// includes the kit itself, nests kits or uses serialized components.
+var errInvalidKit = errors.New("invalid kit definition")
+
+// errKitStock is returned when stock would be written to a kit SKU, whose
+// stock is only ever derived from its components.
+var errKitStock = errors.New("kit SKUs hold no stock of their own")
+
+type SKUKitComponentRequest struct {
+	SKUID    string `json:"sku_id"   binding:"required"`
+	Quantity int64  `json:"quantity" binding:"required,gt=0"`
+}
+
+// SKUKitRequest replaces a kit's components; an empty list turns the SKU back
+// into a plain SKU.
+type SKUKitRequest struct {
+	Components []SKUKitComponentRequest `json:"components" binding:"dive"`
+}
+
+// checkKitComponents validates the shape of a kit definition for kitID.
+func checkKitComponents(kitID string, components []SKUKitComponentRequest) error {
+	seen := make(map[string]bool, len(components))
+	for _, comp := range components {
+		if comp.SKUID == kitID || comp.Quantity <= 0 || seen[comp.SKUID] {
+			return errInvalidKit
+		}
+		seen[comp.SKUID] = true
+	}
+	return nil
+}
+
+// kitAvailable is the number of whole kits the available stock of the
+// components makes up; the scarcest component limits it.
+func kitAvailable(components []models.InventoryKitComponent) int64 {
+	if len(components) == 0 {
+		return 0
+	}
+	var kits int64 = -1
+	for _, comp := range components {
+		if comp.Quantity <= 0 {
+			return 0
+		}
+		available := comp.QuantityOnHand - comp.QuantityReserved
+		if available < 0 {
+			available = 0
+		}
+		if n := available / comp.Quantity; kits < 0 || n < kits {
+			kits = n
+		}
+	}
+	return kits
+}
+
+// refuseKits returns errKitStock when any of skuIDs is a kit. The SKUs are
+// locked for share, so putSKUKit cannot turn one into a kit while the write
+// goes in.
+func refuseKits(db *gorm.DB, skuIDs ...string) error {
+	var kits int64
+	if err := db.Raw(
+		`SELECT COUNT(*) FROM (SELECT id FROM skus WHERE id IN (?) FOR SHARE) s
+         WHERE EXISTS(SELECT 1 FROM sku_kit_components WHERE kit_sku_id = s.id)`, skuIDs,
+	).Scan(&kits).Error; err != nil {
+		return err
+	}
+	if kits > 0 {
+		return errKitStock
+	}
+	return nil
+}
+
+// kitError answers a request refused by refuseKits: 409 for a kit SKU and
+// 500 with failed otherwise.
+func kitError(c *gin.Context, err error, failed string) {
+	if errors.Is(err, errKitStock) {
+		c.JSON(http.StatusConflict, gin.H{"error": i18n.Translate(c, "error.sku_is_kit")})
+		return
+	}
+	kitLogger.Errorf("%s %s kit lookup error: %v", c.Request.Method, c.FullPath(), err)
+	c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, failed)})
+}
+
+func loadKitComponents(db *gorm.DB, kitID string) ([]models.SKUKitComponent, error) {
+	var components []models.SKUKitComponent
+	err := db.Raw(
+		`SELECT kit_sku_id,component_sku_id,quantity,created_at FROM sku_kit_components
+         WHERE kit_sku_id = ? ORDER BY component_sku_id`, kitID,
+	).Scan(&components).Error
+	return components, err
+}
+
+// putSKUKit defines the SKU as a kit of the given components, replacing any
+// earlier definition. Component quantities are in the component's base unit.
+// Open kit reservations keep the component quantities they were made with.
+func putSKUKit(c *gin.Context) {
+	var req SKUKitRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_request")})
+		return
+	}
+	kitID := c.Param("id")
+	if err := checkKitComponents(kitID, req.Components); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_kit")})
+		return
+	}
+	now := time.Now().UTC()
+
+	tx := store.DB.GetMasterDB(c.Request.Context()).Begin()
+	if tx.Error != nil {
+		kitLogger.Errorf("putSKUKit begin tx error: %v", tx.Error)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.sku_kit_failed")})
+		return
+	}
+	defer tx.Rollback()
+
+	components, err := replaceKitComponents(tx, kitID, req.Components, now)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.Translate(c, "error.sku_not_found")})
+		return
+	case errors.Is(err, errInvalidKit):
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_kit")})
+		return
+	case err != nil:
+		kitLogger.Errorf("putSKUKit error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.sku_kit_failed")})
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		kitLogger.Errorf("putSKUKit commit error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.sku_kit_failed")})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"sku_id": kitID, "components": components})
+}
+
+// replaceKitComponents replaces the components of the live SKU kitID. A SKU
+// that is serialized, a component of another kit or holds stock of its own
+// cannot become a kit, and components must be live SKUs of the kit's tenant.
+func replaceKitComponents(tx *gorm.DB, kitID string, reqs []SKUKitComponentRequest, now time.Time) ([]models.SKUKitComponent, error) {
+	var kit struct {
+		IsSerialized bool `gorm:"column:is_serialized"`
+		IsComponent  bool `gorm:"column:is_component"`
+		HasStock     bool `gorm:"column:has_stock"`
+	}
+	res := tx.Raw(
+		`SELECT is_serialized,
+                EXISTS(SELECT 1 FROM sku_kit_components WHERE component_sku_id = skus.id) AS is_component,
+                EXISTS(SELECT 1 FROM inventory WHERE sku_id = skus.id
+                       AND (quantity_on_hand <> 0 OR quantity_reserved <> 0 OR quantity_in_transit <> 0)) AS has_stock
+         FROM skus WHERE id = ? AND deleted_at IS NULL FOR UPDATE`, kitID,
+	).Scan(&kit)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	if len(reqs) > 0 && (kit.IsSerialized || kit.IsComponent || kit.HasStock) {
+		return nil, errInvalidKit
+	}
+
+	if err := tx.Exec(`DELETE FROM sku_kit_components WHERE kit_sku_id = ?`, kitID).Error; err != nil {
+		return nil, err
+	}
+
+	components := make([]models.SKUKitComponent, 0, len(reqs))
+	for _, r := range reqs {
+		// A component must be a plain live SKU of the kit's tenant: not
+		// serialized, since kit commits carry no serial numbers, and not a kit
+		// itself.
+		res := tx.Exec(
+			`INSERT INTO sku_kit_components(kit_sku_id,component_sku_id,quantity,created_at)
+             SELECT ?,id,?,? FROM skus
+             WHERE id = ? AND tenant_id = (SELECT tenant_id FROM skus WHERE id = ?) AND deleted_at IS NULL
+               AND NOT is_serialized
+               AND NOT EXISTS(SELECT 1 FROM sku_kit_components WHERE kit_sku_id = skus.id)`,
+			kitID, r.Quantity, now, r.SKUID, kitID,
+		)
+		if res.Error != nil {
+			return nil, res.Error
+		}
+		if res.RowsAffected == 0 {
+			return nil, errInvalidKit
+		}
+		components = append(components, models.SKUKitComponent{
+			KitSKUID: kitID, ComponentSKUID: r.SKUID, Quantity: r.Quantity, CreatedAt: now,
+		})
+	}
+	return components, nil
+}
+
+func listSKUKit(c *gin.Context) {
+	kitID := c.Param("id")
+	components, err := loadKitComponents(store.DB.GetSlaveDB(c.Request.Context()), kitID)
+	if err != nil {
+		kitLogger.Errorf("listSKUKit DB error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.sku_kit_failed")})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"sku_id": kitID, "components": components})
+}
+
+// attachKits adds a derived row for each kit to inventory rows of one hub:
+// the kits among skuIDs, or with no skuIDs every kit with a component stocked
+// at the hub. A kit row's quantity_on_hand is the number of kits available to
+// reserve and its quantity_reserved is zero; the components' own rows carry
+// the stock held for kit reservations.
+func attachKits(db *gorm.DB, hubID string, skuIDs []string, invs []models.Inventory) ([]models.Inventory, error) {
+	filter := `k.kit_sku_id IN (SELECT k2.kit_sku_id FROM sku_kit_components k2
+                                JOIN inventory i2 ON i2.sku_id = k2.component_sku_id AND i2.hub_id = ?)`
+	args := []interface{}{hubID, hubID}
+	if len(skuIDs) > 0 {
+		ph := strings.Repeat("?,", len(skuIDs))
+		filter = fmt.Sprintf(`k.kit_sku_id IN (%s)`, ph[:len(ph)-1])
+		args = args[:1]
+		for _, id := range skuIDs {
+			args = append(args, id)
+		}
+	}
+
+	var rows []struct {
+		KitSKUID         string    `gorm:"column:kit_sku_id"`
+		SKUID            string    `gorm:"column:sku_id"`
+		Quantity         int64     `gorm:"column:quantity"`
+		QuantityOnHand   int64     `gorm:"column:quantity_on_hand"`
+		QuantityReserved int64     `gorm:"column:quantity_reserved"`
+		UpdatedAt        time.Time `gorm:"column:updated_at"`
+	}
+	if err := db.Raw(
+		`SELECT k.kit_sku_id, k.component_sku_id AS sku_id, k.quantity,
+                COALESCE(i.quantity_on_hand, 0) AS quantity_on_hand,
+                COALESCE(i.quantity_reserved, 0) AS quantity_reserved,
+                COALESCE(i.updated_at, k.created_at) AS updated_at
+         FROM sku_kit_components k
+         LEFT JOIN inventory i ON i.sku_id = k.component_sku_id AND i.hub_id = ?
+         WHERE `+filter+`
+         ORDER BY k.kit_sku_id, k.component_sku_id`,
+		args...,
+	).Scan(&rows).Error; err != nil {
+		return invs, err
+	}
+
+	idx := make(map[string]int, len(invs))
+	for i, inv := range invs {
+		idx[inv.SKUID] = i
+	}
+	var kits []models.Inventory
+	for _, r := range rows {
+		if n := len(kits); n == 0 || kits[n-1].SKUID != r.KitSKUID {
+			kits = append(kits, models.Inventory{HubID: hubID, SKUID: r.KitSKUID, IsKit: true})
+		}
+		k := &kits[len(kits)-1]
+		k.Components = append(k.Components, models.InventoryKitComponent{
+			SKUID:            r.SKUID,
+			Quantity:         r.Quantity,
+			QuantityOnHand:   r.QuantityOnHand,
+			QuantityReserved: r.QuantityReserved,
+		})
+		if r.UpdatedAt.After(k.UpdatedAt) {
+			k.UpdatedAt = r.UpdatedAt
+		}
+	}
+	for _, k := range kits {
+		k.QuantityOnHand = kitAvailable(k.Components)
+		if i, ok := idx[k.SKUID]; ok {
+			invs[i] = k
+			continue
+		}
+		invs = append(invs, k)
+	}
+	return invs, nil
+}
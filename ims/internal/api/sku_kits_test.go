@@ -0,0 +1,64 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/abhirup.dandapat/ims/internal/models"
+)
+
+func TestKitAvailable(t *testing.T) {
+	cases := []struct {
+		name       string
+		components []models.InventoryKitComponent
+		want       int64
+	}{
+		{name: "no components", want: 0},
+		{
+			name: "limited by scarcest component",
+			components: []models.InventoryKitComponent{
+				{SKUID: "a", Quantity: 2, QuantityOnHand: 10, QuantityReserved: 1},
+				{SKUID: "b", Quantity: 1, QuantityOnHand: 3},
+			},
+			want: 3,
+		},
+		{
+			name: "partial kits round down",
+			components: []models.InventoryKitComponent{
+				{SKUID: "a", Quantity: 4, QuantityOnHand: 7},
+			},
+			want: 1,
+		},
+		{
+			name: "over-reserved component",
+			components: []models.InventoryKitComponent{
+				{SKUID: "a", Quantity: 1, QuantityOnHand: 5},
+				{SKUID: "b", Quantity: 1, QuantityOnHand: 2, QuantityReserved: 4},
+			},
+			want: 0,
+		},
+	}
+	for _, tc := range cases {
+		if got := kitAvailable(tc.components); got != tc.want {
+			t.Errorf("%s: kitAvailable = %d, want %d", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestCheckKitComponents(t *testing.T) {
+	cases := []struct {
+		name       string
+		components []SKUKitComponentRequest
+		wantErr    bool
+	}{
+		{name: "empty clears the kit"},
+		{name: "valid", components: []SKUKitComponentRequest{{SKUID: "a", Quantity: 1}, {SKUID: "b", Quantity: 3}}},
+		{name: "kit in itself", components: []SKUKitComponentRequest{{SKUID: "kit", Quantity: 1}}, wantErr: true},
+		{name: "duplicate", components: []SKUKitComponentRequest{{SKUID: "a", Quantity: 1}, {SKUID: "a", Quantity: 2}}, wantErr: true},
+		{name: "zero quantity", components: []SKUKitComponentRequest{{SKUID: "a"}}, wantErr: true},
+	}
+	for _, tc := range cases {
+		if err := checkKitComponents("kit", tc.components); (err != nil) != tc.wantErr {
+			t.Errorf("%s: checkKitComponents error = %v, wantErr %v", tc.name, err, tc.wantErr)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/omniful/go_commons/i18n"
+	"github.com/omniful/go_commons/log"
+
+	"github.com/abhirup.dandapat/ims/internal/models"
+	"github.com/abhirup.dandapat/ims/internal/store"
+)
+
+var searchLogger = log.DefaultLogger()
+
+const (
+	defaultSearchPageSize = 20
+	maxSearchPageSize     = 100
+)
+
+// skuSearchRank scores a SKU against the search text: full-text rank over
+// code, name and description (weighted in that order), plus the better
+// trigram similarity of code or name, plus 1 for an exact code. It is
+// rounded so that it survives the round trip through a cursor.
+const skuSearchRank = `ROUND(CAST(ts_rank(search_vector, q.tsq)
+                                  + GREATEST(similarity(code, ?), similarity(name, ?))
+                                  + CASE WHEN lower(code) = lower(?) THEN 1 ELSE 0 END AS NUMERIC), 6)`
+
+// escapeLike quotes the LIKE wildcards in s, so it matches literally.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// searchSKUs answers GET /skus/search: a tenant's SKUs matching q, best
+// match first. A SKU matches when q's words are found in its code, name or
+// description (English stemming for name and description), when its code
+// contains q, or when its code or name is similar to q, which catches typos.
+// seller_id and category_id (with include_descendants) narrow the search.
+// Pages follow next_cursor.
+func searchSKUs(c *gin.Context) {
+	q := strings.TrimSpace(c.Query("q"))
+	tenantID := c.Query("tenant_id")
+	if q == "" || tenantID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_request")})
+		return
+	}
+
+	where := []string{"tenant_id = ?"}
+	args := []interface{}{tenantID}
+	if sellerID := c.Query("seller_id"); sellerID != "" {
+		where = append(where, "seller_id = ?")
+		args = append(args, sellerID)
+	}
+	if categoryID := c.Query("category_id"); categoryID != "" {
+		descendants, err := parseBoolQuery(c, "include_descendants")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_request")})
+			return
+		}
+		if descendants {
+			where = append(where, "category_id IN ("+categoryDescendantsSQL+")")
+		} else {
+			where = append(where, "category_id = ?")
+		}
+		args = append(args, categoryID)
+	}
+	where, err := liveFilter(c, where)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_request")})
+		return
+	}
+	where = append(where, "(search_vector @@ q.tsq OR code ILIKE ? OR code % ? OR name % ?)")
+	args = append(args, "%"+escapeLike(q)+"%", q, q)
+
+	limit := defaultSearchPageSize
+	if v := c.Query("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_request")})
+			return
+		}
+		limit = min(limit, maxSearchPageSize)
+	}
+	after := ""
+	if v := c.Query("cursor"); v != "" {
+		// The rank is kept as the 6-decimal text Postgres rounded it to.
+		parts, err := decodeCursor(v, 2)
+		if err == nil {
+			_, err = strconv.ParseFloat(parts[0], 64)
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_request")})
+			return
+		}
+		after = "WHERE (rank, id) < (CAST(? AS NUMERIC), CAST(? AS UUID))"
+		args = append(args, parts[0], parts[1])
+	}
+	args = append(args, limit+1)
+
+	sql := `SELECT * FROM (
+                SELECT ` + skuColumns + `,deleted_at,` + skuSearchRank + ` AS rank
+                FROM skus, (SELECT websearch_to_tsquery('english', ?) AS tsq) q
+                WHERE ` + strings.Join(where, " AND ") + `
+            ) hits ` + after + `
+            ORDER BY rank DESC, id DESC
+            LIMIT ?`
+	args = append([]interface{}{q, q, q, q}, args...)
+
+	db := store.DB.GetSlaveDB(c.Request.Context())
+	rows, err := db.Raw(sql, args...).Rows()
+	if err != nil {
+		searchLogger.Errorf("searchSKUs DB error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.search_skus_failed")})
+		return
+	}
+	defer rows.Close()
+
+	hits := []models.SKUSearchHit{}
+	for rows.Next() {
+		var h models.SKUSearchHit
+		if err := db.ScanRows(rows, &h); err != nil {
+			searchLogger.Warnf("scan sku search row: %v", err)
+			continue
+		}
+		hits = append(hits, h)
+	}
+
+	resp := gin.H{"skus": hits}
+	if len(hits) > limit {
+		hits = hits[:limit]
+		last := hits[limit-1]
+		resp["skus"] = hits
+		resp["next_cursor"] = encodeCursor(strconv.FormatFloat(last.Rank, 'f', 6, 64), last.ID)
+	}
+	c.JSON(http.StatusOK, resp)
+}
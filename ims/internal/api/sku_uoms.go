@@ -0,0 +1,148 @@
+package api
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/omniful/go_commons/i18n"
+	"github.com/omniful/go_commons/log"
+	"gorm.io/gorm"
+
+	"github.com/abhirup.dandapat/ims/internal/models"
+	"github.com/abhirup.dandapat/ims/internal/store"
+)
+
+var uomLogger = log.DefaultLogger()
+
+// errUnknownUOM is returned for a unit the SKU has no conversion for, or a
+// quantity too large to convert.
+var errUnknownUOM = errors.New("unit of measure not defined for SKU")
+
+type SKUUOMRequest struct {
+	UOM    string `json:"uom"    binding:"required"`
+	Factor int64  `json:"factor" binding:"required,gt=0"`
+}
+
+// convertQuantity multiplies qty in a unit by the unit's factor, failing
+// rather than overflowing.
+func convertQuantity(qty, factor int64) (int64, error) {
+	if factor <= 0 {
+		return 0, errUnknownUOM
+	}
+	if qty > math.MaxInt64/factor || qty < math.MinInt64/factor {
+		return 0, errUnknownUOM
+	}
+	return qty * factor, nil
+}
+
+// toBaseUnits converts qty in uom to skuID's base unit. An empty uom or the
+// base unit itself leaves qty as it is.
+func toBaseUnits(db *gorm.DB, skuID, uom string, qty int64) (int64, error) {
+	if uom == "" {
+		return qty, nil
+	}
+	var u struct {
+		BaseUOM string `gorm:"column:base_uom"`
+		Factor  int64  `gorm:"column:factor"`
+	}
+	res := db.Raw(
+		`SELECT s.base_uom, COALESCE(u.factor, 0) AS factor
+         FROM skus s LEFT JOIN sku_uoms u ON u.sku_id = s.id AND u.uom = ?
+         WHERE s.id = ?`,
+		uom, skuID,
+	).Scan(&u)
+	if res.Error != nil {
+		return 0, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return 0, errUnknownUOM
+	}
+	if u.BaseUOM == uom {
+		return qty, nil
+	}
+	return convertQuantity(qty, u.Factor)
+}
+
+// putSKUUOM defines or changes how many base units one uom of the SKU holds.
+// Changing a factor does not touch stock already booked, which is kept in base
+// units.
+func putSKUUOM(c *gin.Context) {
+	var req SKUUOMRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_request")})
+		return
+	}
+	skuID := c.Param("id")
+	now := time.Now().UTC()
+
+	db := store.DB.GetMasterDB(c.Request.Context())
+	var u models.SKUUOM
+	res := db.Raw(
+		`INSERT INTO sku_uoms(sku_id,uom,factor,created_at,updated_at)
+         SELECT id,?,?,?,? FROM skus WHERE id = ? AND base_uom <> ?
+         ON CONFLICT (sku_id,uom) DO UPDATE SET factor = EXCLUDED.factor, updated_at = EXCLUDED.updated_at
+         RETURNING sku_id,uom,factor,created_at,updated_at`,
+		req.UOM, req.Factor, now, now, skuID, req.UOM,
+	).Scan(&u)
+	if res.Error != nil {
+		uomLogger.Errorf("putSKUUOM DB error: %v", res.Error)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.sku_uom_failed")})
+		return
+	}
+	if res.RowsAffected == 0 {
+		// Either the SKU does not exist or uom is its base unit, whose factor
+		// is always 1.
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_uom")})
+		return
+	}
+
+	c.JSON(http.StatusOK, u)
+}
+
+// listSKUUOMs returns the SKU's base unit followed by its other units.
+func listSKUUOMs(c *gin.Context) {
+	skuID := c.Param("id")
+	db := store.DB.GetSlaveDB(c.Request.Context())
+
+	var sku models.SKU
+	res := db.Raw(`SELECT id,base_uom,created_at,updated_at FROM skus WHERE id = ?`, skuID).Scan(&sku)
+	if res.Error != nil {
+		uomLogger.Errorf("listSKUUOMs DB error: %v", res.Error)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.sku_uom_failed")})
+		return
+	}
+	if res.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.Translate(c, "error.sku_not_found")})
+		return
+	}
+
+	var uoms []models.SKUUOM
+	if err := db.Raw(
+		`SELECT sku_id,uom,factor,created_at,updated_at FROM sku_uoms WHERE sku_id = ? ORDER BY factor, uom`, skuID,
+	).Scan(&uoms).Error; err != nil {
+		uomLogger.Errorf("listSKUUOMs DB error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.sku_uom_failed")})
+		return
+	}
+	base := models.SKUUOM{SKUID: skuID, UOM: sku.BaseUOM, Factor: 1, CreatedAt: sku.CreatedAt, UpdatedAt: sku.UpdatedAt}
+
+	c.JSON(http.StatusOK, gin.H{"base_uom": sku.BaseUOM, "uoms": append([]models.SKUUOM{base}, uoms...)})
+}
+
+func deleteSKUUOM(c *gin.Context) {
+	db := store.DB.GetMasterDB(c.Request.Context())
+	res := db.Exec(`DELETE FROM sku_uoms WHERE sku_id = ? AND uom = ?`, c.Param("id"), c.Param("uom"))
+	if res.Error != nil {
+		uomLogger.Errorf("deleteSKUUOM DB error: %v", res.Error)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.sku_uom_failed")})
+		return
+	}
+	if res.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.Translate(c, "error.sku_uom_not_found")})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
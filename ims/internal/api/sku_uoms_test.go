@@ -0,0 +1,33 @@
+package api
+
+import (
+	"math"
+	"testing"
+)
+
+func TestConvertQuantity(t *testing.T) {
+	cases := []struct {
+		qty, factor int64
+		want        int64
+		wantErr     bool
+	}{
+		{qty: 3, factor: 12, want: 36},
+		{qty: -2, factor: 24, want: -48},
+		{qty: 5, factor: 1, want: 5},
+		{qty: 1, factor: 0, wantErr: true},
+		{qty: math.MaxInt64 / 2, factor: 3, wantErr: true},
+		{qty: math.MinInt64 / 2, factor: 3, wantErr: true},
+	}
+	for _, tc := range cases {
+		got, err := convertQuantity(tc.qty, tc.factor)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("convertQuantity(%d, %d): expected error", tc.qty, tc.factor)
+			}
+			continue
+		}
+		if err != nil || got != tc.want {
+			t.Errorf("convertQuantity(%d, %d) = %d, %v, want %d", tc.qty, tc.factor, got, err, tc.want)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package dispatcher
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/omniful/go_commons/db/sql/postgres"
+	commonsHttp "github.com/omniful/go_commons/http"
+	"github.com/omniful/go_commons/log"
+
+	"github.com/abhirup.dandapat/ims/constants"
+)
+
+// pendingDelivery is a claimed webhook_deliveries row joined with the
+// registration it targets.
+type pendingDelivery struct {
+	ID          string `gorm:"column:id"`
+	WebhookID   string `gorm:"column:webhook_id"`
+	Event       string `gorm:"column:event"`
+	Payload     []byte `gorm:"column:payload"`
+	Attempts    int    `gorm:"column:attempts"`
+	CallbackURL string `gorm:"column:callback_url"`
+	Headers     []byte `gorm:"column:headers"`
+	IsActive    bool   `gorm:"column:is_active"`
+}
+
+// deliveryTimeout bounds one webhook POST.
+const deliveryTimeout = 5 * time.Second
+
+// leaseMargin covers the database writes around a batch's deliveries.
+const leaseMargin = time.Minute
+
+type Dispatcher struct {
+	db          *postgres.DbCluster
+	httpClient  *commonsHttp.Client
+	batchSize   int
+	maxAttempts int
+	backoff     time.Duration
+	lease       time.Duration
+	logger      *log.Logger
+}
+
+func NewDispatcher(
+	db *postgres.DbCluster,
+	httpClient *commonsHttp.Client,
+	batchSize int,
+	maxAttempts int,
+	backoff time.Duration,
+) *Dispatcher {
+	return &Dispatcher{
+		db:          db,
+		httpClient:  httpClient,
+		batchSize:   batchSize,
+		maxAttempts: maxAttempts,
+		backoff:     backoff,
+		lease:       time.Duration(batchSize)*deliveryTimeout + leaseMargin,
+		logger:      log.DefaultLogger(),
+	}
+}
+
+// Run polls for due deliveries every interval until ctx is done.
+func (d *Dispatcher) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := d.DispatchPending(ctx); err != nil {
+			d.logger.Errorf("dispatch pending webhooks: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DispatchPending claims up to batchSize due deliveries and sends them. A
+// claim pushes next_attempt_at out by the lease, so concurrent dispatchers
+// skip rows that are in flight and a crashed dispatcher's rows come back. The
+// batch is sent one delivery at a time, so the lease covers every delivery
+// timing out in turn; a shorter one would let another dispatcher claim the
+// tail of the batch while it is still queued here and send it twice.
+func (d *Dispatcher) DispatchPending(ctx context.Context) error {
+	now := time.Now().UTC()
+	var batch []pendingDelivery
+	if err := d.db.GetMasterDB(ctx).Raw(
+		`WITH claimed AS (
+             UPDATE webhook_deliveries SET next_attempt_at = ?
+             WHERE id IN (
+                 SELECT id FROM webhook_deliveries
+                 WHERE status = ? AND next_attempt_at <= ?
+                 ORDER BY next_attempt_at
+                 LIMIT ?
+                 FOR UPDATE SKIP LOCKED
+             )
+             RETURNING id,webhook_id,event,payload,attempts
+         )
+         SELECT c.id,c.webhook_id,c.event,c.payload,c.attempts,w.callback_url,w.headers,w.is_active
+         FROM claimed c JOIN webhooks w ON w.id = c.webhook_id`,
+		now.Add(d.lease), constants.DeliveryStatusPending, now, d.batchSize,
+	).Scan(&batch).Error; err != nil {
+		return err
+	}
+
+	for _, p := range batch {
+		d.deliver(ctx, p)
+	}
+	return nil
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, p pendingDelivery) {
+	if !p.IsActive {
+		d.record(ctx, p, constants.DeliveryStatusCancelled, p.Attempts, nil, "webhook deactivated")
+		return
+	}
+
+	hdrs := make(http.Header)
+	if len(p.Headers) > 0 {
+		var m map[string]string
+		if err := json.Unmarshal(p.Headers, &m); err != nil {
+			d.logger.Warnf("webhook %s: ignoring malformed headers: %v", p.WebhookID, err)
+		}
+		for k, v := range m {
+			hdrs.Add(k, v)
+		}
+	}
+	hdrs.Set("Content-Type", "application/json")
+	hdrs.Set("X-IMS-Event", p.Event)
+	hdrs.Set("X-IMS-Delivery", p.ID)
+
+	resp, err := d.httpClient.Post(&commonsHttp.Request{
+		Url:     p.CallbackURL,
+		Body:    json.RawMessage(p.Payload),
+		Timeout: deliveryTimeout,
+		Headers: hdrs,
+	}, nil)
+	if err != nil {
+		d.logger.Warnf("webhook POST failed (%s→%s): %v", p.WebhookID, p.CallbackURL, err)
+		d.retryOrFail(ctx, p, nil, err.Error())
+		return
+	}
+
+	code := resp.StatusCode()
+	if code < 200 || code >= 300 {
+		d.logger.Warnf("webhook POST %s→%s returned %d", p.WebhookID, p.CallbackURL, code)
+		d.retryOrFail(ctx, p, &code, fmt.Sprintf("unexpected status %d", code))
+		return
+	}
+
+	d.record(ctx, p, constants.DeliveryStatusDelivered, p.Attempts+1, &code, "")
+	d.logger.Infof("webhook delivered: %s %s → %s", p.Event, p.WebhookID, p.CallbackURL)
+}
+
+// retryDelay is how long to wait after the attempts-th failed attempt: the
+// backoff doubles with each attempt. ok is false once maxAttempts is reached
+// and the delivery should fail instead.
+func (d *Dispatcher) retryDelay(attempts int) (delay time.Duration, ok bool) {
+	if attempts >= d.maxAttempts {
+		return 0, false
+	}
+	return d.backoff * time.Duration(1<<uint(attempts-1)), true
+}
+
+// retryOrFail schedules the next attempt with exponential backoff, or marks
+// the delivery failed once maxAttempts is reached.
+func (d *Dispatcher) retryOrFail(ctx context.Context, p pendingDelivery, code *int, errMsg string) {
+	attempts := p.Attempts + 1
+	delay, ok := d.retryDelay(attempts)
+	if !ok {
+		d.record(ctx, p, constants.DeliveryStatusFailed, attempts, code, errMsg)
+		return
+	}
+
+	now := time.Now().UTC()
+	next := now.Add(delay)
+	if err := d.db.GetMasterDB(ctx).Exec(
+		`UPDATE webhook_deliveries
+         SET attempts = ?, response_status = ?, last_error = ?, next_attempt_at = ?, updated_at = ?
+         WHERE id = ?`,
+		attempts, code, errMsg, next, now, p.ID,
+	).Error; err != nil {
+		d.logger.Errorf("schedule retry for delivery %s: %v", p.ID, err)
+	}
+}
+
+func (d *Dispatcher) record(ctx context.Context, p pendingDelivery, status string, attempts int, code *int, errMsg string) {
+	now := time.Now().UTC()
+	var deliveredAt *time.Time
+	if status == constants.DeliveryStatusDelivered {
+		deliveredAt = &now
+	}
+	var lastError *string
+	if errMsg != "" {
+		lastError = &errMsg
+	}
+	if err := d.db.GetMasterDB(ctx).Exec(
+		`UPDATE webhook_deliveries
+         SET status = ?, attempts = ?, response_status = ?, last_error = ?, delivered_at = ?, updated_at = ?
+         WHERE id = ?`,
+		status, attempts, code, lastError, deliveredAt, now, p.ID,
+	).Error; err != nil {
+		d.logger.Errorf("record delivery %s as %s: %v", p.ID, status, err)
+	}
+}
@@ -0,0 +1,36 @@
+package dispatcher
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRetryDelay(t *testing.T) {
+	d := &Dispatcher{maxAttempts: 4, backoff: 30 * time.Second}
+	cases := []struct {
+		attempts int
+		want     time.Duration
+		wantOK   bool
+	}{
+		{attempts: 1, want: 30 * time.Second, wantOK: true},
+		{attempts: 2, want: time.Minute, wantOK: true},
+		{attempts: 3, want: 2 * time.Minute, wantOK: true},
+		{attempts: 4},
+		{attempts: 5},
+	}
+	for _, tc := range cases {
+		got, ok := d.retryDelay(tc.attempts)
+		if ok != tc.wantOK || got != tc.want {
+			t.Errorf("retryDelay(%d) = %v, %v, want %v, %v", tc.attempts, got, ok, tc.want, tc.wantOK)
+		}
+	}
+}
+
+func TestLeaseOutlastsBatch(t *testing.T) {
+	for _, batchSize := range []int{1, 12, 50, 500} {
+		d := NewDispatcher(nil, nil, batchSize, 5, time.Second)
+		if d.lease <= time.Duration(batchSize)*deliveryTimeout {
+			t.Errorf("batchSize %d: lease %v does not cover %d deliveries timing out", batchSize, d.lease, batchSize)
+		}
+	}
+}
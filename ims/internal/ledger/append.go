@@ -0,0 +1,40 @@
+package ledger
+
+import (
+	"gorm.io/gorm"
+
+	"github.com/abhirup.dandapat/ims/constants"
+	"github.com/abhirup.dandapat/ims/internal/models"
+	"github.com/abhirup.dandapat/ims/internal/outbox"
+)
+
+// Append adds a row to inventory_transactions using db, which may be an open
+// transaction so the row commits together with the balance change, and queues
+// the inventory.transaction.created event. Serial numbers on t are linked to the
+// row in inventory_transaction_serials. Rows that move on-hand stock are
+// costed against the hub's cost layers first (see valueRow); t.UnitCost is only
+// read on inbound rows.
+func Append(db *gorm.DB, t models.InventoryTransaction) error {
+	if err := valueRow(db, &t); err != nil {
+		return err
+	}
+	if err := db.Exec(
+		`INSERT INTO inventory_transactions
+		 (id,tenant_id,hub_id,sku_id,delta,transaction_type,reference_id,reason_code,lot_number,uom,uom_quantity,unit_cost,total_cost,created_at)
+		 VALUES(?,?,?,?,?,?,?,?,?,?,?,?,?,?)`,
+		t.ID, t.TenantID, t.HubID, t.SKUID,
+		t.Delta, t.TransactionType, t.ReferenceID, t.ReasonCode, t.LotNumber, t.UOM, t.UOMQuantity,
+		t.UnitCost, t.TotalCost, t.CreatedAt,
+	).Error; err != nil {
+		return err
+	}
+	for _, sn := range t.SerialNumbers {
+		if err := db.Exec(
+			`INSERT INTO inventory_transaction_serials(transaction_id,sku_id,serial_number) VALUES(?,?,?)`,
+			t.ID, t.SKUID, sn,
+		).Error; err != nil {
+			return err
+		}
+	}
+	return outbox.Enqueue(db, t.TenantID, constants.EventInventoryTransactionCreated, t, t.CreatedAt)
+}
@@ -0,0 +1,212 @@
+package ledger
+
+import (
+	"math"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/abhirup.dandapat/ims/constants"
+	"github.com/abhirup.dandapat/ims/internal/models"
+)
+
+// costLayer is an open cost layer as read for update.
+type costLayer struct {
+	ID                string  `gorm:"column:id"`
+	UnitCost          float64 `gorm:"column:unit_cost"`
+	QuantityRemaining int64   `gorm:"column:quantity_remaining"`
+}
+
+func roundCost(v float64, places int) float64 {
+	p := math.Pow10(places)
+	return math.Round(v*p) / p
+}
+
+// movesOnHand reports whether rows of txType move quantity_on_hand and so
+// carry a cost. Rows of types outside PostedTypes, such as those sent to POST
+// /inventory/transactions, move no balance and are left uncosted.
+func movesOnHand(txType string) bool {
+	switch txType {
+	case constants.TransactionTypeReserve, constants.TransactionTypeRelease:
+		return false
+	}
+	for _, t := range PostedTypes {
+		if t == txType {
+			return true
+		}
+	}
+	return false
+}
+
+// consumeLayers draws qty units from layers, oldest first. It returns how much
+// each layer gives, the value drawn and the quantity the layers could not
+// cover.
+func consumeLayers(layers []costLayer, qty int64) ([]int64, float64, int64) {
+	taken := make([]int64, len(layers))
+	var value float64
+	for i, l := range layers {
+		if qty == 0 {
+			break
+		}
+		n := min(l.QuantityRemaining, qty)
+		taken[i] = n
+		value += float64(n) * l.UnitCost
+		qty -= n
+	}
+	return taken, value, qty
+}
+
+// averageCost is the unit cost of the stock in layers plus qty units at
+// unitCost.
+func averageCost(layers []costLayer, qty int64, unitCost float64) float64 {
+	total, value := qty, float64(qty)*unitCost
+	for _, l := range layers {
+		total += l.QuantityRemaining
+		value += float64(l.QuantityRemaining) * l.UnitCost
+	}
+	if total <= 0 {
+		return unitCost
+	}
+	return value / float64(total)
+}
+
+func openLayers(db *gorm.DB, hubID, skuID string) ([]costLayer, error) {
+	var layers []costLayer
+	err := db.Raw(
+		`SELECT id,unit_cost,quantity_remaining FROM inventory_cost_layers
+         WHERE hub_id = ? AND sku_id = ? AND quantity_remaining > 0
+         ORDER BY created_at, id
+         FOR UPDATE`,
+		hubID, skuID,
+	).Scan(&layers).Error
+	return layers, err
+}
+
+// currentCost is the unit cost stock of a hub/SKU would be valued at now: the
+// average of its open layers, or when none are left the last inbound cost.
+func currentCost(db *gorm.DB, hubID, skuID string) (float64, error) {
+	var cost *float64
+	if err := db.Raw(
+		`SELECT SUM(unit_cost * quantity_remaining) / NULLIF(SUM(quantity_remaining), 0)
+         FROM inventory_cost_layers WHERE hub_id = ? AND sku_id = ? AND quantity_remaining > 0`,
+		hubID, skuID,
+	).Scan(&cost).Error; err != nil {
+		return 0, err
+	}
+	if cost != nil {
+		return *cost, nil
+	}
+	if err := db.Raw(
+		`SELECT unit_cost FROM inventory_transactions
+         WHERE hub_id = ? AND sku_id = ? AND delta > 0 AND unit_cost IS NOT NULL
+         ORDER BY created_at DESC LIMIT 1`,
+		hubID, skuID,
+	).Scan(&cost).Error; err != nil {
+		return 0, err
+	}
+	if cost != nil {
+		return *cost, nil
+	}
+	return 0, nil
+}
+
+// inboundCost is the unit cost of an inbound row sent without one. Stock
+// coming back from a transfer keeps the cost it left the source hub at.
+func inboundCost(db *gorm.DB, t *models.InventoryTransaction) (float64, error) {
+	if t.TransactionType == constants.TransactionTypeTransferIn || t.TransactionType == constants.TransactionTypeTransferCancel {
+		var out struct {
+			Quantity int64   `gorm:"column:quantity"`
+			Value    float64 `gorm:"column:value"`
+		}
+		if err := db.Raw(
+			`SELECT COALESCE(-SUM(delta), 0) AS quantity, COALESCE(-SUM(total_cost), 0) AS value
+             FROM inventory_transactions
+             WHERE reference_id = ? AND sku_id = ? AND transaction_type = ?`,
+			t.ReferenceID, t.SKUID, constants.TransactionTypeTransferOut,
+		).Scan(&out).Error; err != nil {
+			return 0, err
+		}
+		if out.Quantity > 0 {
+			return out.Value / float64(out.Quantity), nil
+		}
+	}
+	return currentCost(db, t.HubID, t.SKUID)
+}
+
+// valueRow sets the cost of a row that moves on-hand stock and updates the
+// cost layers of its hub/SKU. Inbound rows open a layer at t.UnitCost, or at
+// the cost inboundCost finds when none is given; tenants on average costing
+// merge it into a single layer. Outbound rows draw from the oldest layers and
+// get the average unit cost of what they drew; stock issued beyond the layers
+// (negative inventory) is valued at the current cost.
+func valueRow(db *gorm.DB, t *models.InventoryTransaction) error {
+	if t.Delta == 0 || !movesOnHand(t.TransactionType) {
+		t.UnitCost, t.TotalCost = nil, nil
+		return nil
+	}
+
+	layers, err := openLayers(db, t.HubID, t.SKUID)
+	if err != nil {
+		return err
+	}
+
+	if t.Delta < 0 {
+		qty := -t.Delta
+		taken, value, short := consumeLayers(layers, qty)
+		for i, n := range taken {
+			if n == 0 {
+				continue
+			}
+			if err := db.Exec(
+				`UPDATE inventory_cost_layers SET quantity_remaining = quantity_remaining - ?, updated_at = ? WHERE id = ?`,
+				n, t.CreatedAt, layers[i].ID,
+			).Error; err != nil {
+				return err
+			}
+		}
+		if short > 0 {
+			cost, err := currentCost(db, t.HubID, t.SKUID)
+			if err != nil {
+				return err
+			}
+			value += float64(short) * cost
+		}
+		unitCost, totalCost := roundCost(value/float64(qty), 6), -roundCost(value, 4)
+		t.UnitCost, t.TotalCost = &unitCost, &totalCost
+		return nil
+	}
+
+	unitCost := 0.0
+	if t.UnitCost != nil {
+		unitCost = *t.UnitCost
+	} else if unitCost, err = inboundCost(db, t); err != nil {
+		return err
+	}
+	unitCost = roundCost(unitCost, 6)
+	totalCost := roundCost(float64(t.Delta)*unitCost, 4)
+	t.UnitCost, t.TotalCost = &unitCost, &totalCost
+
+	var method string
+	if err := db.Raw(`SELECT costing_method FROM tenants WHERE id = ?`, t.TenantID).Scan(&method).Error; err != nil {
+		return err
+	}
+	layerQty, layerCost := t.Delta, unitCost
+	if method == constants.CostingMethodAverage && len(layers) > 0 {
+		layerCost = roundCost(averageCost(layers, t.Delta, unitCost), 6)
+		for _, l := range layers {
+			layerQty += l.QuantityRemaining
+		}
+		if err := db.Exec(
+			`UPDATE inventory_cost_layers SET quantity_remaining = 0, updated_at = ?
+             WHERE hub_id = ? AND sku_id = ? AND quantity_remaining > 0`,
+			t.CreatedAt, t.HubID, t.SKUID,
+		).Error; err != nil {
+			return err
+		}
+	}
+	return db.Exec(
+		`INSERT INTO inventory_cost_layers(id,tenant_id,hub_id,sku_id,transaction_id,unit_cost,quantity_received,quantity_remaining,created_at,updated_at)
+         VALUES(?,?,?,?,?,?,?,?,?,?)`,
+		uuid.New().String(), t.TenantID, t.HubID, t.SKUID, t.ID, layerCost, layerQty, layerQty, t.CreatedAt, t.CreatedAt,
+	).Error
+}
@@ -0,0 +1,67 @@
+package ledger
+
+import (
+	"math"
+	"testing"
+)
+
+func TestConsumeLayers(t *testing.T) {
+	layers := []costLayer{
+		{ID: "old", UnitCost: 2, QuantityRemaining: 5},
+		{ID: "new", UnitCost: 3.5, QuantityRemaining: 10},
+	}
+	cases := []struct {
+		qty       int64
+		wantTaken []int64
+		wantValue float64
+		wantShort int64
+	}{
+		{qty: 3, wantTaken: []int64{3, 0}, wantValue: 6},
+		{qty: 7, wantTaken: []int64{5, 2}, wantValue: 17},
+		{qty: 20, wantTaken: []int64{5, 10}, wantValue: 45, wantShort: 5},
+	}
+	for _, tc := range cases {
+		taken, value, short := consumeLayers(layers, tc.qty)
+		if len(taken) != len(tc.wantTaken) || taken[0] != tc.wantTaken[0] || taken[1] != tc.wantTaken[1] {
+			t.Errorf("consumeLayers(%d) taken = %v, want %v", tc.qty, taken, tc.wantTaken)
+		}
+		if math.Abs(value-tc.wantValue) > 1e-9 || short != tc.wantShort {
+			t.Errorf("consumeLayers(%d) = %v, %d, want %v, %d", tc.qty, value, short, tc.wantValue, tc.wantShort)
+		}
+	}
+}
+
+func TestAverageCost(t *testing.T) {
+	cases := []struct {
+		layers   []costLayer
+		qty      int64
+		unitCost float64
+		want     float64
+	}{
+		{qty: 4, unitCost: 2.5, want: 2.5},
+		{layers: []costLayer{{UnitCost: 2, QuantityRemaining: 10}}, qty: 10, unitCost: 4, want: 3},
+		{layers: []costLayer{{UnitCost: 1, QuantityRemaining: 1}, {UnitCost: 3, QuantityRemaining: 1}}, qty: 2, unitCost: 5, want: 3.5},
+	}
+	for _, tc := range cases {
+		if got := averageCost(tc.layers, tc.qty, tc.unitCost); math.Abs(got-tc.want) > 1e-9 {
+			t.Errorf("averageCost(%v, %d, %v) = %v, want %v", tc.layers, tc.qty, tc.unitCost, got, tc.want)
+		}
+	}
+}
+
+func TestMovesOnHand(t *testing.T) {
+	cases := map[string]bool{
+		"upsert":       true,
+		"commit":       true,
+		"transfer_out": true,
+		"reserve":      false,
+		"release":      false,
+		"reservation":  false,
+		"manual_fix":   false,
+	}
+	for txType, want := range cases {
+		if got := movesOnHand(txType); got != want {
+			t.Errorf("movesOnHand(%q) = %v, want %v", txType, got, want)
+		}
+	}
+}
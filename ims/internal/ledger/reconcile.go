@@ -0,0 +1,190 @@
+package ledger
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/omniful/go_commons/db/sql/postgres"
+	"gorm.io/gorm"
+
+	"github.com/abhirup.dandapat/ims/constants"
+	"github.com/abhirup.dandapat/ims/internal/models"
+)
+
+// PostedTypes are the transaction types IMS writes together with the balance
+// change they describe. Ledger rows of any other type (for example those sent
+// to POST /inventory/transactions) move the ledger sum but not the balance.
+var PostedTypes = []string{
+	constants.TransactionTypeUpsert,
+	constants.TransactionTypeAdjustment,
+	constants.TransactionTypeReserve,
+	constants.TransactionTypeCommit,
+	constants.TransactionTypeRelease,
+	constants.TransactionTypeReceipt,
+	constants.TransactionTypeTransferOut,
+	constants.TransactionTypeTransferIn,
+	constants.TransactionTypeTransferCancel,
+}
+
+type ReconcileOptions struct {
+	TenantID string
+	HubID    string
+	// Fix posts an adjustment row with ReasonCode for each on-hand mismatch
+	// so the ledger sums to the balance again. Reserved mismatches are only
+	// reported: they point at a reservation bug, not at a missing row.
+	Fix        bool
+	ReasonCode string
+}
+
+type hubRef struct {
+	ID       string `gorm:"column:id"`
+	TenantID string `gorm:"column:tenant_id"`
+}
+
+// Reconcile compares every inventory row of the selected hubs with the sum of
+// its ledger rows and reports the hub/SKUs that disagree.
+func Reconcile(ctx context.Context, db *postgres.DbCluster, opts ReconcileOptions) (models.ReconcileReport, error) {
+	report := models.ReconcileReport{Mismatches: []models.LedgerMismatch{}}
+	read := db.GetSlaveDB(ctx)
+
+	where := []string{"1=1"}
+	args := []interface{}{}
+	if opts.TenantID != "" {
+		where = append(where, "tenant_id = ?")
+		args = append(args, opts.TenantID)
+	}
+	if opts.HubID != "" {
+		where = append(where, "id = ?")
+		args = append(args, opts.HubID)
+	}
+	var hubs []hubRef
+	if err := read.Raw(
+		`SELECT id,tenant_id FROM hubs WHERE `+strings.Join(where, " AND ")+` ORDER BY id`, args...,
+	).Scan(&hubs).Error; err != nil {
+		return report, err
+	}
+
+	for _, hub := range hubs {
+		mismatches, err := reconcileHub(read, hub)
+		if err != nil {
+			return report, fmt.Errorf("reconcile hub %s: %w", hub.ID, err)
+		}
+		report.HubsScanned++
+
+		for i := range mismatches {
+			m := &mismatches[i]
+			if !opts.Fix || m.QuantityOnHand == m.LedgerOnHand {
+				continue
+			}
+			corrected, err := correctOnHand(db.GetMasterDB(ctx), *m, opts.ReasonCode, time.Now().UTC())
+			if err != nil {
+				return report, fmt.Errorf("correct hub %s sku %s: %w", m.HubID, m.SKUID, err)
+			}
+			if corrected {
+				m.Corrected = true
+				report.Corrected++
+			}
+		}
+		report.Mismatches = append(report.Mismatches, mismatches...)
+	}
+	return report, nil
+}
+
+func reconcileHub(db *gorm.DB, hub hubRef) ([]models.LedgerMismatch, error) {
+	var mismatches []models.LedgerMismatch
+	if err := db.Raw(fmt.Sprintf(
+		`WITH ledger AS (
+             SELECT sku_id, SUM(%s) AS on_hand, SUM(%s) AS reserved
+             FROM inventory_transactions WHERE hub_id = ?
+             GROUP BY sku_id
+         ), balance AS (
+             SELECT sku_id, quantity_on_hand, quantity_reserved FROM inventory WHERE hub_id = ?
+         )
+         SELECT COALESCE(b.sku_id, l.sku_id) AS sku_id,
+                COALESCE(b.quantity_on_hand, 0) AS quantity_on_hand,
+                COALESCE(l.on_hand, 0) AS ledger_on_hand,
+                COALESCE(b.quantity_reserved, 0) AS quantity_reserved,
+                COALESCE(l.reserved, 0) AS ledger_reserved
+         FROM balance b FULL OUTER JOIN ledger l ON l.sku_id = b.sku_id
+         WHERE COALESCE(b.quantity_on_hand, 0) <> COALESCE(l.on_hand, 0)
+            OR COALESCE(b.quantity_reserved, 0) <> COALESCE(l.reserved, 0)
+         ORDER BY 1`,
+		OnHandDelta, ReservedDelta,
+	), hub.ID, hub.ID).Scan(&mismatches).Error; err != nil {
+		return nil, err
+	}
+
+	typeFilter := strings.TrimSuffix(strings.Repeat("?,", len(PostedTypes)+1), ",")
+	for i := range mismatches {
+		m := &mismatches[i]
+		m.TenantID, m.HubID = hub.TenantID, hub.ID
+
+		args := []interface{}{hub.ID, m.SKUID, constants.TransactionTypeLegacyReservation}
+		for _, t := range PostedTypes {
+			args = append(args, t)
+		}
+		if err := db.Raw(
+			`SELECT id,tenant_id,hub_id,sku_id,delta,transaction_type,reference_id,reason_code,lot_number,uom,uom_quantity,unit_cost,total_cost,created_at
+             FROM inventory_transactions
+             WHERE hub_id = ? AND sku_id = ? AND transaction_type NOT IN (`+typeFilter+`)
+             ORDER BY created_at`,
+			args...,
+		).Scan(&m.Transactions).Error; err != nil {
+			return nil, err
+		}
+	}
+	return mismatches, nil
+}
+
+// correctOnHand posts an adjustment row bringing the ledger's on-hand sum back
+// to quantity_on_hand. The balance row is locked and the gap recomputed inside
+// the transaction, so a write racing the scan cannot be double counted.
+func correctOnHand(db *gorm.DB, m models.LedgerMismatch, reasonCode string, now time.Time) (bool, error) {
+	tx := db.Begin()
+	if tx.Error != nil {
+		return false, tx.Error
+	}
+	defer tx.Rollback()
+
+	if err := tx.Exec(
+		`INSERT INTO inventory(hub_id,sku_id,updated_at) VALUES(?,?,?)
+         ON CONFLICT (hub_id,sku_id) DO NOTHING`,
+		m.HubID, m.SKUID, now,
+	).Error; err != nil {
+		return false, err
+	}
+	var onHand int64
+	if err := tx.Raw(
+		`SELECT quantity_on_hand FROM inventory WHERE hub_id = ? AND sku_id = ? FOR UPDATE`,
+		m.HubID, m.SKUID,
+	).Scan(&onHand).Error; err != nil {
+		return false, err
+	}
+	var ledgerOnHand int64
+	if err := tx.Raw(
+		fmt.Sprintf(`SELECT COALESCE(SUM(%s), 0) FROM inventory_transactions WHERE hub_id = ? AND sku_id = ?`, OnHandDelta),
+		m.HubID, m.SKUID,
+	).Scan(&ledgerOnHand).Error; err != nil {
+		return false, err
+	}
+	if onHand == ledgerOnHand {
+		return false, nil
+	}
+
+	if err := Append(tx, models.InventoryTransaction{
+		ID:              uuid.New().String(),
+		TenantID:        m.TenantID,
+		HubID:           m.HubID,
+		SKUID:           m.SKUID,
+		Delta:           onHand - ledgerOnHand,
+		TransactionType: constants.TransactionTypeAdjustment,
+		ReasonCode:      reasonCode,
+		CreatedAt:       now,
+	}); err != nil {
+		return false, err
+	}
+	return true, tx.Commit().Error
+}
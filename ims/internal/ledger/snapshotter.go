@@ -0,0 +1,49 @@
+package ledger
+
+import (
+	"context"
+	"time"
+
+	"github.com/omniful/go_commons/db/sql/postgres"
+	"github.com/omniful/go_commons/log"
+)
+
+// Snapshotter takes a ledger snapshot once per interval. Cutoffs are aligned to
+// the interval and trail the clock by lag, so ledger rows stamped before a
+// cutoff but still in an open transaction have committed when it is taken.
+type Snapshotter struct {
+	db       *postgres.DbCluster
+	interval time.Duration
+	lag      time.Duration
+	logger   *log.Logger
+}
+
+func NewSnapshotter(db *postgres.DbCluster, interval, lag time.Duration) *Snapshotter {
+	return &Snapshotter{
+		db:       db,
+		interval: interval,
+		lag:      lag,
+		logger:   log.DefaultLogger(),
+	}
+}
+
+// Run snapshots the latest due cutoff, then checks again every interval until
+// ctx is done.
+func (s *Snapshotter) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		cutoff := time.Now().UTC().Add(-s.lag).Truncate(s.interval)
+		n, err := TakeSnapshot(s.db.GetMasterDB(ctx), cutoff)
+		if err != nil {
+			s.logger.Errorf("inventory snapshot at %s: %v", cutoff.Format(time.RFC3339), err)
+		} else {
+			s.logger.Infof("inventory snapshot at %s: %d rows", cutoff.Format(time.RFC3339), n)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package ledger
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/abhirup.dandapat/ims/constants"
+	"github.com/abhirup.dandapat/ims/internal/models"
+)
+
+type ValuationOptions struct {
+	TenantID string
+	HubID    string
+	SKUIDs   []string
+	From     time.Time
+	To       time.Time
+}
+
+// Valuation sums the quantity and cost of a tenant's ledger rows per hub/SKU:
+// the balance before From and before To, what came in between, what left as
+// commits (COGS) and what left any other way. Rows posted before costing was
+// in place carry no cost and count at zero value.
+func Valuation(db *gorm.DB, opts ValuationOptions) ([]models.InventoryValuation, error) {
+	var hubIDs []string
+	if opts.HubID != "" {
+		hubIDs = []string{opts.HubID}
+	}
+	hubFilter, hubArgs := inFilter("hub_id", hubIDs)
+	skuFilter, skuArgs := inFilter("sku_id", opts.SKUIDs)
+
+	sqlStr := fmt.Sprintf(
+		`SELECT hub_id, sku_id,
+                SUM(CASE WHEN created_at < ? THEN %[1]s ELSE 0 END) AS opening_quantity,
+                SUM(CASE WHEN created_at < ? THEN COALESCE(total_cost, 0) ELSE 0 END) AS opening_value,
+                SUM(CASE WHEN created_at >= ? AND %[1]s > 0 THEN %[1]s ELSE 0 END) AS inbound_quantity,
+                SUM(CASE WHEN created_at >= ? AND %[1]s > 0 THEN COALESCE(total_cost, 0) ELSE 0 END) AS inbound_value,
+                SUM(CASE WHEN created_at >= ? AND transaction_type = '%[2]s' THEN -delta ELSE 0 END) AS cogs_quantity,
+                SUM(CASE WHEN created_at >= ? AND transaction_type = '%[2]s' THEN -COALESCE(total_cost, 0) ELSE 0 END) AS cogs,
+                SUM(CASE WHEN created_at >= ? AND %[1]s < 0 AND transaction_type <> '%[2]s' THEN -(%[1]s) ELSE 0 END) AS other_outbound_quantity,
+                SUM(CASE WHEN created_at >= ? AND %[1]s < 0 AND transaction_type <> '%[2]s' THEN -COALESCE(total_cost, 0) ELSE 0 END) AS other_outbound_value,
+                SUM(%[1]s) AS closing_quantity,
+                SUM(COALESCE(total_cost, 0)) AS closing_value
+         FROM inventory_transactions
+         WHERE tenant_id = ? AND created_at < ?%[3]s%[4]s
+         GROUP BY hub_id, sku_id
+         HAVING SUM(%[1]s) <> 0 OR SUM(CASE WHEN created_at >= ? THEN ABS(%[1]s) ELSE 0 END) > 0
+         ORDER BY hub_id, sku_id`,
+		OnHandDelta, constants.TransactionTypeCommit, hubFilter, skuFilter,
+	)
+
+	args := make([]interface{}, 0, 11+len(hubArgs)+len(skuArgs))
+	for i := 0; i < 8; i++ {
+		args = append(args, opts.From)
+	}
+	args = append(args, opts.TenantID, opts.To)
+	args = append(args, hubArgs...)
+	args = append(args, skuArgs...)
+	args = append(args, opts.From)
+
+	var rows []models.InventoryValuation
+	if err := db.Raw(sqlStr, args...).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// CatalogImport is a bulk SKU import job. The counters are filled in when
+// the import completes; the uploaded rows and the error report are read
+// separately.
+type CatalogImport struct {
+	ID          string     `json:"id"                    gorm:"column:id"`
+	TenantID    string     `json:"tenant_id"             gorm:"column:tenant_id"`
+	Format      string     `json:"format"                gorm:"column:format"`
+	FileName    *string    `json:"file_name,omitempty"   gorm:"column:file_name"`
+	Status      string     `json:"status"                gorm:"column:status"`
+	TotalRows   int        `json:"total_rows"            gorm:"column:total_rows"`
+	CreatedRows int        `json:"created_rows"          gorm:"column:created_rows"`
+	UpdatedRows int        `json:"updated_rows"          gorm:"column:updated_rows"`
+	FailedRows  int        `json:"failed_rows"           gorm:"column:failed_rows"`
+	Attempts    int        `json:"attempts"              gorm:"column:attempts"`
+	LastError   *string    `json:"last_error,omitempty"  gorm:"column:last_error"`
+	CreatedAt   time.Time  `json:"created_at"            gorm:"column:created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"            gorm:"column:updated_at"`
+	StartedAt   *time.Time `json:"started_at,omitempty"  gorm:"column:started_at"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty" gorm:"column:finished_at"`
+}
@@ -3,10 +3,18 @@ package models
 import "time"
 
 type Category struct {
-	ID          string    `db:"id"          json:"id"`
-	TenantID    string    `db:"tenant_id"   json:"tenant_id"`
-	Name        string    `db:"name"        json:"name"`
-	Description string    `db:"description" json:"description,omitempty"`
-	CreatedAt   time.Time `db:"created_at"  json:"created_at"`
-	UpdatedAt   time.Time `db:"updated_at"  json:"updated_at"`
+	ID          string     `db:"id"          json:"id"`
+	TenantID    string     `db:"tenant_id"   json:"tenant_id"`
+	ParentID    *string    `db:"parent_id"   json:"parent_id,omitempty"`
+	Name        string     `db:"name"        json:"name"`
+	Description string     `db:"description" json:"description,omitempty"`
+	CreatedAt   time.Time  `db:"created_at"  json:"created_at"`
+	UpdatedAt   time.Time  `db:"updated_at"  json:"updated_at"`
+	DeletedAt   *time.Time `db:"deleted_at"  json:"deleted_at,omitempty"`
+}
+
+// CategoryNode is a category with the subtree below it.
+type CategoryNode struct {
+	Category
+	Children []CategoryNode `json:"children"`
 }
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// HubLocation is a zone, aisle or bin inside a hub. Zones have no parent,
+// aisles sit in a zone and bins in an aisle.
+type HubLocation struct {
+	ID        string    `json:"id"                  gorm:"column:id"`
+	HubID     string    `json:"hub_id"              gorm:"column:hub_id"`
+	ParentID  *string   `json:"parent_id,omitempty" gorm:"column:parent_id"`
+	Type      string    `json:"type"                gorm:"column:location_type"`
+	Code      string    `json:"code"                gorm:"column:code"`
+	CreatedAt time.Time `json:"created_at"          gorm:"column:created_at"`
+}
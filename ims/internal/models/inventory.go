@@ -3,11 +3,26 @@ package models
 import "time"
 
 type Inventory struct {
-	HubID            string    `json:"hub_id"            gorm:"column:hub_id"`
-	SKUID            string    `json:"sku_id"            gorm:"column:sku_id"`
-	QuantityOnHand   int64     `json:"quantity_on_hand"  gorm:"column:quantity_on_hand"`
-	QuantityReserved int64     `json:"quantity_reserved" gorm:"column:quantity_reserved"`
-	MinThreshold     int64     `json:"min_threshold"     gorm:"column:min_threshold"`
-	MaxThreshold     int64     `json:"max_threshold"     gorm:"column:max_threshold"`
-	UpdatedAt        time.Time `json:"updated_at"        gorm:"column:updated_at"`
+	HubID             string    `json:"hub_id"              gorm:"column:hub_id"`
+	SKUID             string    `json:"sku_id"              gorm:"column:sku_id"`
+	QuantityOnHand    int64     `json:"quantity_on_hand"    gorm:"column:quantity_on_hand"`
+	QuantityReserved  int64     `json:"quantity_reserved"   gorm:"column:quantity_reserved"`
+	QuantityInTransit int64     `json:"quantity_in_transit" gorm:"column:quantity_in_transit"`
+	MinThreshold      int64     `json:"min_threshold"       gorm:"column:min_threshold"`
+	MaxThreshold      int64     `json:"max_threshold"       gorm:"column:max_threshold"`
+	SafetyStock       int64     `json:"safety_stock"        gorm:"column:safety_stock"`
+	Version           int64     `json:"version"             gorm:"column:version"`
+	UpdatedAt         time.Time `json:"updated_at"          gorm:"column:updated_at"`
+
+	// Set by GET /inventory only.
+	HubCode *string `json:"hub_code,omitempty" gorm:"column:hub_code"`
+	SKUCode string  `json:"sku_code,omitempty" gorm:"column:sku_code"`
+
+	Lots []InventoryLot `json:"lots,omitempty" gorm:"-"`
+
+	// Kit rows only: the stock of each component. A kit row is derived and
+	// never stored; its quantity_on_hand is the number of whole kits the
+	// components make up.
+	IsKit      bool                    `json:"is_kit,omitempty"     gorm:"-"`
+	Components []InventoryKitComponent `json:"components,omitempty" gorm:"-"`
 }
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+type InventoryAlert struct {
+	ID         string     `db:"id"          json:"id"`
+	TenantID   string     `db:"tenant_id"   json:"tenant_id"`
+	HubID      string     `db:"hub_id"      json:"hub_id"`
+	SKUID      string     `db:"sku_id"      json:"sku_id"`
+	AlertType  string     `db:"alert_type"  json:"alert_type"`
+	Quantity   int64      `db:"quantity"    json:"quantity"`
+	Threshold  int64      `db:"threshold"   json:"threshold"`
+	Status     string     `db:"status"      json:"status"`
+	CreatedAt  time.Time  `db:"created_at"  json:"created_at"`
+	UpdatedAt  time.Time  `db:"updated_at"  json:"updated_at"`
+	ResolvedAt *time.Time `db:"resolved_at" json:"resolved_at,omitempty"`
+}
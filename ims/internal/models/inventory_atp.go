@@ -0,0 +1,19 @@
+package models
+
+// InventoryATPHub is what one hub can promise of a SKU. For a kit only
+// Available is set, in kits the hub's components make up.
+type InventoryATPHub struct {
+	HubID            string `json:"hub_id"`
+	QuantityOnHand   int64  `json:"quantity_on_hand"`
+	QuantityReserved int64  `json:"quantity_reserved"`
+	SafetyStock      int64  `json:"safety_stock"`
+	Available        int64  `json:"available"`
+}
+
+// InventoryATP is the available-to-promise of a SKU across a tenant's hubs.
+type InventoryATP struct {
+	SKUID     string            `json:"sku_id"`
+	IsKit     bool              `json:"is_kit,omitempty"`
+	Available int64             `json:"available"`
+	Hubs      []InventoryATPHub `json:"hubs"`
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// InventoryBalance is a hub/SKU balance rebuilt from inventory_transactions as
+// of a point in time. SnapshotAt is the snapshot the rebuild started from, if
+// any.
+type InventoryBalance struct {
+	HubID            string     `json:"hub_id"                gorm:"column:hub_id"`
+	SKUID            string     `json:"sku_id"                gorm:"column:sku_id"`
+	QuantityOnHand   int64      `json:"quantity_on_hand"      gorm:"column:quantity_on_hand"`
+	QuantityReserved int64      `json:"quantity_reserved"     gorm:"column:quantity_reserved"`
+	AsOf             time.Time  `json:"as_of"                 gorm:"-"`
+	SnapshotAt       *time.Time `json:"snapshot_at,omitempty" gorm:"column:snapshot_at"`
+}
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// InventoryBin is the quantity of a SKU held in one bin.
+type InventoryBin struct {
+	LocationID string    `json:"location_id" gorm:"column:location_id"`
+	Code       string    `json:"code"        gorm:"column:code"`
+	HubID      string    `json:"hub_id"      gorm:"column:hub_id"`
+	SKUID      string    `json:"sku_id"      gorm:"column:sku_id"`
+	Quantity   int64     `json:"quantity"    gorm:"column:quantity"`
+	UpdatedAt  time.Time `json:"updated_at"  gorm:"column:updated_at"`
+}
+
+// InventoryBinMove records stock moving into, out of or between bins. A nil
+// side is stock outside any bin.
+type InventoryBinMove struct {
+	ID             string    `json:"id"                         gorm:"column:id"`
+	TenantID       string    `json:"tenant_id"                  gorm:"column:tenant_id"`
+	HubID          string    `json:"hub_id"                     gorm:"column:hub_id"`
+	SKUID          string    `json:"sku_id"                     gorm:"column:sku_id"`
+	FromLocationID *string   `json:"from_location_id,omitempty" gorm:"column:from_location_id"`
+	ToLocationID   *string   `json:"to_location_id,omitempty"   gorm:"column:to_location_id"`
+	Quantity       int64     `json:"quantity"                   gorm:"column:quantity"`
+	ReferenceID    string    `json:"reference_id,omitempty"     gorm:"column:reference_id"`
+	CreatedAt      time.Time `json:"created_at"                 gorm:"column:created_at"`
+}
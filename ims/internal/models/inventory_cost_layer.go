@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// InventoryCostLayer is stock of a hub/SKU still on hand at the unit cost it
+// was received at.
+type InventoryCostLayer struct {
+	ID                string    `json:"id"                 gorm:"column:id"`
+	TenantID          string    `json:"tenant_id"          gorm:"column:tenant_id"`
+	HubID             string    `json:"hub_id"             gorm:"column:hub_id"`
+	SKUID             string    `json:"sku_id"             gorm:"column:sku_id"`
+	TransactionID     string    `json:"transaction_id"     gorm:"column:transaction_id"`
+	UnitCost          float64   `json:"unit_cost"          gorm:"column:unit_cost"`
+	QuantityReceived  int64     `json:"quantity_received"  gorm:"column:quantity_received"`
+	QuantityRemaining int64     `json:"quantity_remaining" gorm:"column:quantity_remaining"`
+	CreatedAt         time.Time `json:"created_at"         gorm:"column:created_at"`
+	UpdatedAt         time.Time `json:"updated_at"         gorm:"column:updated_at"`
+}
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+type InventoryCount struct {
+	ID         string               `db:"id"          json:"id"`
+	TenantID   string               `db:"tenant_id"   json:"tenant_id"`
+	HubID      string               `db:"hub_id"      json:"hub_id"`
+	Freeze     bool                 `db:"freeze"      json:"freeze"`
+	Status     string               `db:"status"      json:"status"`
+	Lines      []InventoryCountLine `gorm:"-"         json:"lines,omitempty"`
+	CreatedAt  time.Time            `db:"created_at"  json:"created_at"`
+	UpdatedAt  time.Time            `db:"updated_at"  json:"updated_at"`
+	ApprovedAt *time.Time           `db:"approved_at" json:"approved_at,omitempty"`
+}
+
+// InventoryCountLine is a counted SKU. ExpectedQuantity is quantity_on_hand
+// when the count was recorded, so Variance ignores later movements.
+type InventoryCountLine struct {
+	SKUID            string    `json:"sku_id"            gorm:"column:sku_id"`
+	CountedQuantity  int64     `json:"counted_quantity"  gorm:"column:counted_quantity"`
+	ExpectedQuantity int64     `json:"expected_quantity" gorm:"column:expected_quantity"`
+	Variance         int64     `json:"variance"          gorm:"column:variance"`
+	CountedAt        time.Time `json:"counted_at"        gorm:"column:counted_at"`
+}
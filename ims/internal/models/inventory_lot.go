@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// InventoryLot is the part of a hub/SKU's stock that belongs to one lot. Stock
+// not received into a lot stays on the inventory row only.
+type InventoryLot struct {
+	HubID            string     `json:"hub_id"                    gorm:"column:hub_id"`
+	SKUID            string     `json:"sku_id"                    gorm:"column:sku_id"`
+	LotNumber        string     `json:"lot_number"                gorm:"column:lot_number"`
+	ManufacturedAt   *time.Time `json:"manufactured_at,omitempty" gorm:"column:manufactured_at"`
+	ExpiresAt        *time.Time `json:"expires_at,omitempty"      gorm:"column:expires_at"`
+	QuantityOnHand   int64      `json:"quantity_on_hand"          gorm:"column:quantity_on_hand"`
+	QuantityReserved int64      `json:"quantity_reserved"         gorm:"column:quantity_reserved"`
+	CreatedAt        time.Time  `json:"created_at"                gorm:"column:created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"                gorm:"column:updated_at"`
+}
+
+// Expired reports whether the lot is past its expiry date on day.
+func (l InventoryLot) Expired(day time.Time) bool {
+	return l.ExpiresAt != nil && l.ExpiresAt.Before(day)
+}
+
+// InventoryReservationLot is the share of a reservation taken from one lot; an
+// empty LotNumber is stock outside any lot.
+type InventoryReservationLot struct {
+	LotNumber string `json:"lot_number" gorm:"column:lot_number"`
+	Quantity  int64  `json:"quantity"   gorm:"column:quantity"`
+}
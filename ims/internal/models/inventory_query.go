@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// InventoryQueryRow is a hub/SKU row of the v2 inventory query. Rows filled in
+// for a requested SKU with no stock record at the hub are all zero and have no
+// updated_at.
+type InventoryQueryRow struct {
+	HubID             string     `json:"hub_id"              gorm:"column:hub_id"`
+	SKUID             string     `json:"sku_id"              gorm:"column:sku_id"`
+	HubCode           *string    `json:"hub_code,omitempty"  gorm:"column:hub_code"`
+	SKUCode           string     `json:"sku_code"            gorm:"column:sku_code"`
+	QuantityOnHand    int64      `json:"quantity_on_hand"    gorm:"column:quantity_on_hand"`
+	QuantityReserved  int64      `json:"quantity_reserved"   gorm:"column:quantity_reserved"`
+	QuantityInTransit int64      `json:"quantity_in_transit" gorm:"column:quantity_in_transit"`
+	MinThreshold      int64      `json:"min_threshold"       gorm:"column:min_threshold"`
+	MaxThreshold      int64      `json:"max_threshold"       gorm:"column:max_threshold"`
+	SafetyStock       int64      `json:"safety_stock"        gorm:"column:safety_stock"`
+	Version           int64      `json:"version"             gorm:"column:version"`
+	UpdatedAt         *time.Time `json:"updated_at"          gorm:"column:updated_at"`
+}
+
+// InventoryQueryPage is one page of the v2 inventory query. NextCursor is
+// empty on the last page.
+type InventoryQueryPage struct {
+	Items      []InventoryQueryRow `json:"items"`
+	NextCursor string              `json:"next_cursor,omitempty"`
+}
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+type InventoryReservation struct {
+	ID          string    `db:"id"           json:"id"`
+	TenantID    string    `db:"tenant_id"    json:"tenant_id"`
+	HubID       string    `db:"hub_id"       json:"hub_id"`
+	SKUID       string    `db:"sku_id"       json:"sku_id"`
+	ReferenceID string    `db:"reference_id" json:"reference_id"`
+	Quantity    int64     `db:"quantity"     json:"quantity"`
+	UOM         string    `db:"uom"          json:"uom,omitempty"`
+	UOMQuantity int64     `db:"uom_quantity" json:"uom_quantity,omitempty"`
+	Status      string    `db:"status"       json:"status"`
+	ParentID    *string   `db:"parent_id"    json:"parent_id,omitempty"`
+	CreatedAt   time.Time `db:"created_at"   json:"created_at"`
+	UpdatedAt   time.Time `db:"updated_at"   json:"updated_at"`
+
+	Allocations []InventoryReservationLot `gorm:"-" json:"allocations,omitempty"`
+	// Components are the reservations of a kit's components.
+	Components []InventoryReservation `gorm:"-" json:"components,omitempty"`
+}
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// InventorySerial is one unit of a serialized SKU. History holds the ledger
+// rows that moved the unit, oldest first, and is only filled on lookup.
+type InventorySerial struct {
+	SKUID        string                 `json:"sku_id"            gorm:"column:sku_id"`
+	SerialNumber string                 `json:"serial_number"     gorm:"column:serial_number"`
+	TenantID     string                 `json:"tenant_id"         gorm:"column:tenant_id"`
+	HubID        string                 `json:"hub_id"            gorm:"column:hub_id"`
+	Status       string                 `json:"status"            gorm:"column:status"`
+	CreatedAt    time.Time              `json:"created_at"        gorm:"column:created_at"`
+	UpdatedAt    time.Time              `json:"updated_at"        gorm:"column:updated_at"`
+	History      []InventoryTransaction `json:"history,omitempty" gorm:"-"`
+}
@@ -3,12 +3,19 @@ package models
 import "time"
 
 type InventoryTransaction struct {
-	ID              string    `db:"id"              json:"id"`
-	TenantID        string    `db:"tenant_id"       json:"tenant_id"`
-	HubID           string    `db:"hub_id"          json:"hub_id"`
-	SKUID           string    `db:"sku_id"          json:"sku_id"`
-	Delta           int64     `db:"delta"           json:"delta"`
-	TransactionType string    `db:"transaction_type"json:"transaction_type"`
-	ReferenceID     string    `db:"reference_id"    json:"reference_id,omitempty"`
-	CreatedAt       time.Time `db:"created_at"      json:"created_at"`
+	ID              string    `db:"id"               json:"id"`
+	TenantID        string    `db:"tenant_id"        json:"tenant_id"`
+	HubID           string    `db:"hub_id"           json:"hub_id"`
+	SKUID           string    `db:"sku_id"           json:"sku_id"`
+	Delta           int64     `db:"delta"            json:"delta"`
+	TransactionType string    `db:"transaction_type" json:"transaction_type"`
+	ReferenceID     string    `db:"reference_id"     json:"reference_id,omitempty"`
+	ReasonCode      string    `db:"reason_code"      json:"reason_code,omitempty"`
+	LotNumber       string    `db:"lot_number"       json:"lot_number,omitempty"`
+	UOM             string    `db:"uom"              json:"uom,omitempty"`
+	UOMQuantity     int64     `db:"uom_quantity"     json:"uom_quantity,omitempty"`
+	UnitCost        *float64  `db:"unit_cost"        json:"unit_cost,omitempty"`
+	TotalCost       *float64  `db:"total_cost"       json:"total_cost,omitempty"`
+	CreatedAt       time.Time `db:"created_at"       json:"created_at"`
+	SerialNumbers   []string  `json:"serial_numbers,omitempty" gorm:"-"`
 }
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+type InventoryTransfer struct {
+	ID               string                  `db:"id"                 json:"id"`
+	TenantID         string                  `db:"tenant_id"          json:"tenant_id"`
+	SourceHubID      string                  `db:"source_hub_id"      json:"source_hub_id"`
+	DestinationHubID string                  `db:"destination_hub_id" json:"destination_hub_id"`
+	ReferenceID      string                  `db:"reference_id"       json:"reference_id,omitempty"`
+	Status           string                  `db:"status"             json:"status"`
+	Items            []InventoryTransferItem `gorm:"-"                json:"items,omitempty"`
+	CreatedAt        time.Time               `db:"created_at"         json:"created_at"`
+	UpdatedAt        time.Time               `db:"updated_at"         json:"updated_at"`
+}
+
+type InventoryTransferItem struct {
+	SKUID             string `json:"sku_id"             gorm:"column:sku_id"`
+	Quantity          int64  `json:"quantity"           gorm:"column:quantity"`
+	QuantityReceived  int64  `json:"quantity_received"  gorm:"column:quantity_received"`
+	QuantityCancelled int64  `json:"quantity_cancelled" gorm:"column:quantity_cancelled"`
+}
+
+// Outstanding is the quantity still in transit.
+func (i InventoryTransferItem) Outstanding() int64 {
+	return i.Quantity - i.QuantityReceived - i.QuantityCancelled
+}
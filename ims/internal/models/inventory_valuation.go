@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// InventoryValuation is the movement of one hub/SKU's stock and its value
+// over a period, summed from inventory_transactions. Quantities are in base
+// units; outbound quantities and values are positive.
+type InventoryValuation struct {
+	HubID                 string  `json:"hub_id"                   gorm:"column:hub_id"`
+	SKUID                 string  `json:"sku_id"                   gorm:"column:sku_id"`
+	OpeningQuantity       int64   `json:"opening_quantity"         gorm:"column:opening_quantity"`
+	OpeningValue          float64 `json:"opening_value"            gorm:"column:opening_value"`
+	InboundQuantity       int64   `json:"inbound_quantity"         gorm:"column:inbound_quantity"`
+	InboundValue          float64 `json:"inbound_value"            gorm:"column:inbound_value"`
+	COGSQuantity          int64   `json:"cogs_quantity"            gorm:"column:cogs_quantity"`
+	COGS                  float64 `json:"cogs"                     gorm:"column:cogs"`
+	OtherOutboundQuantity int64   `json:"other_outbound_quantity"  gorm:"column:other_outbound_quantity"`
+	OtherOutboundValue    float64 `json:"other_outbound_value"     gorm:"column:other_outbound_value"`
+	ClosingQuantity       int64   `json:"closing_quantity"         gorm:"column:closing_quantity"`
+	ClosingValue          float64 `json:"closing_value"            gorm:"column:closing_value"`
+}
+
+// InventoryValuationReport is the valuation of a tenant's stock between From
+// (inclusive) and To (exclusive).
+type InventoryValuationReport struct {
+	TenantID      string               `json:"tenant_id"`
+	CostingMethod string               `json:"costing_method"`
+	From          time.Time            `json:"from"`
+	To            time.Time            `json:"to"`
+	Rows          []InventoryValuation `json:"rows"`
+	Totals        InventoryValuation   `json:"totals"`
+}
@@ -0,0 +1,22 @@
+package models
+
+// LedgerMismatch is a hub/SKU whose inventory row disagrees with the sum of its
+// inventory_transactions. Transactions lists the ledger rows that were not
+// posted by an IMS balance change and so likely explain the drift.
+type LedgerMismatch struct {
+	TenantID         string                 `json:"tenant_id"         gorm:"-"`
+	HubID            string                 `json:"hub_id"            gorm:"-"`
+	SKUID            string                 `json:"sku_id"            gorm:"column:sku_id"`
+	QuantityOnHand   int64                  `json:"quantity_on_hand"  gorm:"column:quantity_on_hand"`
+	LedgerOnHand     int64                  `json:"ledger_on_hand"    gorm:"column:ledger_on_hand"`
+	QuantityReserved int64                  `json:"quantity_reserved" gorm:"column:quantity_reserved"`
+	LedgerReserved   int64                  `json:"ledger_reserved"   gorm:"column:ledger_reserved"`
+	Transactions     []InventoryTransaction `json:"transactions"      gorm:"-"`
+	Corrected        bool                   `json:"corrected"         gorm:"-"`
+}
+
+type ReconcileReport struct {
+	HubsScanned int              `json:"hubs_scanned"`
+	Mismatches  []LedgerMismatch `json:"mismatches"`
+	Corrected   int              `json:"corrected"`
+}
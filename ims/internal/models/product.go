@@ -0,0 +1,83 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Product groups the SKUs that are variants of one item. Variants inherit the
+// product's tenant, seller and category.
+type Product struct {
+	ID          string             `json:"id"                    gorm:"column:id"`
+	TenantID    string             `json:"tenant_id"             gorm:"column:tenant_id"`
+	SellerID    string             `json:"seller_id"             gorm:"column:seller_id"`
+	CategoryID  *string            `json:"category_id,omitempty" gorm:"column:category_id"`
+	Name        string             `json:"name"                  gorm:"column:name"`
+	Description string             `json:"description,omitempty" gorm:"column:description"`
+	CreatedAt   time.Time          `json:"created_at"            gorm:"column:created_at"`
+	UpdatedAt   time.Time          `json:"updated_at"            gorm:"column:updated_at"`
+	DeletedAt   *time.Time         `json:"deleted_at,omitempty"  gorm:"column:deleted_at"`
+	Attributes  []ProductAttribute `json:"attributes"            gorm:"-"`
+}
+
+// ProductAttribute is a variant attribute of a product, in display order.
+type ProductAttribute struct {
+	Name     string      `json:"name"             gorm:"column:name"`
+	Type     string      `json:"type"             gorm:"column:attribute_type"`
+	Values   StringSlice `json:"values,omitempty" gorm:"column:allowed_values"`
+	Position int         `json:"position"         gorm:"column:position"`
+}
+
+// ProductVariant is a variant SKU with its stock summed over the tenant's
+// hubs, or over one hub when the listing asks for it.
+type ProductVariant struct {
+	SKU
+	QuantityOnHand   int64 `json:"quantity_on_hand"  gorm:"column:quantity_on_hand"`
+	QuantityReserved int64 `json:"quantity_reserved" gorm:"column:quantity_reserved"`
+	Available        int64 `json:"available"         gorm:"column:available"`
+}
+
+// VariantAttributes maps attribute names to a variant's values; it is stored
+// as a JSONB object.
+type VariantAttributes map[string]string
+
+func (a VariantAttributes) Value() (driver.Value, error) {
+	if a == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(a)
+	return string(b), err
+}
+
+func (a *VariantAttributes) Scan(src interface{}) error {
+	return scanJSON(src, a)
+}
+
+// StringSlice is a list of strings stored as a JSONB array.
+type StringSlice []string
+
+func (s StringSlice) Value() (driver.Value, error) {
+	if s == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(s)
+	return string(b), err
+}
+
+func (s *StringSlice) Scan(src interface{}) error {
+	return scanJSON(src, s)
+}
+
+func scanJSON(src interface{}, dst interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		return nil
+	case []byte:
+		return json.Unmarshal(v, dst)
+	case string:
+		return json.Unmarshal([]byte(v), dst)
+	}
+	return fmt.Errorf("cannot scan %T into %T", src, dst)
+}
@@ -9,4 +9,5 @@ type Seller struct {
 	Metadata  map[string]interface{} `db:"metadata"   json:"metadata,omitempty"`
 	CreatedAt time.Time              `db:"created_at" json:"created_at"`
 	UpdatedAt time.Time              `db:"updated_at" json:"updated_at"`
+	DeletedAt *time.Time             `db:"deleted_at" json:"deleted_at,omitempty"`
 }
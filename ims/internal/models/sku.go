@@ -15,6 +15,11 @@ type SKU struct {
     Length      float64   `db:"length"        json:"length,omitempty"`
     Width       float64   `db:"width"         json:"width,omitempty"`
     Height      float64   `db:"height"        json:"height,omitempty"`
+    IsSerialized bool     `db:"is_serialized" json:"is_serialized"`
+    BaseUOM     string    `db:"base_uom"      json:"base_uom"`
+    ProductID   *string   `db:"product_id"    json:"product_id,omitempty"`
+    VariantAttributes VariantAttributes `db:"variant_attributes" json:"variant_attributes,omitempty"`
     CreatedAt   time.Time `db:"created_at"    json:"created_at"`
     UpdatedAt   time.Time `db:"updated_at"    json:"updated_at"`
+    DeletedAt   *time.Time `db:"deleted_at"   json:"deleted_at,omitempty"`
 }
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// SKUBarcode is a barcode that identifies a SKU when scanned. GTIN is the
+// barcode zero-padded to 14 digits, the form it is unique in per tenant.
+type SKUBarcode struct {
+	TenantID  string    `json:"tenant_id"    gorm:"column:tenant_id"`
+	SKUID     string    `json:"sku_id"       gorm:"column:sku_id"`
+	Barcode   string    `json:"barcode"      gorm:"column:barcode"`
+	Type      string    `json:"barcode_type" gorm:"column:barcode_type"`
+	GTIN      string    `json:"gtin"         gorm:"column:gtin"`
+	CreatedAt time.Time `json:"created_at"   gorm:"column:created_at"`
+}
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// SKUKitComponent is one component of a kit SKU and how many base units of it
+// go into one kit.
+type SKUKitComponent struct {
+	KitSKUID       string    `json:"kit_sku_id"       gorm:"column:kit_sku_id"`
+	ComponentSKUID string    `json:"component_sku_id" gorm:"column:component_sku_id"`
+	Quantity       int64     `json:"quantity"         gorm:"column:quantity"`
+	CreatedAt      time.Time `json:"created_at"       gorm:"column:created_at"`
+}
+
+// InventoryKitComponent is a component's stock at a hub as reported on the
+// kit's inventory row.
+type InventoryKitComponent struct {
+	SKUID            string `json:"sku_id"            gorm:"column:sku_id"`
+	Quantity         int64  `json:"quantity"          gorm:"column:quantity"`
+	QuantityOnHand   int64  `json:"quantity_on_hand"  gorm:"column:quantity_on_hand"`
+	QuantityReserved int64  `json:"quantity_reserved" gorm:"column:quantity_reserved"`
+}
@@ -0,0 +1,8 @@
+package models
+
+// SKUSearchHit is a SKU that matches a search. Hits with a higher Rank match
+// better.
+type SKUSearchHit struct {
+	SKU
+	Rank float64 `json:"rank" gorm:"column:rank"`
+}
@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// SKUUOM is a unit a SKU can be counted in and how many base units it holds.
+type SKUUOM struct {
+	SKUID     string    `json:"sku_id"     gorm:"column:sku_id"`
+	UOM       string    `json:"uom"        gorm:"column:uom"`
+	Factor    int64     `json:"factor"     gorm:"column:factor"`
+	CreatedAt time.Time `json:"created_at" gorm:"column:created_at"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"column:updated_at"`
+}
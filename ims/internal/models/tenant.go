@@ -3,9 +3,12 @@ package models
 import "time"
 
 type Tenant struct {
-	ID        string                 `db:"id"         json:"id"`
-	Name      string                 `db:"name"       json:"name"`
-	Metadata  map[string]interface{} `db:"metadata"   json:"metadata,omitempty"`
-	CreatedAt time.Time              `db:"created_at" json:"created_at"`
-	UpdatedAt time.Time              `db:"updated_at" json:"updated_at"`
+	ID                     string                 `db:"id"                       json:"id"`
+	Name                   string                 `db:"name"                     json:"name"`
+	Metadata               map[string]interface{} `db:"metadata"                 json:"metadata,omitempty"`
+	AllowNegativeInventory bool                   `db:"allow_negative_inventory" json:"allow_negative_inventory"`
+	CostingMethod          string                 `db:"costing_method"           json:"costing_method"`
+	CreatedAt              time.Time              `db:"created_at"               json:"created_at"`
+	UpdatedAt              time.Time              `db:"updated_at"               json:"updated_at"`
+	DeletedAt              *time.Time             `db:"deleted_at"               json:"deleted_at,omitempty"`
 }
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type WebhookDelivery struct {
+	ID             string          `db:"id"              json:"id"`
+	TenantID       string          `db:"tenant_id"       json:"tenant_id"`
+	WebhookID      string          `db:"webhook_id"      json:"webhook_id"`
+	Event          string          `db:"event"           json:"event"`
+	Payload        json.RawMessage `db:"payload"         json:"payload"`
+	Status         string          `db:"status"          json:"status"`
+	Attempts       int             `db:"attempts"        json:"attempts"`
+	ResponseStatus *int            `db:"response_status" json:"response_status,omitempty"`
+	LastError      *string         `db:"last_error"      json:"last_error,omitempty"`
+	NextAttemptAt  time.Time       `db:"next_attempt_at" json:"next_attempt_at"`
+	DeliveredAt    *time.Time      `db:"delivered_at"    json:"delivered_at,omitempty"`
+	CreatedAt      time.Time       `db:"created_at"      json:"created_at"`
+	UpdatedAt      time.Time       `db:"updated_at"      json:"updated_at"`
+}
@@ -0,0 +1,53 @@
+package outbox
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/abhirup.dandapat/ims/constants"
+)
+
+// payload is the body every delivery posts.
+type payload struct {
+	Event      string      `json:"event"`
+	TenantID   string      `json:"tenant_id"`
+	OccurredAt time.Time   `json:"occurred_at"`
+	Data       interface{} `json:"data"`
+}
+
+// Enqueue queues one delivery per active webhook of the tenant that subscribes
+// to event. It writes through db, normally the caller's transaction, so a
+// rolled back change never notifies anyone. The IMS dispatcher
+// (cmd/dispatcher) sends the queued deliveries.
+func Enqueue(db *gorm.DB, tenantID, event string, data interface{}, now time.Time) error {
+	var webhookIDs []string
+	if err := db.Raw(
+		`SELECT id FROM webhooks WHERE tenant_id = ? AND is_active AND ? = ANY(events)`,
+		tenantID, event,
+	).Scan(&webhookIDs).Error; err != nil {
+		return err
+	}
+	if len(webhookIDs) == 0 {
+		return nil
+	}
+
+	body, err := json.Marshal(payload{Event: event, TenantID: tenantID, OccurredAt: now, Data: data})
+	if err != nil {
+		return err
+	}
+
+	for _, webhookID := range webhookIDs {
+		if err := db.Exec(
+			`INSERT INTO webhook_deliveries(id,tenant_id,webhook_id,event,payload,status,next_attempt_at,created_at,updated_at)
+             VALUES(?,?,?,?,?,?,?,?,?)`,
+			uuid.New().String(), tenantID, webhookID, event, string(body),
+			constants.DeliveryStatusPending, now, now, now,
+		).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,12 @@
+CREATE TABLE inventory_reservations (
+  id            UUID        PRIMARY KEY,
+  tenant_id     UUID        NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  hub_id        UUID        NOT NULL REFERENCES hubs(id) ON DELETE CASCADE,
+  sku_id        UUID        NOT NULL REFERENCES skus(id) ON DELETE CASCADE,
+  reference_id  TEXT        NOT NULL,
+  quantity      BIGINT      NOT NULL CHECK (quantity > 0),
+  status        TEXT        NOT NULL DEFAULT 'reserved',
+  created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at    TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  UNIQUE (hub_id, sku_id, reference_id)
+);
@@ -0,0 +1,2 @@
+ALTER TABLE tenants DROP COLUMN allow_negative_inventory;
+ALTER TABLE inventory DROP COLUMN version;
@@ -0,0 +1,2 @@
+ALTER TABLE inventory ADD COLUMN version BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE tenants ADD COLUMN allow_negative_inventory BOOLEAN NOT NULL DEFAULT FALSE;
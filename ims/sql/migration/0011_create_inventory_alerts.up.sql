@@ -0,0 +1,17 @@
+CREATE TABLE inventory_alerts (
+  id           UUID        PRIMARY KEY,
+  tenant_id    UUID        NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  hub_id       UUID        NOT NULL REFERENCES hubs(id) ON DELETE CASCADE,
+  sku_id       UUID        NOT NULL REFERENCES skus(id) ON DELETE CASCADE,
+  alert_type   TEXT        NOT NULL,
+  quantity     BIGINT      NOT NULL,
+  threshold    BIGINT      NOT NULL,
+  status       TEXT        NOT NULL DEFAULT 'open',
+  created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  resolved_at  TIMESTAMPTZ NULL
+);
+
+CREATE UNIQUE INDEX inventory_alerts_open_uniq
+  ON inventory_alerts (hub_id, sku_id, alert_type) WHERE status = 'open';
+CREATE INDEX inventory_alerts_tenant_idx ON inventory_alerts (tenant_id, status, created_at DESC);
@@ -0,0 +1,18 @@
+CREATE TABLE webhook_deliveries (
+  id               UUID        PRIMARY KEY,
+  tenant_id        UUID        NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  webhook_id       UUID        NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+  event            TEXT        NOT NULL,
+  payload          JSONB       NOT NULL,
+  status           TEXT        NOT NULL DEFAULT 'pending',
+  attempts         INT         NOT NULL DEFAULT 0,
+  response_status  INT         NULL,
+  last_error       TEXT        NULL,
+  next_attempt_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  delivered_at     TIMESTAMPTZ NULL,
+  created_at       TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at       TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX webhook_deliveries_pending_idx ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX webhook_deliveries_webhook_idx ON webhook_deliveries (webhook_id, created_at DESC);
@@ -0,0 +1,15 @@
+-- Put the absolute quantity back on upsert rows: with true deltas, the running
+-- on-hand total at each upsert row is the quantity it set.
+WITH running AS (
+  SELECT id, transaction_type,
+         SUM(CASE WHEN transaction_type IN ('reserve','release','reservation') THEN 0 ELSE delta END)
+           OVER (PARTITION BY hub_id, sku_id ORDER BY created_at, id) AS on_hand
+  FROM inventory_transactions
+)
+UPDATE inventory_transactions t
+SET delta = r.on_hand
+FROM running r
+WHERE t.id = r.id AND r.transaction_type = 'upsert';
+
+DROP INDEX inventory_transactions_hub_sku_created_idx;
+DROP TABLE inventory_snapshots;
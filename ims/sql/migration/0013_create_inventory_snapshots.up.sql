@@ -0,0 +1,32 @@
+CREATE TABLE inventory_snapshots (
+  hub_id            UUID        NOT NULL REFERENCES hubs(id) ON DELETE CASCADE,
+  sku_id            UUID        NOT NULL REFERENCES skus(id) ON DELETE CASCADE,
+  snapshot_at       TIMESTAMPTZ NOT NULL,
+  quantity_on_hand  BIGINT      NOT NULL,
+  quantity_reserved BIGINT      NOT NULL,
+  created_at        TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (hub_id, sku_id, snapshot_at)
+);
+
+CREATE INDEX inventory_transactions_hub_sku_created_idx ON inventory_transactions (hub_id, sku_id, created_at);
+
+-- upsert rows used to store the absolute quantity in delta. Rebuild the true
+-- delta: each upsert starts a new segment whose opening balance is the
+-- absolute value, so the change is that value minus the previous segment's
+-- closing balance. Legacy 'reservation' rows never moved quantity_on_hand.
+WITH ordered AS (
+  SELECT id, hub_id, sku_id, transaction_type, delta,
+         COUNT(*) FILTER (WHERE transaction_type = 'upsert')
+           OVER (PARTITION BY hub_id, sku_id ORDER BY created_at, id) AS seg
+  FROM inventory_transactions
+), closing AS (
+  SELECT hub_id, sku_id, seg,
+         SUM(CASE WHEN transaction_type IN ('reserve','release','reservation') THEN 0 ELSE delta END) AS on_hand
+  FROM ordered
+  GROUP BY hub_id, sku_id, seg
+)
+UPDATE inventory_transactions t
+SET delta = o.delta - COALESCE(c.on_hand, 0)
+FROM ordered o
+LEFT JOIN closing c ON c.hub_id = o.hub_id AND c.sku_id = o.sku_id AND c.seg = o.seg - 1
+WHERE t.id = o.id AND o.transaction_type = 'upsert';
@@ -0,0 +1 @@
+ALTER TABLE inventory_transactions ADD COLUMN reason_code TEXT NULL;
@@ -0,0 +1,3 @@
+DROP TABLE inventory_transfer_items;
+DROP TABLE inventory_transfers;
+ALTER TABLE inventory DROP COLUMN quantity_in_transit;
@@ -0,0 +1,25 @@
+ALTER TABLE inventory ADD COLUMN quantity_in_transit BIGINT NOT NULL DEFAULT 0;
+
+CREATE TABLE inventory_transfers (
+  id                 UUID        PRIMARY KEY,
+  tenant_id          UUID        NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  source_hub_id      UUID        NOT NULL REFERENCES hubs(id) ON DELETE CASCADE,
+  destination_hub_id UUID        NOT NULL REFERENCES hubs(id) ON DELETE CASCADE,
+  reference_id       TEXT        NULL,
+  status             TEXT        NOT NULL DEFAULT 'in_transit',
+  created_at         TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at         TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  CHECK (source_hub_id <> destination_hub_id)
+);
+
+CREATE INDEX inventory_transfers_tenant_idx ON inventory_transfers (tenant_id, status, created_at DESC);
+
+CREATE TABLE inventory_transfer_items (
+  transfer_id        UUID   NOT NULL REFERENCES inventory_transfers(id) ON DELETE CASCADE,
+  sku_id             UUID   NOT NULL REFERENCES skus(id) ON DELETE CASCADE,
+  quantity           BIGINT NOT NULL CHECK (quantity > 0),
+  quantity_received  BIGINT NOT NULL DEFAULT 0,
+  quantity_cancelled BIGINT NOT NULL DEFAULT 0,
+  PRIMARY KEY (transfer_id, sku_id),
+  CHECK (quantity_received + quantity_cancelled <= quantity)
+);
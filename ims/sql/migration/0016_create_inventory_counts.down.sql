@@ -0,0 +1,2 @@
+DROP TABLE inventory_count_lines;
+DROP TABLE inventory_counts;
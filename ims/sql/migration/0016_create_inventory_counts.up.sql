@@ -0,0 +1,22 @@
+CREATE TABLE inventory_counts (
+  id          UUID        PRIMARY KEY,
+  tenant_id   UUID        NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  hub_id      UUID        NOT NULL REFERENCES hubs(id) ON DELETE CASCADE,
+  freeze      BOOLEAN     NOT NULL DEFAULT FALSE,
+  status      TEXT        NOT NULL DEFAULT 'open',
+  created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  approved_at TIMESTAMPTZ NULL
+);
+
+CREATE UNIQUE INDEX inventory_counts_open_uniq ON inventory_counts (hub_id) WHERE status = 'open';
+CREATE INDEX inventory_counts_tenant_idx ON inventory_counts (tenant_id, created_at DESC);
+
+CREATE TABLE inventory_count_lines (
+  count_id          UUID        NOT NULL REFERENCES inventory_counts(id) ON DELETE CASCADE,
+  sku_id            UUID        NOT NULL REFERENCES skus(id) ON DELETE CASCADE,
+  counted_quantity  BIGINT      NOT NULL CHECK (counted_quantity >= 0),
+  expected_quantity BIGINT      NOT NULL,
+  counted_at        TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (count_id, sku_id)
+);
@@ -0,0 +1,3 @@
+ALTER TABLE inventory_transactions DROP COLUMN lot_number;
+DROP TABLE inventory_reservation_lots;
+DROP TABLE inventory_lots;
@@ -0,0 +1,24 @@
+CREATE TABLE inventory_lots (
+  hub_id            UUID        NOT NULL REFERENCES hubs(id) ON DELETE CASCADE,
+  sku_id            UUID        NOT NULL REFERENCES skus(id) ON DELETE CASCADE,
+  lot_number        TEXT        NOT NULL,
+  manufactured_at   DATE        NULL,
+  expires_at        DATE        NULL,
+  quantity_on_hand  BIGINT      NOT NULL DEFAULT 0 CHECK (quantity_on_hand >= 0),
+  quantity_reserved BIGINT      NOT NULL DEFAULT 0 CHECK (quantity_reserved >= 0 AND quantity_reserved <= quantity_on_hand),
+  created_at        TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at        TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (hub_id, sku_id, lot_number)
+);
+
+CREATE INDEX inventory_lots_fefo_idx ON inventory_lots (hub_id, sku_id, expires_at);
+
+-- lot_number '' is stock held outside any lot.
+CREATE TABLE inventory_reservation_lots (
+  reservation_id UUID   NOT NULL REFERENCES inventory_reservations(id) ON DELETE CASCADE,
+  lot_number     TEXT   NOT NULL,
+  quantity       BIGINT NOT NULL CHECK (quantity > 0),
+  PRIMARY KEY (reservation_id, lot_number)
+);
+
+ALTER TABLE inventory_transactions ADD COLUMN lot_number TEXT NULL;
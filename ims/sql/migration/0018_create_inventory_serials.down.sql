@@ -0,0 +1,3 @@
+DROP TABLE inventory_transaction_serials;
+DROP TABLE inventory_serials;
+ALTER TABLE skus DROP COLUMN is_serialized;
@@ -0,0 +1,26 @@
+ALTER TABLE skus ADD COLUMN is_serialized BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- One row per unit of a serialized SKU; hub_id is where the unit was last
+-- received and status whether it is still there.
+CREATE TABLE inventory_serials (
+  sku_id        UUID        NOT NULL REFERENCES skus(id) ON DELETE CASCADE,
+  serial_number TEXT        NOT NULL,
+  tenant_id     UUID        NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  hub_id        UUID        NOT NULL REFERENCES hubs(id) ON DELETE CASCADE,
+  status        TEXT        NOT NULL,
+  created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at    TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (sku_id, serial_number)
+);
+
+CREATE INDEX inventory_serials_serial_number_idx ON inventory_serials (serial_number);
+CREATE INDEX inventory_serials_hub_sku_idx ON inventory_serials (hub_id, sku_id, status);
+
+CREATE TABLE inventory_transaction_serials (
+  transaction_id UUID NOT NULL REFERENCES inventory_transactions(id) ON DELETE CASCADE,
+  sku_id         UUID NOT NULL,
+  serial_number  TEXT NOT NULL,
+  PRIMARY KEY (transaction_id, serial_number)
+);
+
+CREATE INDEX inventory_transaction_serials_serial_idx ON inventory_transaction_serials (sku_id, serial_number);
@@ -0,0 +1,3 @@
+DROP TABLE inventory_bin_moves;
+DROP TABLE inventory_bins;
+DROP TABLE hub_locations;
@@ -0,0 +1,42 @@
+-- Zones hold aisles and aisles hold bins; stock is only ever kept in bins.
+CREATE TABLE hub_locations (
+  id            UUID        PRIMARY KEY,
+  hub_id        UUID        NOT NULL REFERENCES hubs(id) ON DELETE CASCADE,
+  parent_id     UUID        NULL REFERENCES hub_locations(id) ON DELETE CASCADE,
+  location_type TEXT        NOT NULL,
+  code          TEXT        NOT NULL,
+  created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  UNIQUE (hub_id, code)
+);
+
+CREATE INDEX hub_locations_parent_idx ON hub_locations (parent_id);
+
+-- Per-bin share of inventory.quantity_on_hand; the rest of a hub/SKU's stock
+-- is not yet put away into any bin.
+CREATE TABLE inventory_bins (
+  location_id UUID        NOT NULL REFERENCES hub_locations(id),
+  hub_id      UUID        NOT NULL REFERENCES hubs(id) ON DELETE CASCADE,
+  sku_id      UUID        NOT NULL REFERENCES skus(id) ON DELETE CASCADE,
+  quantity    BIGINT      NOT NULL DEFAULT 0 CHECK (quantity >= 0),
+  updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (location_id, sku_id)
+);
+
+CREATE INDEX inventory_bins_hub_sku_idx ON inventory_bins (hub_id, sku_id);
+
+-- A NULL side is stock outside any bin: putaway has no source, picks and
+-- drains have no destination.
+CREATE TABLE inventory_bin_moves (
+  id               UUID        PRIMARY KEY,
+  tenant_id        UUID        NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  hub_id           UUID        NOT NULL REFERENCES hubs(id) ON DELETE CASCADE,
+  sku_id           UUID        NOT NULL REFERENCES skus(id) ON DELETE CASCADE,
+  from_location_id UUID        NULL REFERENCES hub_locations(id),
+  to_location_id   UUID        NULL REFERENCES hub_locations(id),
+  quantity         BIGINT      NOT NULL CHECK (quantity > 0),
+  reference_id     TEXT        NULL,
+  created_at       TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  CHECK (from_location_id IS NOT NULL OR to_location_id IS NOT NULL)
+);
+
+CREATE INDEX inventory_bin_moves_hub_sku_created_idx ON inventory_bin_moves (hub_id, sku_id, created_at);
@@ -0,0 +1,4 @@
+ALTER TABLE inventory_reservations DROP COLUMN uom, DROP COLUMN uom_quantity;
+ALTER TABLE inventory_transactions DROP COLUMN uom, DROP COLUMN uom_quantity;
+DROP TABLE sku_uoms;
+ALTER TABLE skus DROP COLUMN base_uom;
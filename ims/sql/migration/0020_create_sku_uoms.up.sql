@@ -0,0 +1,15 @@
+ALTER TABLE skus ADD COLUMN base_uom TEXT NOT NULL DEFAULT 'each';
+
+-- factor is the number of base units in one uom, e.g. 12 for a case of 12.
+CREATE TABLE sku_uoms (
+  sku_id     UUID        NOT NULL REFERENCES skus(id) ON DELETE CASCADE,
+  uom        TEXT        NOT NULL,
+  factor     BIGINT      NOT NULL CHECK (factor > 0),
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (sku_id, uom)
+);
+
+-- The unit and quantity the caller used; delta and quantity stay in base units.
+ALTER TABLE inventory_transactions ADD COLUMN uom TEXT NULL, ADD COLUMN uom_quantity BIGINT NULL;
+ALTER TABLE inventory_reservations ADD COLUMN uom TEXT NULL, ADD COLUMN uom_quantity BIGINT NULL;
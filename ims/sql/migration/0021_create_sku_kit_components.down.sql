@@ -0,0 +1,2 @@
+ALTER TABLE inventory_reservations DROP COLUMN parent_id;
+DROP TABLE sku_kit_components;
@@ -0,0 +1,17 @@
+-- A kit is sold as one SKU but stocked as its components; quantity is the
+-- number of component units (in base units) in one kit.
+CREATE TABLE sku_kit_components (
+  kit_sku_id       UUID        NOT NULL REFERENCES skus(id) ON DELETE CASCADE,
+  component_sku_id UUID        NOT NULL REFERENCES skus(id) ON DELETE RESTRICT,
+  quantity         BIGINT      NOT NULL CHECK (quantity > 0),
+  created_at       TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (kit_sku_id, component_sku_id),
+  CHECK (kit_sku_id <> component_sku_id)
+);
+
+CREATE INDEX sku_kit_components_component_idx ON sku_kit_components (component_sku_id);
+
+-- A kit reservation holds no stock itself; its component reservations point
+-- back to it and are settled with it.
+ALTER TABLE inventory_reservations
+  ADD COLUMN parent_id UUID NULL REFERENCES inventory_reservations(id) ON DELETE CASCADE;
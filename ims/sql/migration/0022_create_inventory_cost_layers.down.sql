@@ -0,0 +1,3 @@
+DROP TABLE inventory_cost_layers;
+ALTER TABLE inventory_transactions DROP COLUMN unit_cost, DROP COLUMN total_cost;
+ALTER TABLE tenants DROP COLUMN costing_method;
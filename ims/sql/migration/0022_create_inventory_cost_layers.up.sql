@@ -0,0 +1,28 @@
+ALTER TABLE tenants ADD COLUMN costing_method TEXT NOT NULL DEFAULT 'fifo'
+  CHECK (costing_method IN ('fifo', 'average'));
+
+-- unit_cost is per base unit. total_cost is the signed value the row moved
+-- into (inbound) or out of (outbound) stock; rows that do not move on-hand
+-- stock leave both NULL.
+ALTER TABLE inventory_transactions
+  ADD COLUMN unit_cost  NUMERIC(18,6) NULL,
+  ADD COLUMN total_cost NUMERIC(18,4) NULL;
+
+-- Stock still on hand at the cost it came in at. Outbound stock is drawn from
+-- the oldest layer first; tenants on average costing keep a single open layer
+-- per hub/SKU that every receipt is merged into.
+CREATE TABLE inventory_cost_layers (
+  id                 UUID          PRIMARY KEY,
+  tenant_id          UUID          NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  hub_id             UUID          NOT NULL REFERENCES hubs(id) ON DELETE CASCADE,
+  sku_id             UUID          NOT NULL REFERENCES skus(id) ON DELETE CASCADE,
+  transaction_id     UUID          NOT NULL,
+  unit_cost          NUMERIC(18,6) NOT NULL CHECK (unit_cost >= 0),
+  quantity_received  BIGINT        NOT NULL CHECK (quantity_received > 0),
+  quantity_remaining BIGINT        NOT NULL CHECK (quantity_remaining >= 0),
+  created_at         TIMESTAMPTZ   NOT NULL DEFAULT NOW(),
+  updated_at         TIMESTAMPTZ   NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX inventory_cost_layers_open_idx ON inventory_cost_layers (hub_id, sku_id, created_at)
+  WHERE quantity_remaining > 0;
@@ -0,0 +1,2 @@
+ALTER TABLE inventory DROP COLUMN safety_stock;
+ALTER TABLE hubs DROP COLUMN is_active;
@@ -0,0 +1,5 @@
+-- Inactive hubs keep their stock but are left out of available-to-promise.
+ALTER TABLE hubs ADD COLUMN is_active BOOLEAN NOT NULL DEFAULT TRUE;
+
+-- Stock a hub keeps back from promising to orders.
+ALTER TABLE inventory ADD COLUMN safety_stock BIGINT NOT NULL DEFAULT 0 CHECK (safety_stock >= 0);
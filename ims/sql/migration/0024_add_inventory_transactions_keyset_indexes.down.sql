@@ -0,0 +1,3 @@
+DROP INDEX inventory_transactions_reference_idx;
+DROP INDEX inventory_transactions_hub_created_idx;
+DROP INDEX inventory_transactions_tenant_created_idx;
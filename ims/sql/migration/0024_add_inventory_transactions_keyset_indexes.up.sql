@@ -0,0 +1,5 @@
+-- Keyset pagination and exports walk the ledger of a tenant or a hub by
+-- (created_at, id).
+CREATE INDEX inventory_transactions_tenant_created_idx ON inventory_transactions (tenant_id, created_at, id);
+CREATE INDEX inventory_transactions_hub_created_idx ON inventory_transactions (hub_id, created_at, id);
+CREATE INDEX inventory_transactions_reference_idx ON inventory_transactions (reference_id);
@@ -0,0 +1,24 @@
+ALTER TABLE inventory_transactions
+  DROP CONSTRAINT inventory_transactions_tenant_id_fkey,
+  ADD CONSTRAINT inventory_transactions_tenant_id_fkey FOREIGN KEY (tenant_id) REFERENCES tenants(id) ON DELETE CASCADE,
+  DROP CONSTRAINT inventory_transactions_hub_id_fkey,
+  ADD CONSTRAINT inventory_transactions_hub_id_fkey FOREIGN KEY (hub_id) REFERENCES hubs(id) ON DELETE CASCADE,
+  DROP CONSTRAINT inventory_transactions_sku_id_fkey,
+  ADD CONSTRAINT inventory_transactions_sku_id_fkey FOREIGN KEY (sku_id) REFERENCES skus(id) ON DELETE CASCADE;
+ALTER TABLE inventory
+  DROP CONSTRAINT inventory_hub_id_fkey,
+  ADD CONSTRAINT inventory_hub_id_fkey FOREIGN KEY (hub_id) REFERENCES hubs(id) ON DELETE CASCADE,
+  DROP CONSTRAINT inventory_sku_id_fkey,
+  ADD CONSTRAINT inventory_sku_id_fkey FOREIGN KEY (sku_id) REFERENCES skus(id) ON DELETE CASCADE;
+ALTER TABLE skus
+  DROP CONSTRAINT skus_seller_id_fkey,
+  ADD CONSTRAINT skus_seller_id_fkey FOREIGN KEY (seller_id) REFERENCES sellers(id) ON DELETE CASCADE;
+ALTER TABLE hubs
+  DROP CONSTRAINT hubs_seller_id_fkey,
+  ADD CONSTRAINT hubs_seller_id_fkey FOREIGN KEY (seller_id) REFERENCES sellers(id) ON DELETE CASCADE;
+
+ALTER TABLE skus       DROP COLUMN deleted_at;
+ALTER TABLE hubs       DROP COLUMN deleted_at;
+ALTER TABLE categories DROP COLUMN deleted_at;
+ALTER TABLE sellers    DROP COLUMN deleted_at;
+ALTER TABLE tenants    DROP COLUMN deleted_at;
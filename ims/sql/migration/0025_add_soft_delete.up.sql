@@ -0,0 +1,28 @@
+-- Catalog rows are soft deleted: the API sets deleted_at and hides the row,
+-- and inventory and ledger history that point at it stay intact.
+ALTER TABLE tenants    ADD COLUMN deleted_at TIMESTAMPTZ NULL;
+ALTER TABLE sellers    ADD COLUMN deleted_at TIMESTAMPTZ NULL;
+ALTER TABLE categories ADD COLUMN deleted_at TIMESTAMPTZ NULL;
+ALTER TABLE hubs       ADD COLUMN deleted_at TIMESTAMPTZ NULL;
+ALTER TABLE skus       ADD COLUMN deleted_at TIMESTAMPTZ NULL;
+
+-- A hard delete of a seller, hub or SKU used to cascade into inventory and
+-- the ledger. Refuse it instead while anything still points at the row.
+ALTER TABLE hubs
+  DROP CONSTRAINT hubs_seller_id_fkey,
+  ADD CONSTRAINT hubs_seller_id_fkey FOREIGN KEY (seller_id) REFERENCES sellers(id) ON DELETE RESTRICT;
+ALTER TABLE skus
+  DROP CONSTRAINT skus_seller_id_fkey,
+  ADD CONSTRAINT skus_seller_id_fkey FOREIGN KEY (seller_id) REFERENCES sellers(id) ON DELETE RESTRICT;
+ALTER TABLE inventory
+  DROP CONSTRAINT inventory_hub_id_fkey,
+  ADD CONSTRAINT inventory_hub_id_fkey FOREIGN KEY (hub_id) REFERENCES hubs(id) ON DELETE RESTRICT,
+  DROP CONSTRAINT inventory_sku_id_fkey,
+  ADD CONSTRAINT inventory_sku_id_fkey FOREIGN KEY (sku_id) REFERENCES skus(id) ON DELETE RESTRICT;
+ALTER TABLE inventory_transactions
+  DROP CONSTRAINT inventory_transactions_tenant_id_fkey,
+  ADD CONSTRAINT inventory_transactions_tenant_id_fkey FOREIGN KEY (tenant_id) REFERENCES tenants(id) ON DELETE RESTRICT,
+  DROP CONSTRAINT inventory_transactions_hub_id_fkey,
+  ADD CONSTRAINT inventory_transactions_hub_id_fkey FOREIGN KEY (hub_id) REFERENCES hubs(id) ON DELETE RESTRICT,
+  DROP CONSTRAINT inventory_transactions_sku_id_fkey,
+  ADD CONSTRAINT inventory_transactions_sku_id_fkey FOREIGN KEY (sku_id) REFERENCES skus(id) ON DELETE RESTRICT;
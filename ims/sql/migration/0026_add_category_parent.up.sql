@@ -0,0 +1,6 @@
+-- Categories form a tree per tenant; roots have no parent.
+ALTER TABLE categories
+  ADD COLUMN parent_id UUID NULL REFERENCES categories(id) ON DELETE RESTRICT,
+  ADD CONSTRAINT categories_parent_not_self CHECK (parent_id <> id);
+
+CREATE INDEX categories_parent_idx ON categories (parent_id);
@@ -0,0 +1,4 @@
+DROP INDEX skus_product_variant_idx;
+ALTER TABLE skus DROP COLUMN variant_attributes, DROP COLUMN product_id;
+DROP TABLE product_attributes;
+DROP TABLE products;
@@ -0,0 +1,35 @@
+-- A product groups SKUs that are variants of one item, told apart by the
+-- values of the product's variant attributes (e.g. size and colour).
+CREATE TABLE products (
+  id          UUID        PRIMARY KEY,
+  tenant_id   UUID        NOT NULL REFERENCES tenants(id) ON DELETE RESTRICT,
+  seller_id   UUID        NOT NULL REFERENCES sellers(id) ON DELETE RESTRICT,
+  category_id UUID        NULL REFERENCES categories(id),
+  name        TEXT        NOT NULL,
+  description TEXT        NULL,
+  created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  deleted_at  TIMESTAMPTZ NULL
+);
+
+CREATE INDEX products_tenant_idx ON products (tenant_id);
+
+-- string and number attributes take any value of their type; enum
+-- attributes only one of allowed_values (a JSON array of strings).
+CREATE TABLE product_attributes (
+  product_id     UUID    NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+  name           TEXT    NOT NULL,
+  attribute_type TEXT    NOT NULL CHECK (attribute_type IN ('string','number','enum')),
+  allowed_values JSONB   NULL,
+  position       INT     NOT NULL,
+  PRIMARY KEY (product_id, name)
+);
+
+-- A variant SKU carries its attribute values as a JSON object; each
+-- combination appears once among a product's live SKUs.
+ALTER TABLE skus
+  ADD COLUMN product_id         UUID  NULL REFERENCES products(id) ON DELETE RESTRICT,
+  ADD COLUMN variant_attributes JSONB NULL;
+
+CREATE UNIQUE INDEX skus_product_variant_idx ON skus (product_id, variant_attributes)
+  WHERE product_id IS NOT NULL AND deleted_at IS NULL;
@@ -0,0 +1,14 @@
+-- Barcodes scanned for a SKU. gtin is the barcode zero-padded to 14 digits,
+-- so a UPC-A and the EAN-13 with its leading zero are the same code; it is
+-- unique per tenant. barcode keeps the digits as they were given.
+CREATE TABLE sku_barcodes (
+  tenant_id    UUID        NOT NULL REFERENCES tenants(id) ON DELETE RESTRICT,
+  gtin         TEXT        NOT NULL,
+  sku_id       UUID        NOT NULL REFERENCES skus(id) ON DELETE CASCADE,
+  barcode      TEXT        NOT NULL,
+  barcode_type TEXT        NOT NULL CHECK (barcode_type IN ('ean8','upc_a','ean13','gtin14')),
+  created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (tenant_id, gtin)
+);
+
+CREATE INDEX sku_barcodes_sku_idx ON sku_barcodes (sku_id);
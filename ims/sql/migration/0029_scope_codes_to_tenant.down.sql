@@ -0,0 +1,4 @@
+DROP INDEX hubs_tenant_code_idx;
+ALTER TABLE hubs DROP COLUMN code;
+DROP INDEX skus_tenant_code_idx;
+ALTER TABLE skus ADD CONSTRAINT skus_code_key UNIQUE (code);
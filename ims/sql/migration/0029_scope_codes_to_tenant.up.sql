@@ -0,0 +1,8 @@
+-- SKU codes were unique across all tenants. They are now unique among a
+-- tenant's live SKUs, so a deleted SKU's code can be reused.
+ALTER TABLE skus DROP CONSTRAINT skus_code_key;
+CREATE UNIQUE INDEX skus_tenant_code_idx ON skus (tenant_id, code) WHERE deleted_at IS NULL;
+
+-- Hubs get an optional code, unique among a tenant's live hubs.
+ALTER TABLE hubs ADD COLUMN code TEXT NULL;
+CREATE UNIQUE INDEX hubs_tenant_code_idx ON hubs (tenant_id, code) WHERE deleted_at IS NULL;
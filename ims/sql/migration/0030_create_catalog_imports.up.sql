@@ -0,0 +1,27 @@
+-- Bulk SKU imports, processed in the background by cmd/importer. payload
+-- holds the uploaded rows as a JSON array; error_report is the CSV of the
+-- rows that were not imported, written when the import completes.
+CREATE TABLE catalog_imports (
+  id           UUID        PRIMARY KEY,
+  tenant_id    UUID        NOT NULL REFERENCES tenants(id) ON DELETE RESTRICT,
+  format       TEXT        NOT NULL CHECK (format IN ('csv','json')),
+  file_name    TEXT        NULL,
+  status       TEXT        NOT NULL DEFAULT 'pending'
+                           CHECK (status IN ('pending','processing','completed','failed')),
+  payload      JSONB       NOT NULL,
+  total_rows   INT         NOT NULL,
+  created_rows INT         NOT NULL DEFAULT 0,
+  updated_rows INT         NOT NULL DEFAULT 0,
+  failed_rows  INT         NOT NULL DEFAULT 0,
+  error_report TEXT        NULL,
+  attempts     INT         NOT NULL DEFAULT 0,
+  last_error   TEXT        NULL,
+  lease_until  TIMESTAMPTZ NULL,
+  created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  started_at   TIMESTAMPTZ NULL,
+  finished_at  TIMESTAMPTZ NULL
+);
+
+CREATE INDEX catalog_imports_pending_idx ON catalog_imports (created_at) WHERE status IN ('pending','processing');
+CREATE INDEX catalog_imports_tenant_idx ON catalog_imports (tenant_id, created_at DESC);
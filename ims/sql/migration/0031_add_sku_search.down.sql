@@ -0,0 +1,4 @@
+DROP INDEX skus_name_trgm_idx;
+DROP INDEX skus_code_trgm_idx;
+DROP INDEX skus_search_vector_idx;
+ALTER TABLE skus DROP COLUMN search_vector;
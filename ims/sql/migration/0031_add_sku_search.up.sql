@@ -0,0 +1,15 @@
+-- Search over SKUs. search_vector weighs the code above the name above the
+-- description; the code is indexed word for word ('simple'), name and
+-- description with English stemming. The trigram indexes serve fuzzy and
+-- substring matches on code and name.
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+ALTER TABLE skus ADD COLUMN search_vector TSVECTOR GENERATED ALWAYS AS (
+  setweight(to_tsvector('simple', coalesce(code, '')), 'A') ||
+  setweight(to_tsvector('english', coalesce(name, '')), 'B') ||
+  setweight(to_tsvector('english', coalesce(description, '')), 'C')
+) STORED;
+
+CREATE INDEX skus_search_vector_idx ON skus USING GIN (search_vector);
+CREATE INDEX skus_code_trgm_idx ON skus USING GIN (code gin_trgm_ops);
+CREATE INDEX skus_name_trgm_idx ON skus USING GIN (name gin_trgm_ops);
@@ -0,0 +1 @@
+DROP INDEX inventory_bin_moves_hub_created_idx;
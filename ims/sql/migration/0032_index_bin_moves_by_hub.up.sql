@@ -0,0 +1,3 @@
+-- Serves the keyset pages of a hub's bin moves, newest first, when no SKU
+-- is named.
+CREATE INDEX inventory_bin_moves_hub_created_idx ON inventory_bin_moves (hub_id, created_at, id);
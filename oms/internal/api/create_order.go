@@ -2,7 +2,7 @@ package api
 
 import (
 	"encoding/json"
-	"fmt"
+	"errors"
 	stdhttp "net/http"
 	"time"
 
@@ -18,6 +18,7 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 
 	"github.com/abhirup.dandapat/oms/internal/models"
+	"github.com/abhirup.dandapat/oms/internal/store"
 )
 
 type CreateOrderRequest struct {
@@ -26,6 +27,7 @@ type CreateOrderRequest struct {
 	HubID    string `json:"hub_id"    binding:"required"`
 	SKUID    string `json:"sku_id"    binding:"required"`
 	Quantity int64  `json:"quantity"  binding:"required,gt=0"`
+	UOM      string `json:"uom"`
 }
 
 func CreateOrder(c *gin.Context) {
@@ -37,7 +39,6 @@ func CreateOrder(c *gin.Context) {
 	ctx := c.Request.Context()
 
 	baseURL := config.GetString(ctx, "ims.baseUrl")
-	url := fmt.Sprintf("%s/inventory?hub_id=%s&sku_ids=%s", baseURL, req.HubID, req.SKUID)
 
 	transport := &stdhttp.Transport{}
 	httpClient, err := commonsHttp.NewHTTPClient("order-service", "", transport)
@@ -47,34 +48,25 @@ func CreateOrder(c *gin.Context) {
 		return
 	}
 
-	var invs []models.Inventory
-	getReq := &commonsHttp.Request{
-		Url:     url,
-		Timeout: 5 * time.Second,
-	}
-	if _, err := httpClient.Get(getReq, &invs); err != nil || len(invs) != 1 {
-		log.DefaultLogger().Errorf("CreateOrder: IMS GET failed: %v", err)
-		c.JSON(stdhttp.StatusServiceUnavailable, gin.H{"error": i18n.Translate(c, "error.inventory_unavailable")})
-		return
-	}
-	inv := invs[0]
-	if inv.QuantityOnHand < req.Quantity {
-		c.JSON(stdhttp.StatusConflict, gin.H{"error": i18n.Translate(c, "error.insufficient_inventory")})
-		return
-	}
-
-	putReq := &commonsHttp.Request{
-		Url: baseURL + "/inventory",
-		Body: map[string]interface{}{
-			"tenant_id": req.TenantID,
-			"hub_id":    req.HubID,
-			"sku_id":    req.SKUID,
-			"quantity":  inv.QuantityOnHand - req.Quantity,
-		},
-		Timeout: 5 * time.Second,
+	orderID := uuid.New().String()
+	reservation := models.InventoryReservation{
+		TenantID:    req.TenantID,
+		HubID:       req.HubID,
+		SKUID:       req.SKUID,
+		ReferenceID: orderID,
+		Quantity:    req.Quantity,
+		UOM:         req.UOM,
 	}
-	if _, err := httpClient.Post(putReq, nil); err != nil {
-		log.DefaultLogger().Errorf("CreateOrder: IMS PUT failed: %v", err)
+	if err := store.ReserveInventory(httpClient, baseURL, reservation); err != nil {
+		if errors.Is(err, store.ErrInsufficientInventory) {
+			c.JSON(stdhttp.StatusConflict, gin.H{"error": i18n.Translate(c, "error.insufficient_inventory")})
+			return
+		}
+		if errors.Is(err, store.ErrInvalidUOM) {
+			c.JSON(stdhttp.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_uom")})
+			return
+		}
+		log.DefaultLogger().Errorf("CreateOrder: IMS reserve failed: %v", err)
 		c.JSON(stdhttp.StatusServiceUnavailable, gin.H{"error": i18n.Translate(c, "error.inventory_update_failed")})
 		return
 	}
@@ -83,13 +75,13 @@ func CreateOrder(c *gin.Context) {
 	cli, err := mongo.Connect(ctx, options.Client().ApplyURI(mongoURI))
 	if err != nil {
 		log.DefaultLogger().Errorf("CreateOrder: mongo connect: %v", err)
+		releaseReservation(httpClient, baseURL, reservation)
 		c.JSON(stdhttp.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.internal")})
 		return
 	}
 	defer cli.Disconnect(ctx)
 	coll := cli.Database("omsdb").Collection("orders")
 
-	orderID := uuid.New().String()
 	now := time.Now().UTC()
 	order := models.Order{
 		ID:        orderID,
@@ -98,11 +90,13 @@ func CreateOrder(c *gin.Context) {
 		HubID:     req.HubID,
 		SKUID:     req.SKUID,
 		Quantity:  req.Quantity,
+		UOM:       req.UOM,
 		Status:    "new_order",
 		CreatedAt: now,
 	}
 	if _, err := coll.InsertOne(ctx, order); err != nil {
 		log.DefaultLogger().Errorf("CreateOrder: mongo insert: %v", err)
+		releaseReservation(httpClient, baseURL, reservation)
 		c.JSON(stdhttp.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.internal")})
 		return
 	}
@@ -119,6 +113,7 @@ func CreateOrder(c *gin.Context) {
 		HubID:     req.HubID,
 		SKUID:     req.SKUID,
 		Quantity:  req.Quantity,
+		UOM:       req.UOM,
 		CreatedAt: now,
 	}
 	payload, _ := json.Marshal(evt)
@@ -131,5 +126,18 @@ func CreateOrder(c *gin.Context) {
 		log.DefaultLogger().Errorf("CreateOrder: publish order.created failed: %v", err)
 	}
 
-	c.JSON(stdhttp.StatusCreated, gin.H{"order_id": orderID})
+	resp := gin.H{"order_id": orderID}
+	if req.UOM != "" {
+		resp["quantity"], resp["uom"] = req.Quantity, req.UOM
+	}
+	c.JSON(stdhttp.StatusCreated, resp)
+}
+
+// releaseReservation gives back the stock held for an order that could not be
+// saved. A failed release is only logged; the hold then stays until someone
+// releases the reference by hand.
+func releaseReservation(client *commonsHttp.Client, baseURL string, r models.InventoryReservation) {
+	if err := store.ReleaseInventory(client, baseURL, r); err != nil {
+		log.DefaultLogger().Errorf("CreateOrder: release reservation %s failed: %v", r.ReferenceID, err)
+	}
 }
@@ -0,0 +1,155 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	stdhttp "net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/omniful/go_commons/config"
+	commonsHttp "github.com/omniful/go_commons/http"
+	"github.com/omniful/go_commons/i18n"
+	"github.com/omniful/go_commons/kafka"
+	"github.com/omniful/go_commons/log"
+	"github.com/omniful/go_commons/pubsub"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/abhirup.dandapat/oms/internal/models"
+	"github.com/abhirup.dandapat/oms/internal/store"
+)
+
+// ShipOrderRequest is the optional body of POST /orders/:id/ship; serialized
+// SKUs need the serial numbers of the units shipped.
+type ShipOrderRequest struct {
+	SerialNumbers []string `json:"serial_numbers"`
+}
+
+// ShipOrder commits the order's IMS reservation and marks it shipped.
+func ShipOrder(c *gin.Context) {
+	var req ShipOrderRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(stdhttp.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_request")})
+			return
+		}
+	}
+	transitionOrder(c, "shipped", []string{"new_order"}, store.CommitInventory, req.SerialNumbers)
+}
+
+// CancelOrder releases any IMS reservation held for the order and marks it
+// cancelled. On-hold orders never reserved stock, so there is nothing to release.
+func CancelOrder(c *gin.Context) {
+	transitionOrder(c, "cancelled", []string{"on_hold", "new_order"}, store.ReleaseInventory, nil)
+}
+
+type reservationCall func(*commonsHttp.Client, string, models.InventoryReservation) error
+
+func transitionOrder(c *gin.Context, status string, from []string, settle reservationCall, serials []string) {
+	ctx := c.Request.Context()
+	id := c.Param("id")
+
+	mongoURI := config.GetString(ctx, "mongo.uri")
+	cli, err := mongo.Connect(ctx, options.Client().ApplyURI(mongoURI))
+	if err != nil {
+		log.DefaultLogger().Errorf("transitionOrder: mongo connect: %v", err)
+		c.JSON(stdhttp.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.internal")})
+		return
+	}
+	defer cli.Disconnect(ctx)
+	coll := cli.Database("omsdb").Collection("orders")
+
+	var order models.Order
+	if err := coll.FindOne(ctx, bson.M{"_id": id}).Decode(&order); err != nil {
+		c.JSON(stdhttp.StatusNotFound, gin.H{"error": i18n.Translate(c, "error.order_not_found")})
+		return
+	}
+	if order.Status == status {
+		c.JSON(stdhttp.StatusOK, gin.H{"order_id": id, "status": status})
+		return
+	}
+	allowed := false
+	for _, s := range from {
+		allowed = allowed || order.Status == s
+	}
+	if !allowed {
+		c.JSON(stdhttp.StatusConflict, gin.H{"error": i18n.Translate(c, "error.invalid_order_status")})
+		return
+	}
+
+	if order.Status == "new_order" {
+		httpClient, err := commonsHttp.NewHTTPClient("order-service", "", &stdhttp.Transport{})
+		if err != nil {
+			log.DefaultLogger().Errorf("transitionOrder: NewHTTPClient failed: %v", err)
+			c.JSON(stdhttp.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.internal")})
+			return
+		}
+		err = settle(httpClient, config.GetString(ctx, "ims.baseUrl"), models.InventoryReservation{
+			TenantID:      order.TenantID,
+			HubID:         order.HubID,
+			SKUID:         order.SKUID,
+			ReferenceID:   order.ID,
+			SerialNumbers: serials,
+		})
+		if errors.Is(err, store.ErrInvalidSerials) {
+			c.JSON(stdhttp.StatusBadRequest, gin.H{"error": i18n.Translate(c, "error.invalid_serials")})
+			return
+		}
+		if errors.Is(err, store.ErrSerialConflict) {
+			c.JSON(stdhttp.StatusConflict, gin.H{"error": i18n.Translate(c, "error.serial_conflict")})
+			return
+		}
+		if errors.Is(err, store.ErrHubFrozen) {
+			c.JSON(stdhttp.StatusLocked, gin.H{"error": i18n.Translate(c, "error.hub_frozen")})
+			return
+		}
+		if err != nil && !errors.Is(err, store.ErrReservationNotFound) {
+			log.DefaultLogger().Errorf("transitionOrder: IMS %s failed for %s: %v", status, id, err)
+			c.JSON(stdhttp.StatusServiceUnavailable, gin.H{"error": i18n.Translate(c, "error.inventory_update_failed")})
+			return
+		}
+	}
+
+	now := time.Now().UTC()
+	res, err := coll.UpdateOne(ctx,
+		bson.M{"_id": id, "status": order.Status},
+		bson.M{"$set": bson.M{"status": status, "updated_at": now}},
+	)
+	if err != nil {
+		log.DefaultLogger().Errorf("transitionOrder: mongo update: %v", err)
+		c.JSON(stdhttp.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "error.internal")})
+		return
+	}
+	if res.MatchedCount == 0 {
+		c.JSON(stdhttp.StatusConflict, gin.H{"error": i18n.Translate(c, "error.invalid_order_status")})
+		return
+	}
+
+	producer := kafka.NewProducer(
+		kafka.WithBrokers(config.GetStringSlice(ctx, "kafka.brokers")),
+		kafka.WithClientID(config.GetString(ctx, "kafka.clientId")+"-producer"),
+		kafka.WithKafkaVersion(config.GetString(ctx, "kafka.version")),
+	)
+	payload, _ := json.Marshal(gin.H{
+		"order_id":   id,
+		"tenant_id":  order.TenantID,
+		"seller_id":  order.SellerID,
+		"hub_id":     order.HubID,
+		"sku_id":     order.SKUID,
+		"quantity":   order.Quantity,
+		"uom":        order.UOM,
+		"status":     status,
+		"updated_at": now,
+	})
+	if err := producer.Publish(ctx, &pubsub.Message{
+		Topic: config.GetString(ctx, "kafka.topicOrderUpdated"),
+		Key:   id,
+		Value: payload,
+	}); err != nil {
+		log.DefaultLogger().Errorf("transitionOrder: publish order.updated failed: %v", err)
+	}
+
+	c.JSON(stdhttp.StatusOK, gin.H{"order_id": id, "status": status})
+}
@@ -10,6 +10,8 @@ func RegisterRoutes(r *gin.Engine) {
 	r.GET("/orders", ListOrders)
 	r.GET("/orders/errors/:file", DownloadErrorCSV)
 	r.POST("/orders", CreateOrder)
+	r.POST("/orders/:id/ship", ShipOrder)
+	r.POST("/orders/:id/cancel", CancelOrder)
 	r.POST("/webhooks", createWebhook)
 	r.GET("/webhooks/:id", getWebhook)
 	r.GET("/webhooks", listWebhooks)
@@ -3,7 +3,7 @@ package finalizer
 import (
 	"context"
 	"encoding/json"
-	"fmt"
+	"errors"
 	"time"
 
 	commonsHttp "github.com/omniful/go_commons/http"
@@ -13,6 +13,7 @@ import (
 	"go.mongodb.org/mongo-driver/mongo"
 
 	"github.com/abhirup.dandapat/oms/internal/models"
+	"github.com/abhirup.dandapat/oms/internal/store"
 )
 
 const updatedTopic = "order.updated"
@@ -45,49 +46,55 @@ func (h *Handler) Process(ctx context.Context, msg *pubsub.Message) error {
 	}
 	h.logger.Infof("Finalizing order %s", oc.OrderID)
 
-	getReq := &commonsHttp.Request{
-		Url:     fmt.Sprintf("/inventory?hub_id=%s&sku_ids=%s", oc.HubID, oc.SKUID),
-		Timeout: 5 * time.Second,
+	reservation := models.InventoryReservation{
+		TenantID:    oc.TenantID,
+		HubID:       oc.HubID,
+		SKUID:       oc.SKUID,
+		ReferenceID: oc.OrderID,
+		Quantity:    oc.Quantity,
+		UOM:         oc.UOM,
 	}
-	getResp, err := h.client.Get(getReq, nil)
-	if err != nil {
-		h.logger.Errorf("IMS GET error: %v", err)
-		return err
-	}
-	var invs []models.Inventory
-	if err := json.Unmarshal(getResp.Body(), &invs); err != nil || len(invs) == 0 {
-		h.logger.Warnf("bad inventory response: %v", err)
-		return nil
-	}
-	available := invs[0].QuantityOnHand
-	if available < oc.Quantity {
-		h.logger.Warnf("insufficient stock for %s: have=%d need=%d",
-			oc.OrderID, available, oc.Quantity)
-		return nil
-	}
-
-	putReq := &commonsHttp.Request{
-		Url: "/inventory",
-		Body: map[string]interface{}{
-			"tenant_id": oc.TenantID,
-			"hub_id":    oc.HubID,
-			"sku_id":    oc.SKUID,
-			"quantity":  available - oc.Quantity,
-		},
-		Timeout: 5 * time.Second,
-	}
-	if _, err := h.client.Put(putReq, nil); err != nil {
-		h.logger.Errorf("IMS PUT error: %v", err)
+	if err := store.ReserveInventory(h.client, "", reservation); err != nil {
+		if errors.Is(err, store.ErrInsufficientInventory) {
+			h.logger.Warnf("insufficient stock for %s: need=%d", oc.OrderID, oc.Quantity)
+			return nil
+		}
+		if errors.Is(err, store.ErrInvalidUOM) {
+			h.logger.Warnf("unknown uom %q for %s, leaving order on hold", oc.UOM, oc.OrderID)
+			return nil
+		}
+		if errors.Is(err, store.ErrReservationSettled) {
+			// A redelivery after the order was shipped or cancelled.
+			h.logger.Infof("reservation for %s already settled, skipping finalize", oc.OrderID)
+			return nil
+		}
+		if errors.Is(err, store.ErrReservationConflict) {
+			h.logger.Warnf("reservation for %s held with another quantity, leaving order as is", oc.OrderID)
+			return nil
+		}
+		h.logger.Errorf("IMS reserve error: %v", err)
 		return err
 	}
 
-	if _, err := h.coll.UpdateOne(ctx,
-		bson.M{"_id": oc.OrderID},
+	res, err := h.coll.UpdateOne(ctx,
+		bson.M{"_id": oc.OrderID, "status": bson.M{"$in": []string{"on_hold", "new_order"}}},
 		bson.M{"$set": bson.M{"status": "new_order", "updated_at": time.Now().UTC()}},
-	); err != nil {
+	)
+	if err != nil {
 		h.logger.Errorf("mongo update failed: %v", err)
 		return err
 	}
+	if res.MatchedCount == 0 {
+		// The order was cancelled or shipped while we were reserving; a
+		// cancelled order must not keep stock held.
+		if err := store.ReleaseInventory(h.client, "", reservation); err != nil &&
+			!errors.Is(err, store.ErrReservationSettled) {
+			h.logger.Errorf("IMS release error for %s: %v", oc.OrderID, err)
+			return err
+		}
+		h.logger.Infof("Order %s no longer open, skipping finalize", oc.OrderID)
+		return nil
+	}
 	h.logger.Infof("Order %s → new_order", oc.OrderID)
 
 	evt, _ := json.Marshal(oc)
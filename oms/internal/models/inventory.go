@@ -9,3 +9,19 @@ type Inventory struct {
 	QuantityReserved int64     `json:"quantity_reserved" bson:"quantity_reserved"`
 	UpdatedAt        time.Time `json:"updated_at" bson:"updated_at"`
 }
+
+// InventoryPage is a page of the IMS GET /v2/inventory query.
+type InventoryPage struct {
+	Items      []Inventory `json:"items"`
+	NextCursor string      `json:"next_cursor,omitempty"`
+}
+
+type InventoryReservation struct {
+	TenantID      string   `json:"tenant_id"`
+	HubID         string   `json:"hub_id"`
+	SKUID         string   `json:"sku_id"`
+	ReferenceID   string   `json:"reference_id"`
+	Quantity      int64    `json:"quantity,omitempty"`
+	UOM           string   `json:"uom,omitempty"`
+	SerialNumbers []string `json:"serial_numbers,omitempty"`
+}
@@ -12,6 +12,7 @@ type Order struct {
 	HubID     string    `bson:"hub_id"`
 	SKUID     string    `bson:"sku_id"`
 	Quantity  int64     `bson:"quantity"`
+	UOM       string    `bson:"uom,omitempty"`
 	Status    string    `bson:"status"`
 	CreatedAt time.Time `bson:"created_at"`
 }
@@ -23,6 +24,7 @@ type OrderCreated struct {
 	HubID     string    `json:"hub_id"`
 	SKUID     string    `json:"sku_id"`
 	Quantity  int64     `json:"quantity"`
+	UOM       string    `json:"uom,omitempty"`
 	CreatedAt time.Time `json:"created_at"`
 }
 
@@ -46,12 +48,18 @@ func OrderFromRow(rec []string, header []string) (Order, bool) {
 		return Order{}, false
 	}
 
+	var uom string
+	if i, ok := idx["uom"]; ok && i < len(rec) {
+		uom = rec[i]
+	}
+
 	return Order{
 		TenantID:  t,
 		SellerID:  s,
 		HubID:     h,
 		SKUID:     k,
 		Quantity:  qty,
+		UOM:       uom,
 		Status:    "on_hold",
 		CreatedAt: time.Now().UTC(),
 	}, true
@@ -0,0 +1,107 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	stdhttp "net/http"
+	"time"
+
+	commonsHttp "github.com/omniful/go_commons/http"
+
+	"github.com/abhirup.dandapat/oms/internal/models"
+)
+
+var (
+	ErrInsufficientInventory = errors.New("insufficient inventory")
+	ErrReservationNotFound   = errors.New("reservation not found")
+	ErrReservationSettled    = errors.New("reservation already settled")
+	ErrReservationConflict   = errors.New("reference already reserved with another quantity")
+	ErrInvalidSerials        = errors.New("invalid serial numbers")
+	ErrSerialConflict        = errors.New("serial number not in stock at the hub")
+	ErrInvalidUOM            = errors.New("unit of measure not defined for SKU")
+	ErrHubFrozen             = errors.New("hub is frozen by an open count")
+)
+
+// ReserveInventory holds stock in IMS against r.ReferenceID. baseURL is
+// prepended to the IMS path and may be empty when the client already has one.
+// A quantity in r.UOM is converted to the SKU's base unit by IMS. A conflict
+// that carries the existing reservation is ErrReservationSettled when it was
+// already committed or released and ErrReservationConflict when it is still
+// held with another quantity; any other conflict is ErrInsufficientInventory.
+func ReserveInventory(client *commonsHttp.Client, baseURL string, r models.InventoryReservation) error {
+	err := postReservation(client, baseURL+"/inventory/reserve", r, ErrInsufficientInventory, ErrInvalidUOM)
+	var conflict *reservationConflict
+	if !errors.As(err, &conflict) {
+		return err
+	}
+	var body struct {
+		Current *struct {
+			Status string `json:"status"`
+		} `json:"current"`
+	}
+	if json.Unmarshal(conflict.body, &body) != nil || body.Current == nil {
+		return ErrInsufficientInventory
+	}
+	if body.Current.Status != reservationStatusReserved {
+		return ErrReservationSettled
+	}
+	return ErrReservationConflict
+}
+
+// reservationStatusReserved is the IMS status of a reservation still holding
+// stock.
+const reservationStatusReserved = "reserved"
+
+// reservationConflict is a 409 from IMS with its body, for callers that tell
+// conflicts apart by it.
+type reservationConflict struct {
+	err  error
+	body []byte
+}
+
+func (e *reservationConflict) Error() string { return e.err.Error() }
+func (e *reservationConflict) Unwrap() error { return e.err }
+
+// CommitInventory consumes a reservation once the order ships. Serialized SKUs
+// need r.SerialNumbers, one per unit shipped. Only reservations of open orders
+// are committed, so a conflict on a commit that names units is taken to be
+// ErrSerialConflict: one of them is not in stock at the hub.
+func CommitInventory(client *commonsHttp.Client, baseURL string, r models.InventoryReservation) error {
+	conflictErr := ErrReservationSettled
+	if len(r.SerialNumbers) > 0 {
+		conflictErr = ErrSerialConflict
+	}
+	return postReservation(client, baseURL+"/inventory/commit", r, conflictErr, ErrInvalidSerials)
+}
+
+// ReleaseInventory returns reserved stock when the order is cancelled.
+func ReleaseInventory(client *commonsHttp.Client, baseURL string, r models.InventoryReservation) error {
+	return postReservation(client, baseURL+"/inventory/release", r, ErrReservationSettled, nil)
+}
+
+func postReservation(client *commonsHttp.Client, url string, r models.InventoryReservation, conflictErr, badRequestErr error) error {
+	resp, err := client.Post(&commonsHttp.Request{
+		Url:     url,
+		Body:    r,
+		Timeout: 5 * time.Second,
+	}, nil)
+	if err != nil {
+		return err
+	}
+	switch resp.StatusCode() {
+	case stdhttp.StatusOK, stdhttp.StatusCreated:
+		return nil
+	case stdhttp.StatusBadRequest:
+		if badRequestErr != nil {
+			return badRequestErr
+		}
+	case stdhttp.StatusConflict:
+		return &reservationConflict{err: conflictErr, body: resp.Body()}
+	case stdhttp.StatusNotFound:
+		return ErrReservationNotFound
+	case stdhttp.StatusLocked:
+		return ErrHubFrozen
+	}
+	return fmt.Errorf("IMS POST %s returned %d", url, resp.StatusCode())
+}
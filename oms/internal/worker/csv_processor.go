@@ -7,8 +7,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
 	"path"
 	"strconv"
+	"strings"
 	"time"
 
 	awsV2 "github.com/aws/aws-sdk-go-v2/aws"
@@ -90,27 +92,33 @@ func (h *queueHandler) Process(ctx context.Context, msgs *[]sqs.Message) error {
 			order := &models.Order{
 				TenantID: row[idx["tenant_id"]],
 				SellerID: row[idx["seller_id"]],
-				HubID:    row[idx["hub_id"]],
-				SKUID:    row[idx["sku_id"]],
+				HubID:    csvField(row, idx, "hub_id"),
+				SKUID:    csvField(row, idx, "sku_id"),
+				UOM:      csvField(row, idx, "uom"),
 				Quantity: int64(qty),
 			}
 
-			imsURL := fmt.Sprintf(
-				"%s/inventory?hub_id=%s&sku_ids=%s",
-				config.GetString(ctx, "ims.baseUrl"),
-				order.HubID,
-				order.SKUID,
-			)
-			var invs []models.Inventory
+			// Hubs and SKUs may be given by code instead of id. The v2
+			// query is served from the IMS cache and returns a zero-filled
+			// row exactly when the hub and SKU both belong to the tenant;
+			// the row carries their ids.
+			query, ok := inventoryRefQuery(order.TenantID,
+				order.HubID, csvField(row, idx, "hub_code"), order.SKUID, csvField(row, idx, "sku_code"))
+			if !ok {
+				invalid = append(invalid, row)
+				continue
+			}
+			var page models.InventoryPage
 			getReq := &commonsHttp.Request{
-				Url:     imsURL,
+				Url:     config.GetString(ctx, "ims.baseUrl") + "/v2/inventory?" + query.Encode(),
 				Timeout: 5 * time.Second,
 			}
-			if _, err := httpClient.Get(getReq, &invs); err != nil || len(invs) != 1 {
-				logger.Warnf("IMS validation failed for hub=%s sku=%s: %v", order.HubID, order.SKUID, err)
+			if _, err := httpClient.Get(getReq, &page); err != nil || len(page.Items) != 1 {
+				logger.Warnf("IMS validation failed for %s: %v", query.Encode(), err)
 				invalid = append(invalid, row)
 				continue
 			}
+			order.HubID, order.SKUID = page.Items[0].HubID, page.Items[0].SKUID
 
 			if err := saveOrder(ctx, order); err != nil {
 				logger.Errorf("saveOrder error: %v", err)
@@ -119,32 +127,6 @@ func (h *queueHandler) Process(ctx context.Context, msgs *[]sqs.Message) error {
 			}
 			publishOrderCreated(ctx, producer, order)
 			logger.Infof("Processed order: %+v", order)
-
-			txReq := struct {
-				TenantID        string `json:"tenant_id"`
-				HubID           string `json:"hub_id"`
-				SKUID           string `json:"sku_id"`
-				Delta           int64  `json:"delta"`
-				TransactionType string `json:"transaction_type"`
-				ReferenceID     string `json:"reference_id"`
-			}{
-				TenantID:        order.TenantID,
-				HubID:           order.HubID,
-				SKUID:           order.SKUID,
-				Delta:           -order.Quantity,
-				TransactionType: "reservation",
-				ReferenceID:     order.ID,
-			}
-			txBody, _ := json.Marshal(txReq)
-			postReq := &commonsHttp.Request{
-				Url:  config.GetString(ctx, "ims.baseUrl") + "/inventory/transactions",
-				Body: bytes.NewReader(txBody),
-
-				Timeout: 5 * time.Second,
-			}
-			if _, err := httpClient.Post(postReq, nil); err != nil {
-				logger.Warnf("failed to record inventory transaction for order %s: %v", order.ID, err)
-			}
 		}
 
 		if len(invalid) > 0 {
@@ -173,6 +155,36 @@ func (h *queueHandler) Process(ctx context.Context, msgs *[]sqs.Message) error {
 	return nil
 }
 
+// csvField returns a row's value in column, or "" when the file has no such
+// column.
+func csvField(row []string, idx map[string]int, column string) string {
+	if i, ok := idx[column]; ok && i < len(row) {
+		return strings.TrimSpace(row[i])
+	}
+	return ""
+}
+
+// inventoryRefQuery builds the IMS v2 inventory query that checks one hub and
+// one SKU of a tenant, each named by id or, failing that, by code. It reports
+// false when the row names no tenant, hub or SKU.
+func inventoryRefQuery(tenantID, hubID, hubCode, skuID, skuCode string) (url.Values, bool) {
+	if tenantID == "" || (hubID == "" && hubCode == "") || (skuID == "" && skuCode == "") {
+		return nil, false
+	}
+	q := url.Values{"tenant_id": {tenantID}}
+	if hubID != "" {
+		q.Set("hub_ids", hubID)
+	} else {
+		q.Set("hub_codes", hubCode)
+	}
+	if skuID != "" {
+		q.Set("sku_ids", skuID)
+	} else {
+		q.Set("sku_codes", skuCode)
+	}
+	return q, true
+}
+
 func StartCSVProcessor(ctx context.Context) {
 	log.DefaultLogger().Infof("CONFIG kafka.brokers = %#v", config.GetStringSlice(ctx, "kafka.brokers"))
 	log.DefaultLogger().Infof("CONFIG kafka.version = %q", config.GetString(ctx, "kafka.version"))
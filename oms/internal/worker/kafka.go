@@ -31,6 +31,7 @@ func publishOrderCreated(ctx context.Context, producer *kafka.ProducerClient, o
 		HubID:     o.HubID,
 		SKUID:     o.SKUID,
 		Quantity:  o.Quantity,
+		UOM:       o.UOM,
 		CreatedAt: o.CreatedAt,
 	})
 	if err != nil {